@@ -0,0 +1,176 @@
+package tpke
+
+import (
+	"sort"
+	"sync"
+
+	bls "github.com/txhsl/tpke/internal/bls"
+)
+
+// Aggregator combines shares as they arrive. Every share is verified against the verification key of
+// its sender, and the result is ready as soon as threshold valid shares are collected.
+// It is safe to add shares from different goroutines
+type Aggregator struct {
+	mu        sync.Mutex
+	pk        *PublicKey
+	threshold int
+	scaler    int
+	msg       []byte        // Message of signature shares
+	cts       []*CipherText // Ciphertexts of decryption shares
+	indices   []int         // Senders of valid shares in arrival order
+	sigShares []*SignatureShare
+	decShares [][]*DecryptionShare
+	seen      map[int]bool
+	invalid   []int
+	sig       *Signature
+	results   []*bls.PointG1
+}
+
+// Verification keys are required, shares of unknown participants are invalid
+func NewSigAggregator(pk *PublicKey, msg []byte, threshold int, scaler int) *Aggregator {
+	return &Aggregator{
+		pk:        pk,
+		threshold: threshold,
+		scaler:    scaler,
+		msg:       msg,
+		seen:      make(map[int]bool),
+	}
+}
+
+// Ciphertexts are checked here, so that decryption shares can be verified one by one
+func NewDecryptionAggregator(pk *PublicKey, cts []*CipherText, threshold int, scaler int) (*Aggregator, error) {
+	for i, ct := range cts {
+		if err := ct.Verify(); err != nil {
+			return nil, NewTPKECiphertextError().withCipherText(i)
+		}
+	}
+	return &Aggregator{
+		pk:        pk,
+		threshold: threshold,
+		scaler:    scaler,
+		cts:       cts,
+		seen:      make(map[int]bool),
+	}, nil
+}
+
+// AddSigShare returns the signature once threshold valid shares arrive, and nil before that.
+// Repeated shares of a sender are ignored
+func (a *Aggregator) AddSigShare(index int, share *SignatureShare) (*Signature, error) {
+	if a.msg == nil {
+		return nil, NewSigAggregationError()
+	}
+	a.mu.Lock()
+	if a.sig != nil || a.seen[index] {
+		a.mu.Unlock()
+		return a.sig, nil
+	}
+	a.seen[index] = true
+	a.mu.Unlock()
+	// Verify without holding the lock
+	ok := a.pk.VerifySigShare(index, a.msg, share)
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if !ok {
+		a.invalid = append(a.invalid, index)
+		return nil, NewSigShareError().withParticipants(index)
+	}
+	if a.sig != nil {
+		return a.sig, nil
+	}
+	a.indices = append(a.indices, index)
+	a.sigShares = append(a.sigShares, share)
+	if len(a.indices) < a.threshold {
+		return nil, nil
+	}
+	sig, err := aggregateShares(a.indices, a.sigShares, a.scaler)
+	if err != nil {
+		return nil, err
+	}
+	if !a.pk.VerifySig(a.msg, sig) {
+		return nil, NewSigAggregationError()
+	}
+	a.sig = sig
+	return sig, nil
+}
+
+// AddDecryptionShares takes the shares of a sender for all ciphertexts, and returns the plaintexts
+// once threshold valid senders arrive. A sender is invalid if any of its shares is invalid
+func (a *Aggregator) AddDecryptionShares(index int, shares []*DecryptionShare) ([]*bls.PointG1, error) {
+	if a.cts == nil {
+		return nil, NewTPKEDecryptionError()
+	}
+	a.mu.Lock()
+	if a.results != nil || a.seen[index] {
+		a.mu.Unlock()
+		return a.results, nil
+	}
+	a.seen[index] = true
+	a.mu.Unlock()
+	ok := a.verifyDecryptionShares(index, shares)
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if !ok {
+		a.invalid = append(a.invalid, index)
+		return nil, NewTPKEDecryptionShareError().withParticipants(index)
+	}
+	if a.results != nil {
+		return a.results, nil
+	}
+	a.indices = append(a.indices, index)
+	a.decShares = append(a.decShares, shares)
+	if len(a.indices) < a.threshold {
+		return nil, nil
+	}
+	coeff, err := decryptionCoefficients(a.indices, a.scaler)
+	if err != nil {
+		return nil, err
+	}
+	g1 := bls.NewG1()
+	results := make([]*bls.PointG1, len(a.cts))
+	rpks := make([]*bls.PointG1, len(a.cts))
+	for i := 0; i < len(a.cts); i++ {
+		s := make([]*DecryptionShare, len(a.decShares))
+		for j := range a.decShares {
+			s[j] = a.decShares[j][i]
+		}
+		rpk, err := combineDecryptionShares(coeff, s)
+		if err != nil {
+			return nil, err
+		}
+		results[i] = g1.Add(g1.New(), a.cts[i].cMsg, rpk)
+		rpks[i] = rpk
+	}
+	if !verifyDecryptions(a.pk.pg1, a.cts, rpks) {
+		return nil, NewTPKEDecryptionError()
+	}
+	a.results = results
+	return results, nil
+}
+
+func (a *Aggregator) verifyDecryptionShares(index int, shares []*DecryptionShare) bool {
+	if len(shares) != len(a.cts) {
+		return false
+	}
+	for i, share := range shares {
+		if share == nil || share.pg1 == nil || !a.pk.VerifyDecryptionShare(index, a.cts[i], share) {
+			return false
+		}
+	}
+	return true
+}
+
+func (a *Aggregator) Done() bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.sig != nil || a.results != nil
+}
+
+// Participants who sent invalid shares, sorted by index
+func (a *Aggregator) GetInvalidShares() []int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	invalid := make([]int, len(a.invalid))
+	copy(invalid, a.invalid)
+	sort.Ints(invalid)
+	return invalid
+}
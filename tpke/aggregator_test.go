@@ -0,0 +1,86 @@
+package tpke
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+
+	bls "github.com/txhsl/tpke/internal/bls"
+)
+
+func TestAggregator(t *testing.T) {
+	size := 7
+	threshold := 4
+	dkg := NewDKG(size, threshold)
+	if err := dkg.Prepare(); err != nil {
+		t.Fatalf(err.Error())
+	}
+	if err := dkg.Verify(); err != nil {
+		t.Fatalf(err.Error())
+	}
+	pubkey := dkg.PublishGlobalPublicKey()
+	prvkeys := dkg.GetPrivateKeys()
+
+	// Signature shares arrive one by one, participants 2 and 5 send shares of other keys
+	msg := []byte("pizza pizza pizza pizza pizza pizza pizza pizza pizza pizza pizza pizza pizza")
+	sa := NewSigAggregator(pubkey, msg, threshold, dkg.GetScaler())
+	var sig *Signature
+	for _, index := range []int{2, 1, 5, 3, 6, 4, 7} {
+		share := prvkeys[index].SignShare(msg)
+		if index == 2 || index == 5 {
+			share = prvkeys[index%size+1].SignShare(msg)
+		}
+		res, err := sa.AddSigShare(index, share)
+		if (err != nil) != (index == 2 || index == 5) {
+			t.Fatalf("unexpected result of share %d.", index)
+		}
+		if res != nil && sig == nil {
+			// Valid shares 1, 3, 6 and 4
+			if index != 4 {
+				t.Fatalf("signature not ready after %d.", index)
+			}
+			sig = res
+		}
+	}
+	if !sa.Done() || !pubkey.VerifySig(msg, sig) {
+		t.Fatalf("invalid signature.")
+	}
+	if !reflect.DeepEqual(sa.GetInvalidShares(), []int{2, 5}) {
+		t.Fatalf("unexpected invalid shares %v.", sa.GetInvalidShares())
+	}
+
+	// Decryption shares arrive concurrently
+	msgs := []*bls.PointG1{RandPG1(), RandPG1()}
+	cts := Encrypt(msgs, pubkey)
+	da, err := NewDecryptionAggregator(pubkey, cts, threshold, dkg.GetScaler())
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	shares := decryptShare(cts, prvkeys)
+	shares[3][1].pg1 = RandPG1()
+	shares[6] = shares[6][:1]
+	var wg sync.WaitGroup
+	for index := 1; index <= size; index++ {
+		wg.Add(1)
+		go func(index int) {
+			defer wg.Done()
+			da.AddDecryptionShares(index, shares[index])
+		}(index)
+	}
+	wg.Wait()
+	results, err := da.AddDecryptionShares(1, shares[1])
+	if err != nil || results == nil {
+		t.Fatalf("decryption not finished.")
+	}
+	for i := range msgs {
+		if !bls.NewG1().Equal(msgs[i], results[i]) {
+			t.Fatalf("decryption failed.")
+		}
+	}
+	// Shares arriving after the result may not be checked
+	for _, index := range da.GetInvalidShares() {
+		if index != 3 && index != 6 {
+			t.Fatalf("valid share of %d reported.", index)
+		}
+	}
+}
@@ -0,0 +1,294 @@
+package tpke
+
+import (
+	"sort"
+
+	bls "github.com/txhsl/tpke/internal/bls"
+)
+
+// Batch verification checks a random linear combination of n statements with one multi-pairing.
+// Invalid items pass only with probability 1/q, and a failed batch is split in halves until
+// the invalid items are found, so a single bad item costs O(log n) batches
+
+// Sample a random weight for every item, nil if randomness is not available
+func batchWeights(n int) []*bls.Fr {
+	weights := make([]*bls.Fr, n)
+	for i := range weights {
+		fr, err := randomFr(nil)
+		if err != nil {
+			return nil
+		}
+		weights[i] = fr
+	}
+	return weights
+}
+
+// Find invalid items of a failed batch, check is called on sub-batches of positions
+func bisect(positions []int, check func([]int) bool) []int {
+	if len(positions) == 0 || check(positions) {
+		return nil
+	}
+	if len(positions) == 1 {
+		return positions
+	}
+	mid := len(positions) / 2
+	return append(bisect(positions[:mid], check), bisect(positions[mid:], check)...)
+}
+
+func sequence(n int) []int {
+	positions := make([]int, n)
+	for i := range positions {
+		positions[i] = i
+	}
+	return positions
+}
+
+// VerifyCipherTexts returns positions of invalid ciphertexts, checking e(sum(p_i*R1_i),G2)==e(G1,sum(p_i*R2_i))
+func VerifyCipherTexts(cts []*CipherText) []int {
+	invalid := make([]int, 0)
+	positions := make([]int, 0, len(cts))
+	for i, ct := range cts {
+		if ct == nil || ct.bigR == nil || ct.commitment == nil {
+			invalid = append(invalid, i)
+			continue
+		}
+		positions = append(positions, i)
+	}
+	weights := batchWeights(len(cts))
+	if weights == nil {
+		return sequence(len(cts))
+	}
+	check := func(positions []int) bool {
+		g1 := bls.NewG1()
+		g2 := bls.NewG2()
+		r1 := make([]*bls.PointG1, len(positions))
+		r2 := make([]*bls.PointG2, len(positions))
+		w := make([]*bls.Fr, len(positions))
+		for k, i := range positions {
+			r1[k] = g1.New().Set(cts[i].bigR)
+			r2[k] = g2.New().Set(cts[i].commitment)
+			w[k] = weights[i]
+		}
+		sumR1, err := g1.MultiExp(g1.New(), r1, w)
+		if err != nil {
+			return false
+		}
+		sumR2, err := g2.MultiExp(g2.New(), r2, w)
+		if err != nil {
+			return false
+		}
+		pairing := bls.NewEngine()
+		return pairing.AddPair(sumR1, &bls.G2One).AddPairInv(&bls.G1One, sumR2).Check()
+	}
+	invalid = append(invalid, bisect(positions, check)...)
+	sort.Ints(invalid)
+	return invalid
+}
+
+// VerifySigShares returns indices of invalid shares, checking e(sum(p_i*VK_i),H(msg))==e(G1,sum(p_i*S_i)),
+// or e(sum(p_i*S_i),G2)==e(H(msg),sum(p_i*VK_i)) for the min-sig scheme
+func (pk *PublicKey) VerifySigShares(msg []byte, inputs map[int]*SignatureShare) []int {
+	if pk.GetScheme() == MinSig {
+		return pk.verifyMinSigShares(msg, inputs)
+	}
+	indices := make([]int, 0, len(inputs))
+	for index := range inputs {
+		indices = append(indices, index)
+	}
+	sort.Ints(indices)
+
+	invalid := make([]int, 0)
+	positions := make([]int, 0, len(indices))
+	for i, index := range indices {
+		if _, ok := pk.vks[index]; !ok || inputs[index] == nil || inputs[index].pg2 == nil {
+			invalid = append(invalid, index)
+			continue
+		}
+		positions = append(positions, i)
+	}
+	weights := batchWeights(len(indices))
+	if weights == nil {
+		return indices
+	}
+	input, domain := pk.suite.input(MinPubKey, pk.schemeKey(), msg)
+	g2Hash, _ := bls.NewG2().HashToCurve(input, domain)
+	check := func(positions []int) bool {
+		g1 := bls.NewG1()
+		g2 := bls.NewG2()
+		vks := make([]*bls.PointG1, len(positions))
+		sigs := make([]*bls.PointG2, len(positions))
+		w := make([]*bls.Fr, len(positions))
+		for k, i := range positions {
+			vks[k] = g1.New().Set(pk.vks[indices[i]])
+			sigs[k] = g2.New().Set(inputs[indices[i]].pg2)
+			w[k] = weights[i]
+		}
+		sumVK, err := g1.MultiExp(g1.New(), vks, w)
+		if err != nil {
+			return false
+		}
+		sumSig, err := g2.MultiExp(g2.New(), sigs, w)
+		if err != nil {
+			return false
+		}
+		pairing := bls.NewEngine()
+		return pairing.AddPair(sumVK, g2Hash).AddPairInv(&bls.G1One, sumSig).Check()
+	}
+	for _, i := range bisect(positions, check) {
+		invalid = append(invalid, indices[i])
+	}
+	sort.Ints(invalid)
+	return invalid
+}
+
+func (pk *PublicKey) verifyMinSigShares(msg []byte, inputs map[int]*SignatureShare) []int {
+	indices := make([]int, 0, len(inputs))
+	for index := range inputs {
+		indices = append(indices, index)
+	}
+	sort.Ints(indices)
+
+	invalid := make([]int, 0)
+	positions := make([]int, 0, len(indices))
+	for i, index := range indices {
+		if _, ok := pk.vks2[index]; !ok || inputs[index] == nil || inputs[index].pg1 == nil {
+			invalid = append(invalid, index)
+			continue
+		}
+		positions = append(positions, i)
+	}
+	weights := batchWeights(len(indices))
+	if weights == nil {
+		return indices
+	}
+	input, domain := pk.suite.input(MinSig, pk.schemeKey(), msg)
+	g1Hash, _ := bls.NewG1().HashToCurve(input, domain)
+	check := func(positions []int) bool {
+		g1 := bls.NewG1()
+		g2 := bls.NewG2()
+		vks := make([]*bls.PointG2, len(positions))
+		sigs := make([]*bls.PointG1, len(positions))
+		w := make([]*bls.Fr, len(positions))
+		for k, i := range positions {
+			vks[k] = g2.New().Set(pk.vks2[indices[i]])
+			sigs[k] = g1.New().Set(inputs[indices[i]].pg1)
+			w[k] = weights[i]
+		}
+		sumVK, err := g2.MultiExp(g2.New(), vks, w)
+		if err != nil {
+			return false
+		}
+		sumSig, err := g1.MultiExp(g1.New(), sigs, w)
+		if err != nil {
+			return false
+		}
+		pairing := bls.NewEngine()
+		return pairing.AddPair(sumSig, &bls.G2One).AddPairInv(g1Hash, sumVK).Check()
+	}
+	for _, i := range bisect(positions, check) {
+		invalid = append(invalid, indices[i])
+	}
+	sort.Ints(invalid)
+	return invalid
+}
+
+// VerifyDecryptionShares returns positions of invalid shares for every participant, shares of participant i
+// are aligned with cts. Ciphertexts are expected to pass VerifyCipherTexts. Items are grouped by participant,
+// e(sum(p_ij*S_ij),G2)==prod(e(VK_i,sum(p_ij*R2_j))), so a batch costs at most size+1 pairings
+func (pk *PublicKey) VerifyDecryptionShares(cts []*CipherText, inputs map[int]([]*DecryptionShare)) map[int][]int {
+	type item struct {
+		index    int
+		position int
+	}
+	indices := make([]int, 0, len(inputs))
+	for index := range inputs {
+		indices = append(indices, index)
+	}
+	sort.Ints(indices)
+
+	invalid := make(map[int][]int)
+	items := make([]item, 0, len(indices)*len(cts))
+	for _, index := range indices {
+		_, ok := pk.vks[index]
+		for j := 0; j < len(cts); j++ {
+			if !ok || cts[j] == nil || cts[j].commitment == nil || j >= len(inputs[index]) || inputs[index][j] == nil || inputs[index][j].pg1 == nil {
+				invalid[index] = append(invalid[index], j)
+				continue
+			}
+			items = append(items, item{index, j})
+		}
+	}
+	weights := batchWeights(len(items))
+	if weights == nil {
+		for _, it := range items {
+			invalid[it.index] = append(invalid[it.index], it.position)
+		}
+		return invalid
+	}
+	check := func(positions []int) bool {
+		g1 := bls.NewG1()
+		g2 := bls.NewG2()
+		shares := make([]*bls.PointG1, len(positions))
+		w := make([]*bls.Fr, len(positions))
+		groups := make(map[int][]int)
+		for k, i := range positions {
+			it := items[i]
+			shares[k] = g1.New().Set(inputs[it.index][it.position].pg1)
+			w[k] = weights[i]
+			groups[it.index] = append(groups[it.index], i)
+		}
+		sumShare, err := g1.MultiExp(g1.New(), shares, w)
+		if err != nil {
+			return false
+		}
+		pairing := bls.NewEngine()
+		pairing.AddPairInv(sumShare, &bls.G2One)
+		for index, group := range groups {
+			r2 := make([]*bls.PointG2, len(group))
+			gw := make([]*bls.Fr, len(group))
+			for k, i := range group {
+				r2[k] = g2.New().Set(cts[items[i].position].commitment)
+				gw[k] = weights[i]
+			}
+			sumR2, err := g2.MultiExp(g2.New(), r2, gw)
+			if err != nil {
+				return false
+			}
+			pairing.AddPair(g1.New().Set(pk.vks[index]), sumR2)
+		}
+		return pairing.Check()
+	}
+	for _, i := range bisect(sequence(len(items)), check) {
+		invalid[items[i].index] = append(invalid[items[i].index], items[i].position)
+	}
+	for index := range invalid {
+		sort.Ints(invalid[index])
+	}
+	return invalid
+}
+
+// Check e(pk,R2_i)==e(rpk_i,G2) for all ciphertexts in one batch, rpks are negated as in combineDecryptionShares
+func verifyDecryptions(pk *bls.PointG1, cts []*CipherText, rpks []*bls.PointG1) bool {
+	weights := batchWeights(len(cts))
+	if weights == nil {
+		return false
+	}
+	g1 := bls.NewG1()
+	g2 := bls.NewG2()
+	r2 := make([]*bls.PointG2, len(cts))
+	points := make([]*bls.PointG1, len(cts))
+	for i := range cts {
+		r2[i] = g2.New().Set(cts[i].commitment)
+		points[i] = g1.New().Set(rpks[i])
+	}
+	sumR2, err := g2.MultiExp(g2.New(), r2, weights)
+	if err != nil {
+		return false
+	}
+	sumRPK, err := g1.MultiExp(g1.New(), points, weights)
+	if err != nil {
+		return false
+	}
+	pairing := bls.NewEngine()
+	return pairing.AddPair(g1.New().Set(pk), sumR2).AddPair(sumRPK, &bls.G2One).Check()
+}
@@ -0,0 +1,81 @@
+package tpke
+
+import (
+	"reflect"
+	"testing"
+
+	bls "github.com/txhsl/tpke/internal/bls"
+)
+
+func TestBatchVerification(t *testing.T) {
+	size := 7
+	threshold := 5
+	dkg := NewDKG(size, threshold)
+	dkg.Prepare()
+	if err := dkg.Verify(); err != nil {
+		t.Fatalf(err.Error())
+	}
+	pubkey := dkg.PublishGlobalPublicKey()
+	prvkeys := dkg.GetPrivateKeys()
+
+	// Ciphertexts
+	msgs := make([]*bls.PointG1, 20)
+	for i := range msgs {
+		msgs[i] = RandPG1()
+	}
+	cts := Encrypt(msgs, pubkey)
+	if invalid := VerifyCipherTexts(cts); len(invalid) != 0 {
+		t.Fatalf("valid ciphertexts rejected %v.", invalid)
+	}
+	forged := make([]*CipherText, len(cts))
+	copy(forged, cts)
+	forged[3] = &CipherText{
+		cMsg:       cts[3].cMsg,
+		bigR:       RandPG1(),
+		commitment: cts[3].commitment,
+	}
+	forged[17] = nil
+	if invalid := VerifyCipherTexts(forged); !reflect.DeepEqual(invalid, []int{3, 17}) {
+		t.Fatalf("unexpected invalid ciphertexts %v.", invalid)
+	}
+
+	// Decryption shares
+	shares := decryptShare(cts, prvkeys)
+	if invalid := pubkey.VerifyDecryptionShares(cts, shares); len(invalid) != 0 {
+		t.Fatalf("valid shares rejected %v.", invalid)
+	}
+	shares[2][5].pg1 = RandPG1()
+	shares[6][5].pg1 = RandPG1()
+	shares[6][11].pg1 = RandPG1()
+	invalid := pubkey.VerifyDecryptionShares(cts, shares)
+	if len(invalid) != 2 || !reflect.DeepEqual(invalid[2], []int{5}) || !reflect.DeepEqual(invalid[6], []int{5, 11}) {
+		t.Fatalf("unexpected invalid shares %v.", invalid)
+	}
+	results, err := Decrypt(cts, shares, pubkey, threshold, 1)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	for i := range msgs {
+		if !bls.NewG1().Equal(msgs[i], results[i]) {
+			t.Fatalf("decryption failed.")
+		}
+	}
+
+	// Signature shares
+	msg := []byte("pizza pizza pizza pizza pizza pizza pizza pizza pizza pizza pizza pizza pizza")
+	inputs := make(map[int]*SignatureShare)
+	for i, sk := range prvkeys {
+		inputs[i] = sk.SignShare(msg)
+	}
+	if invalid := pubkey.VerifySigShares(msg, inputs); len(invalid) != 0 {
+		t.Fatalf("valid shares rejected %v.", invalid)
+	}
+	inputs[1] = prvkeys[1].SignShare([]byte("another message"))
+	inputs[4] = nil
+	if invalid := pubkey.VerifySigShares(msg, inputs); !reflect.DeepEqual(invalid, []int{1, 4}) {
+		t.Fatalf("unexpected invalid shares %v.", invalid)
+	}
+	if _, err := AggregateAndVerifySig(pubkey, msg, threshold, inputs, 1); err != nil {
+		t.Fatalf(err.Error())
+	}
+}
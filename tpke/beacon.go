@@ -0,0 +1,134 @@
+package tpke
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+)
+
+// BeaconRound is a link of the beacon chain, the signature of round r is TSign(r||sig_{r-1})
+type BeaconRound struct {
+	Round     uint64
+	Signature *Signature
+}
+
+// Output of the round is H(sig_r)
+func (r *BeaconRound) Randomness() []byte {
+	h := sha256.Sum256(r.Signature.ToBytes())
+	return h[:]
+}
+
+// Beacon collects signature shares round by round and chains threshold signatures into random outputs.
+// Rounds start from 1, and the first round links to the genesis seed instead of a previous signature
+type Beacon struct {
+	pk        *PublicKey
+	threshold int
+	genesis   []byte
+	rounds    []*BeaconRound
+	shares    map[int]*SignatureShare // Shares of the next round
+}
+
+func NewBeacon(pk *PublicKey, threshold int, genesis []byte) *Beacon {
+	return &Beacon{
+		pk:        pk,
+		threshold: threshold,
+		genesis:   genesis,
+		rounds:    make([]*BeaconRound, 0),
+		shares:    make(map[int]*SignatureShare),
+	}
+}
+
+// Message of round r is r in 8 bytes big-endian, followed by the previous signature or the genesis seed
+func BeaconInput(round uint64, prev []byte) []byte {
+	out := make([]byte, 8, 8+len(prev))
+	binary.BigEndian.PutUint64(out, round)
+	return append(out, prev...)
+}
+
+func (b *Beacon) NextRound() uint64 {
+	return uint64(len(b.rounds)) + 1
+}
+
+// Participants sign the input with SignShare to join the next round
+func (b *Beacon) Input() []byte {
+	return BeaconInput(b.NextRound(), b.previous())
+}
+
+func (b *Beacon) previous() []byte {
+	if len(b.rounds) == 0 {
+		return b.genesis
+	}
+	return b.rounds[len(b.rounds)-1].Signature.ToBytes()
+}
+
+// Shares are checked one by one if verification keys are set, otherwise invalid shares are only
+// dropped by aggregation. The round is returned once it completes, and nil while waiting for shares
+func (b *Beacon) AddShare(round uint64, index int, share *SignatureShare) (*BeaconRound, error) {
+	if round != b.NextRound() {
+		return nil, NewBeaconRoundError()
+	}
+	if share == nil || (share.pg1 == nil && share.pg2 == nil) {
+		return nil, NewBeaconShareError().withParticipants(index)
+	}
+	if _, ok := b.shares[index]; ok {
+		return nil, nil
+	}
+	input := b.Input()
+	if b.pk.HasVerificationKeys() && !b.pk.VerifySigShare(index, input, share) {
+		return nil, NewBeaconShareError().withParticipants(index)
+	}
+	b.shares[index] = share
+	if len(b.shares) < b.threshold {
+		return nil, nil
+	}
+	sig, err := AggregateAndVerifySig(b.pk, input, b.threshold, b.shares, 1)
+	if err != nil {
+		// Wait for more shares
+		return nil, nil
+	}
+	r := &BeaconRound{
+		Round:     round,
+		Signature: sig,
+	}
+	b.rounds = append(b.rounds, r)
+	b.shares = make(map[int]*SignatureShare)
+	return r, nil
+}
+
+// Return nil if the round is not finished yet
+func (b *Beacon) GetRound(round uint64) *BeaconRound {
+	if round == 0 || round > uint64(len(b.rounds)) {
+		return nil
+	}
+	return b.rounds[round-1]
+}
+
+func (b *Beacon) Latest() *BeaconRound {
+	return b.GetRound(uint64(len(b.rounds)))
+}
+
+// All finished rounds from round 1
+func (b *Beacon) Chain() []*BeaconRound {
+	chain := make([]*BeaconRound, len(b.rounds))
+	copy(chain, b.rounds)
+	return chain
+}
+
+// Check a single round against the previous signature, or the genesis seed for round 1
+func VerifyBeaconRound(pk *PublicKey, prev []byte, r *BeaconRound) bool {
+	if r == nil || r.Signature == nil {
+		return false
+	}
+	return pk.VerifySig(BeaconInput(r.Round, prev), r.Signature)
+}
+
+// Check a chain from round 1 with only the global public key and the genesis seed
+func VerifyBeaconChain(pk *PublicKey, genesis []byte, chain []*BeaconRound) bool {
+	prev := genesis
+	for i, r := range chain {
+		if r == nil || r.Round != uint64(i)+1 || !VerifyBeaconRound(pk, prev, r) {
+			return false
+		}
+		prev = r.Signature.ToBytes()
+	}
+	return true
+}
@@ -0,0 +1,116 @@
+package tpke
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestBeacon(t *testing.T) {
+	size := 7
+	threshold := 5
+	rounds := 64
+	dkg := NewDKG(size, threshold)
+	if err := dkg.Prepare(); err != nil {
+		t.Fatalf(err.Error())
+	}
+	if err := dkg.Verify(); err != nil {
+		t.Fatalf(err.Error())
+	}
+	pk := dkg.PublishGlobalPublicKey()
+	sks := dkg.GetPrivateKeys()
+
+	genesis := []byte("genesis")
+	beacon := NewBeacon(pk, threshold, genesis)
+	r := rand.New(rand.NewSource(time.Now().UnixNano()))
+	outputs := make(map[string]bool)
+	for round := 1; round <= rounds; round++ {
+		input := beacon.Input()
+		// Member 1 always sends a share of the wrong round
+		if _, err := beacon.AddShare(uint64(round), 1, sks[1].SignShare(BeaconInput(uint64(round+1), nil))); err == nil {
+			t.Fatalf("invalid share accepted.")
+		}
+		var result *BeaconRound
+		for _, index := range r.Perm(size) {
+			res, err := beacon.AddShare(uint64(round), index+1, sks[index+1].SignShare(input))
+			if err != nil {
+				t.Fatalf(err.Error())
+			}
+			if res != nil {
+				result = res
+				break
+			}
+		}
+		if result == nil || result.Round != uint64(round) {
+			t.Fatalf("round not finished.")
+		}
+		out := string(result.Randomness())
+		if outputs[out] {
+			t.Fatalf("repeated output.")
+		}
+		outputs[out] = true
+	}
+	if _, err := beacon.AddShare(1, 1, sks[1].SignShare(beacon.Input())); err == nil {
+		t.Fatalf("share of a past round accepted.")
+	}
+
+	// Observers verify the chain with only the global public key and the genesis seed
+	chain := beacon.Chain()
+	if len(chain) != rounds || beacon.Latest() != chain[rounds-1] {
+		t.Fatalf("unexpected chain.")
+	}
+	if !VerifyBeaconChain(pk, genesis, chain) {
+		t.Fatalf("valid chain rejected.")
+	}
+	if !VerifyBeaconRound(pk, chain[9].Signature.ToBytes(), chain[10]) {
+		t.Fatalf("valid round rejected.")
+	}
+	if VerifyBeaconChain(pk, []byte("other"), chain) {
+		t.Fatalf("chain accepted with another genesis.")
+	}
+	chain[20], chain[21] = chain[21], chain[20]
+	if VerifyBeaconChain(pk, genesis, chain) {
+		t.Fatalf("reordered chain accepted.")
+	}
+
+	// Same outputs without verification keys, where invalid shares are dropped by aggregation
+	observer := NewBeacon(&PublicKey{pg1: pk.pg1}, threshold, genesis)
+	for round := 1; round <= 3; round++ {
+		input := observer.Input()
+		if _, err := observer.AddShare(uint64(round), 1, sks[2].SignShare(input)); err != nil {
+			t.Fatalf(err.Error())
+		}
+		var result *BeaconRound
+		for index := 2; index <= size && result == nil; index++ {
+			res, err := observer.AddShare(uint64(round), index, sks[index].SignShare(input))
+			if err != nil {
+				t.Fatalf(err.Error())
+			}
+			result = res
+		}
+		if result == nil || !bytes.Equal(result.Randomness(), beacon.GetRound(uint64(round)).Randomness()) {
+			t.Fatalf("unexpected output.")
+		}
+	}
+	// A nil share is rejected, and a share of the other scheme does not block the round
+	observer = NewBeacon(&PublicKey{pg1: pk.pg1}, threshold, genesis)
+	input := observer.Input()
+	if _, err := observer.AddShare(1, 1, nil); err == nil {
+		t.Fatalf("nil share accepted.")
+	}
+	if _, err := observer.AddShare(1, 1, &SignatureShare{pg1: RandPG1()}); err != nil {
+		t.Fatalf(err.Error())
+	}
+	var result *BeaconRound
+	for index := 2; index <= size && result == nil; index++ {
+		res, err := observer.AddShare(1, index, sks[index].SignShare(input))
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		result = res
+	}
+	if result == nil || !bytes.Equal(result.Randomness(), beacon.GetRound(1).Randomness()) {
+		t.Fatalf("round blocked by a malformed share.")
+	}
+}
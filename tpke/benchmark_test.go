@@ -5,7 +5,7 @@ import (
 	"testing"
 	"time"
 
-	bls "github.com/kilic/bls12-381"
+	bls "github.com/txhsl/tpke/internal/bls"
 )
 
 func TestBenchmark(t *testing.T) {
@@ -36,13 +36,9 @@ func TestBenchmark(t *testing.T) {
 	}
 	encryptedSeeds := Encrypt(seeds, pubkey)
 
-	// Verify encrypted seeds
-	for i := 0; i < sampleAmount; i++ {
-		go parallelCTVerify(encryptedSeeds[i], ch)
-	}
-	_, err := messageHandler(ch, sampleAmount)
-	if err != nil {
-		t.Fatalf(err.Error())
+	// Verify encrypted seeds in one batch
+	if invalid := VerifyCipherTexts(encryptedSeeds); len(invalid) > 0 {
+		t.Fatalf("invalid ciphertexts %v.", invalid)
 	}
 
 	// AES encrypt with different seeds
@@ -96,14 +92,6 @@ type Message struct {
 	err   error
 }
 
-func parallelCTVerify(ct *CipherText, ch chan<- Message) {
-	ch <- Message{
-		index: 0,
-		err:   ct.Verify(),
-		data:  nil,
-	}
-}
-
 func parallelAESEncrypt(index int, seed *bls.PointG1, input []byte, ch chan<- Message) {
 	result, err := AESEncrypt(seed, input)
 	ch <- Message{
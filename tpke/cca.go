@@ -0,0 +1,223 @@
+package tpke
+
+import (
+	"io"
+	"sort"
+
+	bls "github.com/txhsl/tpke/internal/bls"
+)
+
+var (
+	ciphertextProofDomain = []byte("TPKE_TDH2_CIPHERTEXT_PROOF_")
+	shareProofDomain      = []byte("TPKE_TDH2_SHARE_PROOF_")
+)
+
+// CCACipherText follows TDH2, C=M+rpk, U=rG1, Ubar=rGbar, with a proof of log_G1(U)==log_Gbar(Ubar)
+// bound to C and the label, so that ciphertexts can not be modified without knowing r
+type CCACipherText struct {
+	cMsg    *bls.PointG1
+	bigU    *bls.PointG1
+	bigUBar *bls.PointG1
+	label   []byte
+	proof   *DLEQProof
+}
+
+func (pk *PublicKey) EncryptCCA(msg *bls.PointG1, label []byte) (*CCACipherText, error) {
+	return pk.EncryptCCAWithReader(msg, label, nil)
+}
+
+// Encrypt with r and the proof nonce sampled from random, crypto/rand if nil
+func (pk *PublicKey) EncryptCCAWithReader(msg *bls.PointG1, label []byte, random io.Reader) (*CCACipherText, error) {
+	r, err := randomFr(random)
+	if err != nil {
+		return nil, err
+	}
+
+	// C=M+rpk, U=rG1, Ubar=rGbar
+	g1 := bls.NewG1()
+	gBar := secondGenerator()
+	bigU := g1.MulScalar(g1.New(), &bls.G1One, r)
+	bigUBar := g1.MulScalar(g1.New(), gBar, r)
+	cMsg := g1.MulScalar(g1.New(), pk.pg1, r)
+	g1.Add(cMsg, cMsg, msg)
+
+	ct := &CCACipherText{
+		cMsg:    cMsg,
+		bigU:    bigU,
+		bigUBar: bigUBar,
+		label:   append([]byte{}, label...),
+	}
+	ct.proof, err = newDLEQProof(random, ciphertextProofDomain, ct.proofContext(), &bls.G1One, gBar, r)
+	if err != nil {
+		return nil, err
+	}
+	return ct, nil
+}
+
+func (ct *CCACipherText) proofContext() []byte {
+	return appendWithLength(bls.NewG1().ToCompressed(ct.cMsg), ct.label)
+}
+
+func (ct *CCACipherText) GetLabel() []byte {
+	return ct.label
+}
+
+// Verify checks the ciphertext validity proof, any participant runs it before decryption
+func (ct *CCACipherText) Verify() error {
+	if !ct.proof.verify(ciphertextProofDomain, ct.proofContext(), &bls.G1One, ct.bigU, secondGenerator(), ct.bigUBar) {
+		return NewTPKECiphertextError()
+	}
+	return nil
+}
+
+func (ct *CCACipherText) ToBytes() []byte {
+	g1 := bls.NewG1()
+	out := make([]byte, 0, 3*fpByteSize+64+4+len(ct.label))
+	out = append(out, g1.ToCompressed(ct.cMsg)...)
+	out = append(out, g1.ToCompressed(ct.bigU)...)
+	out = append(out, g1.ToCompressed(ct.bigUBar)...)
+	out = append(out, ct.proof.ToBytes()...)
+	return appendWithLength(out, ct.label)
+}
+
+func BytesToCCACipherText(b []byte) (*CCACipherText, error) {
+	if len(b) < 3*fpByteSize+64 {
+		return nil, NewTPKECiphertextError()
+	}
+	g1 := bls.NewG1()
+	points := make([]*bls.PointG1, 3)
+	for i := range points {
+		pg1, err := g1.FromCompressed(b[i*fpByteSize : (i+1)*fpByteSize])
+		if err != nil {
+			return nil, err
+		}
+		points[i] = pg1
+	}
+	proof, err := BytesToDLEQProof(b[3*fpByteSize : 3*fpByteSize+64])
+	if err != nil {
+		return nil, err
+	}
+	label, rest, ok := readWithLength(b[3*fpByteSize+64:])
+	if !ok || len(rest) != 0 {
+		return nil, NewTPKECiphertextError()
+	}
+	return &CCACipherText{
+		cMsg:    points[0],
+		bigU:    points[1],
+		bigUBar: points[2],
+		label:   append([]byte{}, label...),
+		proof:   proof,
+	}, nil
+}
+
+// DecryptShareCCA returns S_i=sk_i*U with a proof of log_G1(VK_i)==log_U(S_i), invalid ciphertexts are rejected
+func (sk *PrivateKey) DecryptShareCCA(ct *CCACipherText) (*DecryptionShare, error) {
+	return sk.DecryptShareCCAWithReader(ct, nil)
+}
+
+// Sample the proof nonce from random, crypto/rand if nil
+func (sk *PrivateKey) DecryptShareCCAWithReader(ct *CCACipherText, random io.Reader) (*DecryptionShare, error) {
+	if err := ct.Verify(); err != nil {
+		return nil, err
+	}
+	g1 := bls.NewG1()
+	pg1 := g1.MulScalar(g1.New(), ct.bigU, sk.fr)
+	proof, err := newDLEQProof(random, shareProofDomain, nil, &bls.G1One, ct.bigU, sk.fr)
+	if err != nil {
+		return nil, err
+	}
+	return &DecryptionShare{
+		pg1:   pg1,
+		proof: proof,
+	}, nil
+}
+
+func (pk *PublicKey) VerifyDecryptionShareCCA(index int, ct *CCACipherText, share *DecryptionShare) bool {
+	vk, ok := pk.vks[index]
+	if !ok || share == nil || share.pg1 == nil || ct == nil {
+		return false
+	}
+	return share.proof.verify(shareProofDomain, nil, &bls.G1One, vk, ct.bigU, share.pg1)
+}
+
+// DecryptCCA combines the first threshold shares with valid proofs for every ciphertext
+func DecryptCCA(cts []*CCACipherText, inputs map[int]([]*DecryptionShare), pub *PublicKey, threshold int) ([]*bls.PointG1, error) {
+	if len(inputs) < threshold {
+		return nil, NewTPKENotEnoughShareError()
+	}
+	indices := make([]int, 0, len(inputs))
+	for index := range inputs {
+		indices = append(indices, index)
+	}
+	sort.Ints(indices)
+
+	results := make([]*bls.PointG1, len(cts))
+	ch := make(chan verifyMessage, len(cts))
+	for i := 0; i < len(cts); i++ {
+		s := make([]*DecryptionShare, len(indices))
+		for j, index := range indices {
+			if i < len(inputs[index]) {
+				s[j] = inputs[index][i]
+			}
+		}
+		go parallelDecryptCCA(i, cts[i], indices, s, pub, threshold, ch)
+	}
+	for i := 0; i < len(cts); i++ {
+		msg := <-ch
+		if msg.err != nil {
+			return nil, msg.err
+		}
+		results[msg.index] = msg.result
+	}
+	return results, nil
+}
+
+func parallelDecryptCCA(index int, ct *CCACipherText, indices []int, shares []*DecryptionShare, pub *PublicKey, threshold int, ch chan<- verifyMessage) {
+	if err := ct.Verify(); err != nil {
+		ch <- verifyMessage{
+			index: index,
+			err:   NewTPKECiphertextError().withCipherText(index),
+		}
+		return
+	}
+	// Collect the first threshold valid shares
+	idx := make([]int, 0, threshold)
+	s := make([]*DecryptionShare, 0, threshold)
+	invalid := make([]int, 0)
+	for j := 0; j < len(shares) && len(idx) < threshold; j++ {
+		if pub.VerifyDecryptionShareCCA(indices[j], ct, shares[j]) {
+			idx = append(idx, indices[j])
+			s = append(s, shares[j])
+		} else {
+			invalid = append(invalid, indices[j])
+		}
+	}
+	if len(idx) < threshold {
+		ch <- verifyMessage{
+			index: index,
+			err:   NewTPKENotEnoughShareError().withParticipants(invalid...).withCipherText(index),
+		}
+		return
+	}
+	coeff, err := decryptionCoefficients(idx, 1)
+	if err != nil {
+		ch <- verifyMessage{
+			index: index,
+			err:   err,
+		}
+		return
+	}
+	rpk, err := combineDecryptionShares(coeff, s)
+	if err != nil {
+		ch <- verifyMessage{
+			index: index,
+			err:   err,
+		}
+		return
+	}
+	g1 := bls.NewG1()
+	ch <- verifyMessage{
+		index:  index,
+		result: g1.Add(g1.New(), ct.cMsg, rpk),
+	}
+}
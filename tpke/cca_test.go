@@ -0,0 +1,119 @@
+package tpke
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	bls "github.com/txhsl/tpke/internal/bls"
+)
+
+func TestCCA(t *testing.T) {
+	size := 7
+	threshold := 5
+	dkg := NewDKG(size, threshold)
+	dkg.Prepare()
+	if err := dkg.Verify(); err != nil {
+		t.Fatalf(err.Error())
+	}
+	pubkey := dkg.PublishGlobalPublicKey()
+	prvkeys := dkg.GetPrivateKeys()
+
+	msg := RandPG1()
+	label := []byte("block 100")
+	ct, err := pubkey.EncryptCCA(msg, label)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if err := ct.Verify(); err != nil {
+		t.Fatalf(err.Error())
+	}
+	result, err := BytesToCCACipherText(ct.ToBytes())
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if err := result.Verify(); err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	// Generate shares with proofs
+	shares := make(map[int][]*DecryptionShare)
+	for i, sk := range prvkeys {
+		share, err := sk.DecryptShareCCA(ct)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		b, err := BytesToDecryptionShare(share.ToBytes())
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		if !pubkey.VerifyDecryptionShareCCA(i, ct, b) {
+			t.Fatalf("valid share rejected.")
+		}
+		shares[i] = []*DecryptionShare{b}
+	}
+
+	// Put wrong shares
+	shares[1][0].pg1 = RandPG1()
+	shares[2][0] = shares[3][0]
+	if pubkey.VerifyDecryptionShareCCA(1, ct, shares[1][0]) || pubkey.VerifyDecryptionShareCCA(2, ct, shares[2][0]) {
+		t.Fatalf("invalid share accepted.")
+	}
+	results, err := DecryptCCA([]*CCACipherText{ct}, shares, pubkey, threshold)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if !bls.NewG1().Equal(msg, results[0]) {
+		t.Fatalf("decryption failed.")
+	}
+}
+
+func TestCCAMalleability(t *testing.T) {
+	sk := &PrivateKey{
+		fr: bls.NewFr().One(),
+	}
+	pubkey := sk.GetPublicKey()
+	ct, err := pubkey.EncryptCCA(RandPG1(), []byte("block 100"))
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	// Shift the message
+	g1 := bls.NewG1()
+	shifted := *ct
+	shifted.cMsg = g1.Add(g1.New(), ct.cMsg, &bls.G1One)
+	if err := shifted.Verify(); err == nil {
+		t.Fatalf("modified ciphertext accepted.")
+	}
+	if _, err := sk.DecryptShareCCA(&shifted); err == nil {
+		t.Fatalf("share generated for modified ciphertext.")
+	}
+
+	// Replay under another label
+	relabeled := *ct
+	relabeled.label = []byte("block 101")
+	if err := relabeled.Verify(); err == nil {
+		t.Fatalf("relabeled ciphertext accepted.")
+	}
+	// Proof scalars plus the group order encode the same proof, and are rejected
+	limit := new(big.Int).Lsh(big.NewInt(1), 256)
+	for {
+		share, err := sk.DecryptShareCCA(ct)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		b := share.ToBytes()
+		e := new(big.Int).Add(new(big.Int).SetBytes(b[fpByteSize:fpByteSize+32]), frOrder)
+		if e.Cmp(limit) >= 0 {
+			continue
+		}
+		e.FillBytes(b[fpByteSize : fpByteSize+32])
+		if _, err := BytesToDecryptionShare(b); !errors.Is(err, ErrEncoding) {
+			t.Fatalf("non-canonical proof accepted.")
+		}
+		break
+	}
+	if _, err := BytesToDLEQProof(append(frOrder.FillBytes(make([]byte, 32)), make([]byte, 32)...)); err == nil {
+		t.Fatalf("non-canonical proof accepted.")
+	}
+}
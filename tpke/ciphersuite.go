@@ -0,0 +1,151 @@
+package tpke
+
+import (
+	bls "github.com/txhsl/tpke/internal/bls"
+)
+
+// Ciphersuite selects how messages are signed, following the BASIC, AUG and POP schemes of the
+// IETF BLS signature draft. POP is the default, and proofs of possession are made with PopProve
+type Ciphersuite byte
+
+const (
+	SuitePOP Ciphersuite = iota
+	SuiteBasic
+	// Messages are prefixed with the public key, the global key for threshold signatures
+	SuiteAug
+)
+
+// Domain separation tags of signatures, indexed by scheme then ciphersuite
+var suiteDomains = [2][3][]byte{
+	MinPubKey: {
+		SuitePOP:   Domain,
+		SuiteBasic: []byte("BLS_SIG_BLS12381G2_XMD:SHA-256_SSWU_RO_NUL_"),
+		SuiteAug:   []byte("BLS_SIG_BLS12381G2_XMD:SHA-256_SSWU_RO_AUG_"),
+	},
+	MinSig: {
+		SuitePOP:   DomainMinSig,
+		SuiteBasic: []byte("BLS_SIG_BLS12381G1_XMD:SHA-256_SSWU_RO_NUL_"),
+		SuiteAug:   []byte("BLS_SIG_BLS12381G1_XMD:SHA-256_SSWU_RO_AUG_"),
+	},
+}
+
+// Domain separation tags of proofs of possession
+var popDomains = [2][]byte{
+	MinPubKey: []byte("BLS_POP_BLS12381G2_XMD:SHA-256_SSWU_RO_POP_"),
+	MinSig:    []byte("BLS_POP_BLS12381G1_XMD:SHA-256_SSWU_RO_POP_"),
+}
+
+func (c Ciphersuite) valid() bool {
+	return c == SuitePOP || c == SuiteBasic || c == SuiteAug
+}
+
+// Message and domain to hash, key is the compressed public key prefixed by the aug suite
+func (c Ciphersuite) input(scheme Scheme, key []byte, msg []byte) ([]byte, []byte) {
+	if c == SuiteAug {
+		msg = append(append([]byte{}, key...), msg...)
+	}
+	return msg, suiteDomains[scheme][c]
+}
+
+// Compressed key in the group of the scheme, G2 for min-sig
+func (pk *PublicKey) schemeKey() []byte {
+	if pk.GetScheme() == MinSig {
+		return bls.NewG2().ToCompressed(pk.pg2)
+	}
+	return bls.NewG1().ToCompressed(pk.pg1)
+}
+
+// SetCiphersuite sets the ciphersuite of signatures verified by the key
+func (pk *PublicKey) SetCiphersuite(suite Ciphersuite) {
+	pk.suite = suite
+}
+
+func (pk *PublicKey) GetCiphersuite() Ciphersuite {
+	return pk.suite
+}
+
+// SetCiphersuite sets the ciphersuite of signature shares.
+// For the aug suite, messages are prefixed with the global public key pub, or the key of sk if nil
+func (sk *PrivateKey) SetCiphersuite(suite Ciphersuite, pub *PublicKey) {
+	sk.suite = suite
+	sk.augKey = nil
+	if suite == SuiteAug && pub != nil {
+		sk.augKey = pub.schemeKey()
+	}
+}
+
+func (sk *PrivateKey) GetCiphersuite() Ciphersuite {
+	return sk.suite
+}
+
+func (sk *PrivateKey) signInput(msg []byte) ([]byte, []byte) {
+	return sk.suite.input(sk.scheme, sk.signKey(), msg)
+}
+
+// Key prepended to messages of the aug suite, the global public key if set
+func (sk *PrivateKey) signKey() []byte {
+	if sk.suite == SuiteAug && sk.augKey == nil {
+		return sk.GetPublicKey().schemeKey()
+	}
+	return sk.augKey
+}
+
+// PopProve proves possession of the key, pi=sk*H(PK) with the pop domain, where PK is
+// the verification key of the share
+func (sk *PrivateKey) PopProve() *Signature {
+	key := sk.GetPublicKey().schemeKey()
+	if sk.scheme == MinSig {
+		g1 := bls.NewG1()
+		g1Hash, _ := g1.HashToCurve(key, popDomains[MinSig])
+		return NewMinSigSignature(g1.MulScalar(g1.New(), g1Hash, sk.fr))
+	}
+	g2 := bls.NewG2()
+	g2Hash, _ := g2.HashToCurve(key, popDomains[MinPubKey])
+	return NewSignature(g2.MulScalar(g2.New(), g2Hash, sk.fr))
+}
+
+// PopVerify checks a proof of possession of the key
+func (pk *PublicKey) PopVerify(proof *Signature) bool {
+	if proof == nil {
+		return false
+	}
+	key := pk.schemeKey()
+	if pk.GetScheme() == MinSig {
+		g1Hash, _ := bls.NewG1().HashToCurve(key, popDomains[MinSig])
+		return verifyG1Sig(pk.pg2, g1Hash, proof.pg1)
+	}
+	g2Hash, _ := bls.NewG2().HashToCurve(key, popDomains[MinPubKey])
+	return verifyG2Sig(pk.pg1, g2Hash, proof.pg2)
+}
+
+// PopVerifyShare checks a proof of possession of the verification key of participant index
+func (pk *PublicKey) PopVerifyShare(index int, proof *Signature) bool {
+	vk := pk.GetVerificationKey(index)
+	return vk != nil && vk.PopVerify(proof)
+}
+
+// The global key of the aug suite is a compressed key in the group of the scheme, empty if the key of sk is used
+func decodeAugKey(scheme Scheme, suite Ciphersuite, b []byte) ([]byte, error) {
+	if len(b) == 0 {
+		return nil, nil
+	}
+	if suite != SuiteAug {
+		return nil, NewEncodingLengthError()
+	}
+	var err error
+	if scheme == MinSig {
+		if len(b) != 2*fpByteSize {
+			return nil, NewEncodingLengthError()
+		}
+		_, err = decodeG2(b)
+	} else {
+		if len(b) != fpByteSize {
+			return nil, NewEncodingLengthError()
+		}
+		_, err = decodeG1(b)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte{}, b...), nil
+}
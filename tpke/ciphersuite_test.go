@@ -0,0 +1,169 @@
+package tpke
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"testing"
+
+	bls "github.com/txhsl/tpke/internal/bls"
+)
+
+// Vectors of Kryptology bls_sig, SigBasic/SigAug/SigPop and their Vt (min-sig) variants,
+// with the key generated by KeygenWithSeed(0x01..0x20)
+var kryptologyVectors = struct {
+	sk, pk, pkMinSig string
+	sigs             [2][3]string // [scheme][suite]
+	proofs           [2]string
+}{
+	sk:       "6d282676c1798109d9156328d858a481ef8855eeccdeb82e4c14e6f2c71ab04c",
+	pk:       "a94be725aa82373cebc022086b9ee21432026c2580c17f9da0265fd38cf9e716db041b2d7ed7128eaa7365cc8886963a",
+	pkMinSig: "81c2f7f9244ead8e5aa7190b332c0199d77e9898350b3314c389375f652618ab9ffd4f37be1a3b5c4799574a9f38d19d1254c5cba0b319c2f4a4b5899756541cf422add2feca68cd6512c66d85bf91108357869a7fc7e3ea3486401a31f7d692",
+	sigs: [2][3]string{
+		MinPubKey: {
+			SuitePOP:   "b64314b7601cf176cedb764e8ca2b9196701b0faa8779d4eb54e98bbf6af138ec6649471cd46caea35efd7772257f57a088ebc9629c4f45458172babc59a296c4b56f9742501b0f259cb3e36a13bb420b5f357e8dbda6d36a26c20d9fd9801e7",
+			SuiteBasic: "a8b9434137e7f4afd907c05387193cd44ac7ed3b0630a74d7ff774bd7d155516edb649b79d64bd0f0ce458e36cb4a057104184a0cf9d210715d3d968211d73643a5dafbc22737e6209c14239ef3595940d5935dbe3cee7213bae32f5cfbcd638",
+			SuiteAug:   "91fa2e586adbd87173af25be012cdd5295237bdcca892bfa195e53700e7cf9ce069f1bacb25490c60bac13cba0976c2c00dfe10c27eb97ac5a49dd1114891926f1082469c7afda458ba385636e39d8ec1cee9445f98c791a19a10d162a28d1e1",
+		},
+		MinSig: {
+			SuitePOP:   "a57a27807d8e2598f674f28091980ec0365792455426ac0ce6e8d46a86cddf7f861dc444b792ecac42044753dd8da6d8",
+			SuiteBasic: "943879c3ce0cb0226abcd64d69ef9c879850e0b584b84b76a134af07a911dc63c5d38d734fb209d84add825bca4b04de",
+			SuiteAug:   "a0a6c410bda089cb0d1b6114908fe60c3550413f00787db13cd7afcb9db9f1f89735c0094998c48d74b6325ee165779e",
+		},
+	},
+	proofs: [2]string{
+		MinPubKey: "afdccc84a22a1d338f5c5348ae63b918b09281ac37a634c75b9e0ea46269e874dbd76bd891a74793686626c56ea7965b10568d603bde8f2de455ea4664655603bf18ef61aa6b4a437ded087a66482f5a3e1372bc85b86211b7c4589f34472f67",
+		MinSig:    "a501bd8bc27e152844b8a458cd4caf79818946cb92fd3083e598d67fe27b6dd183f5f5bf308eeb594eb3d05dd8dbcf79",
+	},
+}
+
+func TestCiphersuiteVectors(t *testing.T) {
+	v := kryptologyVectors
+	b, _ := hex.DecodeString(v.sk)
+	fr, err := decodeFr(b)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	msg := []byte("pizza pizza pizza pizza pizza pizza pizza pizza pizza pizza pizza pizza pizza")
+	for _, scheme := range []Scheme{MinPubKey, MinSig} {
+		sk := &PrivateKey{
+			fr:     fr,
+			scheme: scheme,
+		}
+		pk := sk.GetPublicKey()
+		expected := v.pk
+		if scheme == MinSig {
+			expected = v.pkMinSig
+		}
+		if hex.EncodeToString(pk.schemeKey()) != expected {
+			t.Fatalf("public key mismatch.")
+		}
+		for _, suite := range []Ciphersuite{SuitePOP, SuiteBasic, SuiteAug} {
+			sk.SetCiphersuite(suite, nil)
+			pk.SetCiphersuite(suite)
+			share := sk.SignShare(msg)
+			if hex.EncodeToString(share.ToBytes()) != v.sigs[scheme][suite] {
+				t.Fatalf("signature mismatch, scheme %d, suite %d.", scheme, suite)
+			}
+			b, _ := hex.DecodeString(v.sigs[scheme][suite])
+			sig, err := BytesToSig(b)
+			if err != nil {
+				t.Fatalf(err.Error())
+			}
+			if !pk.VerifySig(msg, sig) {
+				t.Fatalf("signature rejected, scheme %d, suite %d.", scheme, suite)
+			}
+			// Suites do not accept signatures of each other
+			pk.SetCiphersuite((suite + 1) % 3)
+			if pk.VerifySig(msg, sig) {
+				t.Fatalf("signature of another suite accepted.")
+			}
+		}
+		proof := sk.PopProve()
+		if hex.EncodeToString(proof.ToBytes()) != v.proofs[scheme] || !pk.PopVerify(proof) {
+			t.Fatalf("proof of possession mismatch, scheme %d.", scheme)
+		}
+	}
+}
+
+func TestCiphersuiteThreshold(t *testing.T) {
+	size := 5
+	threshold := 3
+	msg := []byte("pizza pizza pizza pizza pizza pizza pizza pizza pizza pizza pizza pizza pizza")
+	for _, scheme := range []Scheme{MinPubKey, MinSig} {
+		dkg := NewDKGWithScheme(size, threshold, scheme, nil)
+		dkg.SetCiphersuite(SuiteAug)
+		if err := dkg.Prepare(); err != nil {
+			t.Fatalf(err.Error())
+		}
+		if err := dkg.Verify(); err != nil {
+			t.Fatalf(err.Error())
+		}
+		sks := dkg.GetPrivateKeys()
+		pk := dkg.PublishGlobalPublicKey()
+
+		// Reloaded keys keep the suite and the global key of the aug suite
+		if reloaded, err := BytesToPublicKey(pk.ToBytes()); err != nil || reloaded.GetCiphersuite() != SuiteAug {
+			t.Fatalf("public key lost the ciphersuite.")
+		}
+		var reloadedPK PublicKey
+		if b, err := json.Marshal(pk); err != nil || json.Unmarshal(b, &reloadedPK) != nil || reloadedPK.GetCiphersuite() != SuiteAug {
+			t.Fatalf("public key lost the ciphersuite in json.")
+		}
+		for i, sk := range sks {
+			reloaded, err := BytesToPrivateKey(sk.ToBytes())
+			if err != nil {
+				t.Fatalf(err.Error())
+			}
+			var fromJSON PrivateKey
+			b, err := json.Marshal(sk)
+			if err != nil {
+				t.Fatalf(err.Error())
+			}
+			if err := json.Unmarshal(b, &fromJSON); err != nil {
+				t.Fatalf(err.Error())
+			}
+			for _, r := range []*PrivateKey{reloaded, &fromJSON} {
+				if r.GetCiphersuite() != SuiteAug || !pk.VerifySigShare(i, msg, r.SignShare(msg)) {
+					t.Fatalf("reloaded share signs with another suite.")
+				}
+			}
+		}
+
+		inputs := make(map[int]*SignatureShare)
+		for i, sk := range sks {
+			if !pk.PopVerifyShare(i, sk.PopProve()) {
+				t.Fatalf("proof of possession rejected.")
+			}
+			inputs[i] = sk.SignShare(msg)
+		}
+		if bad := pk.VerifySigShares(msg, inputs); len(bad) != 0 {
+			t.Fatalf("valid shares rejected %v.", bad)
+		}
+		sig, err := AggregateAndVerifySig(pk, msg, threshold, inputs, 1)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+
+		// Same as the signature of the global secret, signed by a single aug signer
+		indices := []int{1, 2, 3}
+		coeff, _ := lagrangeCoefficients(indices)
+		secret := bls.NewFr().Zero()
+		for k, i := range indices {
+			minor := bls.NewFr()
+			minor.Mul(coeff[k], sks[i].fr)
+			secret.Add(secret, minor)
+		}
+		single := &PrivateKey{
+			fr:     secret,
+			scheme: scheme,
+		}
+		single.SetCiphersuite(SuiteAug, nil)
+		if !bytes.Equal(sig.ToBytes(), single.SignShare(msg).ToBytes()) {
+			t.Fatalf("threshold signature differs from the single signer.")
+		}
+		if pk.PopVerifyShare(1, sks[2].PopProve()) {
+			t.Fatalf("proof of another share accepted.")
+		}
+	}
+}
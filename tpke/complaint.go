@@ -0,0 +1,136 @@
+package tpke
+
+import (
+	"bytes"
+	"encoding/binary"
+	"sort"
+
+	bls "github.com/txhsl/tpke/internal/bls"
+)
+
+// Complaint is published by a receiver whose share from the dealer fails verification,
+// evidence is the encrypted share that the receiver got from the dealer
+type Complaint struct {
+	Dealer   int
+	Receiver int
+	Evidence []byte
+}
+
+// ComplaintResponse is published by a dealer who reveals the disputed share f(receiver)
+type ComplaintResponse struct {
+	Dealer   int
+	Receiver int
+	Secret   *bls.Fr
+	Blinding *bls.Fr // f'(receiver), only in the two-phase dkg
+}
+
+// Qualify computes the qualified dealer set QUAL, indices start from 1. A dealer is disqualified if
+// its pvss is invalid, or if any complaint against it is not answered with a valid share
+func Qualify(pvsss map[int]*PVSS, complaints []*Complaint, responses []*ComplaintResponse) []int {
+	qual := make([]int, 0, len(pvsss))
+	for dealer, pvss := range pvsss {
+		if pvss == nil || !pvss.Verify() {
+			continue
+		}
+		answered := true
+		for _, c := range complaints {
+			if c.Dealer != dealer {
+				continue
+			}
+			r := findResponse(responses, dealer, c.Receiver)
+			if r == nil || !pvss.VerifySecretShare(c.Receiver, r.Secret) {
+				answered = false
+				break
+			}
+		}
+		if answered {
+			qual = append(qual, dealer)
+		}
+	}
+	sort.Ints(qual)
+	return qual
+}
+
+// Dealers from 1 to size that are not in the sorted qual
+func disqualified(size int, qual []int) []int {
+	out := make([]int, 0)
+	for i, k := 1, 0; i <= size; i++ {
+		if k < len(qual) && qual[k] == i {
+			k++
+			continue
+		}
+		out = append(out, i)
+	}
+	return out
+}
+
+func findResponse(responses []*ComplaintResponse, dealer int, receiver int) *ComplaintResponse {
+	for _, r := range responses {
+		if r.Dealer == dealer && r.Receiver == receiver && r.Secret != nil {
+			return r
+		}
+	}
+	return nil
+}
+
+// Only complaints whose evidence matches the broadcast encrypted share are accepted
+func (c *Complaint) Match(encrypted []byte) bool {
+	return bytes.Equal(c.Evidence, encrypted)
+}
+
+func encodeComplaints(complaints []*Complaint) []byte {
+	out := make([]byte, 0)
+	for _, c := range complaints {
+		out = binary.BigEndian.AppendUint32(out, uint32(c.Dealer))
+		out = appendWithLength(out, c.Evidence)
+	}
+	return out
+}
+
+func decodeComplaints(receiver int, b []byte) ([]*Complaint, error) {
+	complaints := make([]*Complaint, 0)
+	for len(b) > 0 {
+		if len(b) < 4 {
+			return nil, NewDKGMessageError()
+		}
+		dealer := int(binary.BigEndian.Uint32(b[:4]))
+		evidence, rest, ok := readWithLength(b[4:])
+		if !ok {
+			return nil, NewDKGMessageError()
+		}
+		complaints = append(complaints, &Complaint{
+			Dealer:   dealer,
+			Receiver: receiver,
+			Evidence: evidence,
+		})
+		b = rest
+	}
+	return complaints, nil
+}
+
+func encodeComplaintResponses(responses []*ComplaintResponse) []byte {
+	out := make([]byte, 0)
+	for _, r := range responses {
+		out = binary.BigEndian.AppendUint32(out, uint32(r.Receiver))
+		out = append(out, r.Secret.ToBytes()...)
+	}
+	return out
+}
+
+func decodeComplaintResponses(dealer int, b []byte) ([]*ComplaintResponse, error) {
+	// Receiver index and secret
+	itemSize := 4 + 32
+	if len(b)%itemSize != 0 {
+		return nil, NewDKGMessageError()
+	}
+	responses := make([]*ComplaintResponse, len(b)/itemSize)
+	for i := range responses {
+		item := b[i*itemSize : (i+1)*itemSize]
+		responses[i] = &ComplaintResponse{
+			Dealer:   dealer,
+			Receiver: int(binary.BigEndian.Uint32(item[:4])),
+			Secret:   bls.NewFr().FromBytes(item[4:]),
+		}
+	}
+	return responses, nil
+}
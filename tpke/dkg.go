@@ -1,20 +1,31 @@
 package tpke
 
 import (
-	"math/rand"
-	"time"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"io"
 
 	crypto "github.com/ethereum/go-ethereum/crypto"
 	ecies "github.com/ethereum/go-ethereum/crypto/ecies"
-	bls "github.com/kilic/bls12-381"
+	bls "github.com/txhsl/tpke/internal/bls"
 )
 
+var sessionDomain = []byte("TPKE_DKG_SESSION_V1")
+
 type DKG struct {
 	size         int
 	threshold    int
-	scaler       int // Scaler for global public key, to speed up decryption
 	participants []*Participant
 	messageBox   [][][]byte
+	qual         []int  // Qualified dealers, start from 1
+	refresh      bool   // Deal zero secrets to refresh existing shares
+	session      []byte // Context of proofs of knowledge, derived from the committee if nil
+	twoPhase     bool   // Share with Pedersen commitments first, then reveal Feldman commitments of QUAL
+	scheme       Scheme
+	suite        Ciphersuite
+	random       io.Reader
+	err          error // Error of key generation, returned by Prepare
 }
 
 type Participant struct {
@@ -23,118 +34,554 @@ type Participant struct {
 	secret          *Secret
 	pvss            *PVSS
 	receivedSecrets []*bls.Fr
+	random          io.Reader
+	// Blinding polynomial and Pedersen commitment of the two-phase dkg
+	blinding *Secret
+	pedersen *Commitment
 }
 
 func NewDKG(size int, threshold int) *DKG {
-	participants := make([]*Participant, size)
-	source := rand.NewSource(time.Now().UnixNano())
-	random := rand.New(source)
-	for i := 0; i < size; i++ {
-		key, _ := ecies.GenerateKey(random, crypto.S256(), nil)
-		participants[i] = NewParticipant(key)
-	}
-	return &DKG{
+	return NewDKGWithReader(size, threshold, nil)
+}
+
+// All keys, secrets and encryptions of the dkg are sampled from random, crypto/rand if nil.
+// A seeded DRBG makes the whole run reproducible
+func NewDKGWithReader(size int, threshold int, random io.Reader) *DKG {
+	return NewDKGWithScheme(size, threshold, MinPubKey, random)
+}
+
+// Keys of the min-sig scheme are also published in G2, so that signatures are 48 bytes in G1
+func NewDKGWithScheme(size int, threshold int, scheme Scheme, random io.Reader) *DKG {
+	random = randomReader(random)
+	dkg := &DKG{
 		size:         size,
 		threshold:    threshold,
-		scaler:       getEncryptionScaler(size, threshold),
-		participants: participants,
+		participants: make([]*Participant, size),
+		scheme:       scheme,
+		random:       random,
+	}
+	for i := 0; i < size; i++ {
+		key, err := ecies.GenerateKey(random, crypto.S256(), nil)
+		if err != nil {
+			dkg.err = NewDKGError(err.Error()).withCause(err)
+			break
+		}
+		dkg.participants[i] = NewParticipantWithReader(key, random)
+	}
+	return dkg
+}
+
+func (dkg *DKG) GetScheme() Scheme {
+	return dkg.scheme
+}
+
+// SetSession sets the context that proofs of knowledge of dealers are bound to, e.g. a unique session id,
+// so that proofs can not be replayed in another run
+func (dkg *DKG) SetSession(session []byte) {
+	dkg.session = session
+}
+
+// GetSession returns the context of proofs of knowledge. Without a session id, it is derived from the
+// committee, so that proofs of one committee can not be replayed in the run of another
+func (dkg *DKG) GetSession() []byte {
+	if dkg.session != nil {
+		return dkg.session
+	}
+	keys := make([]*ecies.PublicKey, dkg.size)
+	for i, p := range dkg.participants {
+		if p != nil {
+			keys[i] = p.ethPubKey
+		}
 	}
+	return defaultSession(dkg.size, dkg.threshold, dkg.refresh, keys)
 }
 
-func (dkg *DKG) Prepare() {
-	source := rand.NewSource(time.Now().UnixNano())
-	random := rand.New(source)
+// H(size||threshold||refresh||ecies keys of participants)
+func defaultSession(size int, threshold int, refresh bool, keys []*ecies.PublicKey) []byte {
+	h := sha256.New()
+	h.Write(sessionDomain)
+	b := binary.BigEndian.AppendUint32(nil, uint32(size))
+	b = binary.BigEndian.AppendUint32(b, uint32(threshold))
+	if refresh {
+		b = append(b, 1)
+	} else {
+		b = append(b, 0)
+	}
+	h.Write(b)
+	for _, key := range keys {
+		if key != nil {
+			h.Write(crypto.FromECDSAPub(key.ExportECDSA()))
+		}
+	}
+	return h.Sum(nil)
+}
+
+// SetCiphersuite sets the ciphersuite of published keys, POP by default
+func (dkg *DKG) SetCiphersuite(suite Ciphersuite) {
+	dkg.suite = suite
+}
+
+func (dkg *DKG) Prepare() error {
+	if dkg.err != nil {
+		return dkg.err
+	}
 	dkg.messageBox = make([][][]byte, dkg.size)
 	for i := 0; i < dkg.size; i++ {
 		dkg.messageBox[i] = make([][]byte, dkg.size)
 	}
+	if dkg.twoPhase {
+		return dkg.preparePedersen()
+	}
 	for i := 0; i < dkg.size; i++ {
-		// Init random polynomial a
-		dkg.participants[i].GenerateSecret(dkg.threshold)
+		// Init random polynomial a, with a0=0 for refresh
+		var err error
+		if dkg.refresh {
+			err = dkg.participants[i].GenerateZeroSecret(dkg.threshold)
+		} else {
+			err = dkg.participants[i].GenerateSecret(dkg.threshold)
+		}
+		if err != nil {
+			return NewDKGError(err.Error()).withCause(err)
+		}
 		// Compute PVSS
-		sharedSecrets := dkg.participants[i].GenerateShares(dkg.size)
+		sharedSecrets, err := dkg.participants[i].GenerateShares(dkg.size)
+		if err != nil {
+			return NewDKGError(err.Error()).withCause(err)
+		}
+		if dkg.scheme == MinSig {
+			dkg.participants[i].pvss.setG2Shares(dkg.participants[i].secret.poly.coeff[0], sharedSecrets)
+		}
+		if err := dkg.participants[i].ProveSecret(dkg.GetSession(), i+1); err != nil {
+			return NewDKGError(err.Error()).withCause(err)
+		}
 		// Send messages
 		for j := 0; j < dkg.size; j++ {
 			sharedSecret := sharedSecrets[j].ToBytes()
-			msg, _ := ecies.Encrypt(random, dkg.participants[j].ethPubKey, sharedSecret[:32], nil, nil)
+			msg, err := ecies.Encrypt(dkg.random, dkg.participants[j].ethPubKey, sharedSecret[:32], nil, nil)
+			if err != nil {
+				return NewDKGError(err.Error()).withCause(err)
+			}
 			dkg.messageBox[j][i] = msg
 		}
 	}
+	return nil
 }
 
 func (dkg *DKG) Verify() error {
-	for i := 0; i < dkg.size; i++ {
-		// Verify PVSS
-		if !dkg.participants[i].VerifyPVSS() {
-			return NewDKGPVSSError()
-		}
+	complaints := dkg.Complain()
+	responses := dkg.Respond(complaints)
+	if err := dkg.Finalize(complaints, responses); err != nil {
+		return err
 	}
-	g1 := bls.NewG1()
-	pairing := bls.NewEngine()
+	if dkg.twoPhase {
+		return dkg.Reveal()
+	}
+	return nil
+}
+
+// Receivers verify their shares, and complain about dealers who sent invalid ones
+func (dkg *DKG) Complain() []*Complaint {
+	if dkg.twoPhase {
+		return dkg.complainPedersen()
+	}
+	complaints := make([]*Complaint, 0)
 	for i := 0; i < dkg.size; i++ {
 		dkg.participants[i].receivedSecrets = make([]*bls.Fr, dkg.size)
-		// Verify received secrets
 		for j := 0; j < dkg.size; j++ {
-			ss, _ := dkg.participants[i].ethPrvKey.Decrypt(dkg.messageBox[i][j], nil, nil)
-			// e(r1*fi,g2)=e(bigfi,r2)
-			fi := bls.NewFr().FromBytes(ss)
-			commitment := dkg.participants[j].pvss
-			r1 := g1.New().Set(commitment.r1)
-			e1 := pairing.AddPair(g1.MulScalar(r1, r1, fi), &bls.G2One).Result()
-			e2 := pairing.AddPair(commitment.bigf[i], commitment.r2).Result()
-			if !e1.Equal(e2) {
-				return NewDKGSecretError()
+			fi := dkg.participants[i].ReceiveSecret(i+1, dkg.participants[j].pvss, dkg.messageBox[i][j])
+			if fi == nil {
+				complaints = append(complaints, &Complaint{
+					Dealer:   j + 1,
+					Receiver: i + 1,
+					Evidence: dkg.messageBox[i][j],
+				})
+				continue
 			}
 			// Cache received secrets
 			dkg.participants[i].receivedSecrets[j] = fi
 		}
 	}
+	return complaints
+}
+
+// Dealers answer complaints by revealing the disputed shares
+func (dkg *DKG) Respond(complaints []*Complaint) []*ComplaintResponse {
+	responses := make([]*ComplaintResponse, 0, len(complaints))
+	for _, c := range complaints {
+		dealer := dkg.participants[c.Dealer-1]
+		r := &ComplaintResponse{
+			Dealer:   c.Dealer,
+			Receiver: c.Receiver,
+			Secret:   dealer.RevealSecret(c.Receiver),
+		}
+		if dkg.twoPhase && dealer.blinding != nil {
+			r.Blinding = dealer.blinding.Evaluate(*frFromInt(c.Receiver))
+		}
+		responses = append(responses, r)
+	}
+	return responses
+}
+
+// Compute QUAL, then receivers take revealed shares from qualified dealers.
+// In the two-phase dkg, QUAL is computed from Pedersen commitments, and Reveal follows
+func (dkg *DKG) Finalize(complaints []*Complaint, responses []*ComplaintResponse) error {
+	// Drop complaints with fake evidence
+	accepted := make([]*Complaint, 0, len(complaints))
+	for _, c := range complaints {
+		if c.Dealer <= 0 || c.Dealer > dkg.size || c.Receiver <= 0 || c.Receiver > dkg.size {
+			continue
+		}
+		if c.Match(dkg.messageBox[c.Receiver-1][c.Dealer-1]) {
+			accepted = append(accepted, c)
+		}
+	}
+	if dkg.twoPhase {
+		return dkg.finalizePedersen(accepted, responses)
+	}
+	pvsss := make(map[int]*PVSS)
+	for i := 0; i < dkg.size; i++ {
+		pvsss[i+1] = dkg.participants[i].pvss
+		// Dealers must commit to a polynomial of degree threshold-1, with a public share for every participant
+		if pvsss[i+1] != nil && (len(pvsss[i+1].bigf) != dkg.size || len(pvsss[i+1].public.commitment.coeff) != dkg.threshold) {
+			pvsss[i+1] = nil
+		}
+		// Dealers must not change the secret when refreshing
+		if dkg.refresh && pvsss[i+1] != nil && !pvsss[i+1].IsZeroSecret() {
+			pvsss[i+1] = nil
+		}
+		// Dealers must publish shares in G2 for the min-sig scheme
+		if dkg.scheme == MinSig && pvsss[i+1] != nil && !pvsss[i+1].HasG2Shares() {
+			pvsss[i+1] = nil
+		}
+		// Dealers must prove knowledge of their secrets
+		if pvsss[i+1] != nil && !pvsss[i+1].VerifySecretProof(dkg.GetSession(), i+1) {
+			pvsss[i+1] = nil
+		}
+	}
+	qual := Qualify(pvsss, accepted, responses)
+	if len(qual) == 0 {
+		return NewDKGQualError().withParticipants(disqualified(dkg.size, qual)...)
+	}
+	for _, dealer := range qual {
+		for _, c := range accepted {
+			if c.Dealer == dealer {
+				r := findResponse(responses, dealer, c.Receiver)
+				dkg.participants[c.Receiver-1].receivedSecrets[dealer-1] = bls.NewFr().Set(r.Secret)
+			}
+		}
+	}
+	dkg.qual = qual
 	return nil
 }
 
+func (dkg *DKG) GetQualifiedDealers() []int {
+	if dkg.qual == nil {
+		qual := make([]int, dkg.size)
+		for i := range qual {
+			qual[i] = i + 1
+		}
+		return qual
+	}
+	return dkg.qual
+}
+
 func (dkg *DKG) PublishGlobalPublicKey() *PublicKey {
-	// Compute public key S=sum(A0)
-	scs := make([]*SecretCommitment, dkg.size)
-	for i := 0; i < dkg.size; i++ {
-		scs[i] = dkg.participants[i].pvss.public
+	// Compute public key S=sum(A0), j in QUAL
+	qual := dkg.GetQualifiedDealers()
+	scs := make([]*SecretCommitment, len(qual))
+	for i, j := range qual {
+		scs[i] = dkg.participants[j-1].pvss.public
+	}
+	pk := NewPublicKey(scs)
+	if dkg.scheme == MinSig {
+		// Compute public key in G2 S'=sum(A0'), j in QUAL
+		g2 := bls.NewG2()
+		pk.pg2 = g2.New()
+		for _, j := range qual {
+			g2.Add(pk.pg2, pk.pg2, dkg.participants[j-1].pvss.a0G2)
+		}
 	}
-	return NewGlobalPublicKey(scs, dkg.scaler)
+	pk.SetVerificationKeys(dkg.PublishVerificationKeys())
+	pk.SetCiphersuite(dkg.suite)
+	return pk
+}
+
+func (dkg *DKG) PublishVerificationKeys() map[int]*PublicKey {
+	// Compute verification keys VK_i=sum(F_j(i)), j in QUAL
+	qual := dkg.GetQualifiedDealers()
+	pvsss := make([]*PVSS, len(qual))
+	for i, j := range qual {
+		pvsss[i] = dkg.participants[j-1].pvss
+	}
+	return NewVerificationKeys(pvsss)
 }
 
 func (dkg *DKG) GetPrivateKeys() map[int]*PrivateKey {
+	qual := dkg.GetQualifiedDealers()
+	var pub *PublicKey
+	if dkg.suite == SuiteAug {
+		pub = dkg.PublishGlobalPublicKey()
+	}
 	pks := make(map[int]*PrivateKey)
 	for i := 0; i < dkg.size; i++ {
-		pks[i+1] = NewPrivateKey(dkg.participants[i].receivedSecrets)
+		secrets := make([]*bls.Fr, len(qual))
+		for k, j := range qual {
+			secrets[k] = dkg.participants[i].receivedSecrets[j-1]
+		}
+		pks[i+1] = NewPrivateKey(secrets)
+		pks[i+1].scheme = dkg.scheme
+		pks[i+1].SetCiphersuite(dkg.suite, pub)
 	}
 	return pks
 }
 
+// Deprecated: shares are combined with lagrange coefficients in Fr, the global public key is not scaled anymore
 func (dkg *DKG) GetScaler() int {
-	return dkg.scaler
+	return 1
 }
 
 func NewParticipant(key *ecies.PrivateKey) *Participant {
+	return NewParticipantWithReader(key, nil)
+}
+
+// Secrets of the participant are sampled from random, crypto/rand if nil
+func NewParticipantWithReader(key *ecies.PrivateKey, random io.Reader) *Participant {
 	return &Participant{
 		ethPrvKey: key,
 		ethPubKey: &key.PublicKey,
+		random:    randomReader(random),
+	}
+}
+
+func (p *Participant) GenerateSecret(threshold int) error {
+	secret, err := RandomSecretWithReader(threshold, p.random)
+	if err != nil {
+		return err
 	}
+	p.secret = secret
+	return nil
 }
 
-func (p *Participant) GenerateSecret(threshold int) {
-	p.secret = RandomSecret(threshold)
+func (p *Participant) GenerateZeroSecret(threshold int) error {
+	secret, err := newSecret(bls.NewFr().Zero(), threshold, p.random)
+	if err != nil {
+		return err
+	}
+	p.secret = secret
+	return nil
 }
 
-func (p *Participant) GenerateShares(size int) []*bls.Fr {
+func (p *Participant) GenerateShares(size int) ([]*bls.Fr, error) {
 	// Generate local random number
-	source := rand.NewSource(time.Now().UnixNano())
-	random := rand.New(source)
-	r, _ := bls.NewFr().Rand(random)
-
+	r, err := randomFr(p.random)
+	if err != nil {
+		return nil, err
+	}
 	pvss, ss := GenerateSharedSecrets(r, size, p.secret)
 	p.pvss = pvss
-	return ss
+	return ss, nil
+}
+
+// ProveSecret attaches a proof of knowledge of a0 to the pvss, bound to the session context and the dealer index
+func (p *Participant) ProveSecret(session []byte, dealer int) error {
+	if p.secret == nil || p.pvss == nil {
+		return NewDKGSecretError()
+	}
+	return p.pvss.proveSecret(p.random, session, dealer, p.secret.poly.coeff[0])
 }
 
 func (p *Participant) VerifyPVSS() bool {
 	return p.pvss.Verify()
 }
+
+// Decrypt and verify the share from a dealer, returns nil if invalid
+func (p *Participant) ReceiveSecret(index int, pvss *PVSS, encrypted []byte) *bls.Fr {
+	if pvss == nil {
+		return nil
+	}
+	ss, err := p.ethPrvKey.Decrypt(encrypted, nil, nil)
+	if err != nil {
+		return nil
+	}
+	// e(r1*fi,g2)=e(bigfi,r2)
+	fi := bls.NewFr().FromBytes(ss)
+	if !pvss.VerifySecretShare(index, fi) {
+		return nil
+	}
+	return fi
+}
+
+// Reveal the share f(index) to answer a complaint
+func (p *Participant) RevealSecret(index int) *bls.Fr {
+	if p.secret == nil {
+		return nil
+	}
+	return p.secret.Evaluate(*frFromInt(index))
+}
+
+// Encode the ecies key, the secret, the pvss and received shares, missing parts are empty.
+// A participant is restored with crypto/rand as its random source
+func (p *Participant) ToBytes() []byte {
+	out := append(encodingHeader(encodingParticipant), p.ethPrvKey.D.FillBytes(make([]byte, 32))...)
+	var secret, pvss []byte
+	if p.secret != nil {
+		secret = appendFrs(nil, p.secret.poly.coeff)
+	}
+	if p.pvss != nil {
+		pvss = p.pvss.ToBytes()
+	}
+	out = appendWithLength(out, secret)
+	out = appendWithLength(out, pvss)
+	out = binary.BigEndian.AppendUint32(out, uint32(len(p.receivedSecrets)))
+	for _, fi := range p.receivedSecrets {
+		var b []byte
+		if fi != nil {
+			b = fi.ToBytes()
+		}
+		out = appendWithLength(out, b)
+	}
+	return out
+}
+
+func BytesToParticipant(b []byte) (*Participant, error) {
+	b, err := readEncodingHeader(b, encodingParticipant)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) < 32 {
+		return nil, NewEncodingLengthError()
+	}
+	p, err := participantFromKey(b[:32])
+	if err != nil {
+		return nil, err
+	}
+	secret, b, ok := readWithLength(b[32:])
+	if !ok {
+		return nil, NewEncodingLengthError()
+	}
+	if len(secret) > 0 {
+		coeff, rest, err := readFrs(secret)
+		if err != nil {
+			return nil, err
+		}
+		if len(rest) != 0 || len(coeff) == 0 {
+			return nil, NewEncodingLengthError()
+		}
+		p.secret = &Secret{
+			poly: Poly{
+				coeff: coeff,
+			},
+		}
+	}
+	pvss, b, ok := readWithLength(b)
+	if !ok || len(b) < 4 {
+		return nil, NewEncodingLengthError()
+	}
+	if len(pvss) > 0 {
+		if p.pvss, err = BytesToPVSS(pvss); err != nil {
+			return nil, err
+		}
+	}
+	n := int(binary.BigEndian.Uint32(b[:4]))
+	b = b[4:]
+	if n > len(b)/4 {
+		return nil, NewEncodingLengthError()
+	}
+	if n > 0 {
+		p.receivedSecrets = make([]*bls.Fr, n)
+	}
+	for i := 0; i < n; i++ {
+		var fi []byte
+		fi, b, ok = readWithLength(b)
+		if !ok {
+			return nil, NewEncodingLengthError()
+		}
+		if len(fi) > 0 {
+			if p.receivedSecrets[i], err = decodeFr(fi); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if len(b) != 0 {
+		return nil, NewEncodingLengthError()
+	}
+	return p, nil
+}
+
+func participantFromKey(b []byte) (*Participant, error) {
+	key, err := crypto.ToECDSA(b)
+	if err != nil {
+		return nil, NewEncodingError(err.Error()).withCause(err)
+	}
+	return NewParticipant(ecies.ImportECDSA(key)), nil
+}
+
+type participantJSON struct {
+	Version         byte       `json:"version"`
+	Key             hexBytes   `json:"key"`
+	Secret          []hexBytes `json:"secret,omitempty"`
+	PVSS            *PVSS      `json:"pvss,omitempty"`
+	ReceivedSecrets []hexBytes `json:"received_secrets,omitempty"` // Empty for missing shares
+}
+
+func (p *Participant) MarshalJSON() ([]byte, error) {
+	v := &participantJSON{
+		Version: EncodingVersion,
+		Key:     p.ethPrvKey.D.FillBytes(make([]byte, 32)),
+		PVSS:    p.pvss,
+	}
+	if p.secret != nil {
+		for _, c := range p.secret.poly.coeff {
+			v.Secret = append(v.Secret, c.ToBytes())
+		}
+	}
+	for _, fi := range p.receivedSecrets {
+		var b hexBytes
+		if fi != nil {
+			b = fi.ToBytes()
+		}
+		v.ReceivedSecrets = append(v.ReceivedSecrets, b)
+	}
+	return json.Marshal(v)
+}
+
+func (p *Participant) UnmarshalJSON(b []byte) error {
+	var v participantJSON
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	if err := checkEncodingVersion(v.Version); err != nil {
+		return err
+	}
+	result, err := participantFromKey(v.Key)
+	if err != nil {
+		return err
+	}
+	if len(v.Secret) > 0 {
+		coeff := make([]*bls.Fr, len(v.Secret))
+		for i, h := range v.Secret {
+			if coeff[i], err = decodeFr(h); err != nil {
+				return err
+			}
+		}
+		result.secret = &Secret{
+			poly: Poly{
+				coeff: coeff,
+			},
+		}
+	}
+	result.pvss = v.PVSS
+	if len(v.ReceivedSecrets) > 0 {
+		result.receivedSecrets = make([]*bls.Fr, len(v.ReceivedSecrets))
+		for i, h := range v.ReceivedSecrets {
+			if len(h) == 0 {
+				continue
+			}
+			if result.receivedSecrets[i], err = decodeFr(h); err != nil {
+				return err
+			}
+		}
+	}
+	*p = *result
+	return nil
+}
@@ -0,0 +1,445 @@
+package tpke
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+
+	ecies "github.com/ethereum/go-ethereum/crypto/ecies"
+	bls "github.com/txhsl/tpke/internal/bls"
+)
+
+type DKGRound int
+
+const (
+	DKGRoundDeal     DKGRound = iota // Waiting for pvss and encrypted shares from all dealers
+	DKGRoundVerify                   // Verifying received shares
+	DKGRoundComplain                 // Waiting for complaints from all participants
+	DKGRoundRespond                  // Waiting for dealers to answer complaints
+	DKGRoundFinalize                 // Key generation finished
+)
+
+type DKGMessageType byte
+
+const (
+	DKGDealMessage DKGMessageType = iota + 1
+	DKGComplaintMessage
+	DKGResponseMessage
+)
+
+// DKGMessage is broadcast to all participants, the sender is authenticated by the transport
+type DKGMessage struct {
+	Type    DKGMessageType
+	From    int
+	Payload []byte
+}
+
+func (m *DKGMessage) ToBytes() []byte {
+	out := make([]byte, 5, 5+len(m.Payload))
+	out[0] = byte(m.Type)
+	binary.BigEndian.PutUint32(out[1:5], uint32(m.From))
+	return append(out, m.Payload...)
+}
+
+func BytesToDKGMessage(b []byte) (*DKGMessage, error) {
+	if len(b) < 5 {
+		return nil, NewDKGMessageError()
+	}
+	return &DKGMessage{
+		Type:    DKGMessageType(b[0]),
+		From:    int(binary.BigEndian.Uint32(b[1:5])),
+		Payload: b[5:],
+	}, nil
+}
+
+type dkgDeal struct {
+	pvss      *PVSS
+	encrypted [][]byte // Encrypted shares for all participants
+	secret    *bls.Fr  // Decrypted share for this participant, nil if invalid
+}
+
+// DKGSession runs the dkg for a single participant, indices start from 1
+type DKGSession struct {
+	index       int
+	size        int
+	threshold   int
+	participant *Participant
+	peers       map[int]*ecies.PublicKey
+	round       DKGRound
+	deals       map[int]*dkgDeal
+	complaints  map[int][]*Complaint
+	responses   map[int][]*ComplaintResponse
+	qual        []int
+	silent      map[int]bool // Dealers who left complaints unanswered at the deadline
+	session     []byte       // Context of proofs of knowledge, the same for all participants, derived from peers if nil
+	publicKey   *PublicKey
+	privateKey  *PrivateKey
+}
+
+func NewDKGSession(index int, size int, threshold int, key *ecies.PrivateKey, peers map[int]*ecies.PublicKey) *DKGSession {
+	return NewDKGSessionWithReader(index, size, threshold, key, peers, nil)
+}
+
+// The local secret and share encryptions are sampled from random, crypto/rand if nil
+func NewDKGSessionWithReader(index int, size int, threshold int, key *ecies.PrivateKey, peers map[int]*ecies.PublicKey, random io.Reader) *DKGSession {
+	return &DKGSession{
+		index:       index,
+		size:        size,
+		threshold:   threshold,
+		participant: NewParticipantWithReader(key, random),
+		peers:       peers,
+		round:       DKGRoundDeal,
+		deals:       make(map[int]*dkgDeal),
+		complaints:  make(map[int][]*Complaint),
+		responses:   make(map[int][]*ComplaintResponse),
+		silent:      make(map[int]bool),
+	}
+}
+
+// SetSession sets the context that proofs of knowledge of dealers are bound to, before Deal
+func (s *DKGSession) SetSession(session []byte) {
+	s.session = session
+}
+
+// GetSession returns the context of proofs of knowledge, derived from the committee as in DKG.GetSession if not set
+func (s *DKGSession) GetSession() []byte {
+	if s.session != nil {
+		return s.session
+	}
+	keys := make([]*ecies.PublicKey, s.size)
+	for j := 1; j <= s.size; j++ {
+		keys[j-1] = s.peers[j]
+	}
+	return defaultSession(s.size, s.threshold, false, keys)
+}
+
+func (s *DKGSession) Round() DKGRound {
+	return s.round
+}
+
+func (s *DKGSession) Finished() bool {
+	return s.round == DKGRoundFinalize
+}
+
+// Deal generates the local secret and returns the deal message to broadcast
+func (s *DKGSession) Deal() ([]*DKGMessage, error) {
+	if s.round != DKGRoundDeal || s.participant.pvss != nil {
+		return nil, NewDKGRoundError()
+	}
+	// Init random polynomial a and compute PVSS
+	if err := s.participant.GenerateSecret(s.threshold); err != nil {
+		return nil, NewDKGError(err.Error()).withCause(err)
+	}
+	sharedSecrets, err := s.participant.GenerateShares(s.size)
+	if err != nil {
+		return nil, NewDKGError(err.Error()).withCause(err)
+	}
+	if err := s.participant.ProveSecret(s.GetSession(), s.index); err != nil {
+		return nil, NewDKGError(err.Error()).withCause(err)
+	}
+
+	// Encrypt shares for every participant
+	payload := appendWithLength(nil, s.participant.pvss.ToBytes())
+	for j := 1; j <= s.size; j++ {
+		peer, ok := s.peers[j]
+		if !ok {
+			return nil, NewDKGSenderError()
+		}
+		sharedSecret := sharedSecrets[j-1].ToBytes()
+		msg, err := ecies.Encrypt(s.participant.random, peer, sharedSecret[:32], nil, nil)
+		if err != nil {
+			return nil, NewDKGError(err.Error()).withCause(err)
+		}
+		payload = appendWithLength(payload, msg)
+	}
+	deal := &DKGMessage{
+		Type:    DKGDealMessage,
+		From:    s.index,
+		Payload: payload,
+	}
+	out, err := s.handle(deal)
+	if err != nil {
+		return nil, err
+	}
+	return append([]*DKGMessage{deal}, out...), nil
+}
+
+// OnMessage handles a message from another participant, and returns messages to broadcast
+func (s *DKGSession) OnMessage(from int, b []byte) ([]*DKGMessage, error) {
+	msg, err := BytesToDKGMessage(b)
+	if err != nil {
+		return nil, err
+	}
+	if msg.From != from || from == s.index {
+		return nil, NewDKGSenderError().withParticipants(from)
+	}
+	return s.handle(msg)
+}
+
+func (s *DKGSession) handle(msg *DKGMessage) ([]*DKGMessage, error) {
+	if msg.From <= 0 || msg.From > s.size {
+		return nil, NewDKGSenderError().withParticipants(msg.From)
+	}
+	var err error
+	switch msg.Type {
+	case DKGDealMessage:
+		err = s.onDeal(msg.From, msg.Payload)
+	case DKGComplaintMessage:
+		err = s.onComplaint(msg.From, msg.Payload)
+	case DKGResponseMessage:
+		err = s.onResponse(msg.From, msg.Payload)
+	default:
+		err = NewDKGMessageError()
+	}
+	if err != nil {
+		// Blame the sender
+		var e *CustomError
+		if errors.As(err, &e) && e.Participants == nil {
+			e.withParticipants(msg.From)
+		}
+		return nil, err
+	}
+	return s.advance()
+}
+
+// Timeout closes the current round at its deadline, so that crashed or silent participants can not stall
+// the dkg. Dealers whose deals are missing or whose complaints are left unanswered are disqualified, and missing
+// complaints or responses count as none.
+// All participants must close a round at the same deadline to agree on QUAL
+func (s *DKGSession) Timeout() ([]*DKGMessage, error) {
+	switch s.round {
+	case DKGRoundDeal:
+		for j := 1; j <= s.size; j++ {
+			if _, ok := s.deals[j]; !ok {
+				s.deals[j] = &dkgDeal{}
+			}
+		}
+	case DKGRoundComplain:
+		for j := 1; j <= s.size; j++ {
+			if _, ok := s.complaints[j]; !ok {
+				s.complaints[j] = make([]*Complaint, 0)
+			}
+		}
+	case DKGRoundRespond:
+		// Only dealers with complaints against them owe a response
+		complained := make(map[int]bool)
+		for _, c := range s.acceptedComplaints() {
+			complained[c.Dealer] = true
+		}
+		for j := 1; j <= s.size; j++ {
+			if _, ok := s.responses[j]; !ok {
+				s.responses[j] = make([]*ComplaintResponse, 0)
+				s.silent[j] = complained[j]
+			}
+		}
+	default:
+		return nil, NewDKGRoundError()
+	}
+	return s.advance()
+}
+
+func (s *DKGSession) onDeal(from int, payload []byte) error {
+	if s.round != DKGRoundDeal {
+		return NewDKGRoundError()
+	}
+	if _, ok := s.deals[from]; ok {
+		return NewDKGMessageError()
+	}
+	// Malformed deals are kept, so that the dealer is disqualified
+	b, rest, ok := readWithLength(payload)
+	if !ok {
+		s.deals[from] = &dkgDeal{}
+		return nil
+	}
+	pvss, err := BytesToPVSS(b)
+	if err != nil || len(pvss.bigf) != s.size || len(pvss.public.commitment.coeff) != s.threshold {
+		s.deals[from] = &dkgDeal{}
+		return nil
+	}
+	encrypted := make([][]byte, s.size)
+	for j := range encrypted {
+		encrypted[j], rest, ok = readWithLength(rest)
+		if !ok {
+			s.deals[from] = &dkgDeal{}
+			return nil
+		}
+	}
+	if len(rest) != 0 {
+		s.deals[from] = &dkgDeal{}
+		return nil
+	}
+	s.deals[from] = &dkgDeal{
+		pvss:      pvss,
+		encrypted: encrypted,
+		secret:    s.participant.ReceiveSecret(s.index, pvss, encrypted[s.index-1]),
+	}
+	return nil
+}
+
+func (s *DKGSession) onComplaint(from int, payload []byte) error {
+	if s.round == DKGRoundFinalize {
+		return NewDKGRoundError()
+	}
+	if _, ok := s.complaints[from]; ok {
+		return NewDKGMessageError()
+	}
+	complaints, err := decodeComplaints(from, payload)
+	if err != nil {
+		return err
+	}
+	for _, c := range complaints {
+		if c.Dealer <= 0 || c.Dealer > s.size {
+			return NewDKGMessageError()
+		}
+	}
+	// Complaints may arrive before all deals are received
+	s.complaints[from] = complaints
+	return nil
+}
+
+func (s *DKGSession) onResponse(from int, payload []byte) error {
+	if s.round == DKGRoundFinalize {
+		return NewDKGRoundError()
+	}
+	if _, ok := s.responses[from]; ok {
+		return NewDKGMessageError()
+	}
+	responses, err := decodeComplaintResponses(from, payload)
+	if err != nil {
+		return err
+	}
+	s.responses[from] = responses
+	return nil
+}
+
+func (s *DKGSession) advance() ([]*DKGMessage, error) {
+	var out []*DKGMessage
+	if s.round == DKGRoundDeal && len(s.deals) == s.size {
+		s.round = DKGRoundVerify
+		// Complain about dealers whose shares are invalid
+		complaints := make([]*Complaint, 0)
+		for j := 1; j <= s.size; j++ {
+			if s.deals[j].secret == nil && s.deals[j].pvss != nil {
+				complaints = append(complaints, &Complaint{
+					Dealer:   j,
+					Receiver: s.index,
+					Evidence: s.deals[j].encrypted[s.index-1],
+				})
+			}
+		}
+		complaint := &DKGMessage{
+			Type:    DKGComplaintMessage,
+			From:    s.index,
+			Payload: encodeComplaints(complaints),
+		}
+		if err := s.onComplaint(s.index, complaint.Payload); err != nil {
+			return nil, err
+		}
+		s.round = DKGRoundComplain
+		out = append(out, complaint)
+	}
+	if s.round == DKGRoundComplain && len(s.complaints) == s.size {
+		// Answer every complaint against this dealer by revealing the disputed shares
+		responses := make([]*ComplaintResponse, 0)
+		for receiver := 1; receiver <= s.size; receiver++ {
+			for _, c := range s.complaints[receiver] {
+				if c.Dealer != s.index {
+					continue
+				}
+				responses = append(responses, &ComplaintResponse{
+					Dealer:   s.index,
+					Receiver: c.Receiver,
+					Secret:   s.participant.RevealSecret(c.Receiver),
+				})
+			}
+		}
+		response := &DKGMessage{
+			Type:    DKGResponseMessage,
+			From:    s.index,
+			Payload: encodeComplaintResponses(responses),
+		}
+		if err := s.onResponse(s.index, response.Payload); err != nil {
+			return nil, err
+		}
+		s.round = DKGRoundRespond
+		out = append(out, response)
+	}
+	if s.round == DKGRoundRespond && len(s.responses) == s.size {
+		if err := s.finalize(); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// Drop complaints whose evidence does not match the broadcast encrypted share
+func (s *DKGSession) acceptedComplaints() []*Complaint {
+	accepted := make([]*Complaint, 0)
+	for receiver := 1; receiver <= s.size; receiver++ {
+		for _, c := range s.complaints[receiver] {
+			if s.deals[c.Dealer].encrypted == nil {
+				continue
+			}
+			if c.Match(s.deals[c.Dealer].encrypted[receiver-1]) {
+				accepted = append(accepted, c)
+			}
+		}
+	}
+	return accepted
+}
+
+func (s *DKGSession) finalize() error {
+	pvsss := make(map[int]*PVSS)
+	for j := 1; j <= s.size; j++ {
+		pvsss[j] = s.deals[j].pvss
+		if s.silent[j] {
+			pvsss[j] = nil
+		}
+		// Dealers must prove knowledge of their secrets
+		if pvsss[j] != nil && !pvsss[j].VerifySecretProof(s.GetSession(), j) {
+			pvsss[j] = nil
+		}
+	}
+	responses := make([]*ComplaintResponse, 0)
+	for j := 1; j <= s.size; j++ {
+		responses = append(responses, s.responses[j]...)
+	}
+	complaints := s.acceptedComplaints()
+	qual := Qualify(pvsss, complaints, responses)
+	if len(qual) == 0 {
+		return NewDKGQualError().withParticipants(disqualified(s.size, qual)...)
+	}
+	scs := make([]*SecretCommitment, len(qual))
+	qualPVSS := make([]*PVSS, len(qual))
+	secrets := make([]*bls.Fr, len(qual))
+	for i, j := range qual {
+		scs[i] = s.deals[j].pvss.public
+		qualPVSS[i] = s.deals[j].pvss
+		secrets[i] = s.deals[j].secret
+		// Take the revealed share if the dealer answered a complaint
+		if r := findResponse(responses, j, s.index); r != nil && s.deals[j].pvss.VerifySecretShare(s.index, r.Secret) {
+			secrets[i] = r.Secret
+		}
+		if secrets[i] == nil {
+			return NewDKGSecretError().withParticipants(j)
+		}
+	}
+	s.qual = qual
+	s.publicKey = NewPublicKey(scs)
+	s.publicKey.SetVerificationKeys(NewVerificationKeys(qualPVSS))
+	s.privateKey = NewPrivateKey(secrets)
+	s.round = DKGRoundFinalize
+	return nil
+}
+
+func (s *DKGSession) GetQualifiedDealers() []int {
+	return s.qual
+}
+
+func (s *DKGSession) GetPublicKey() *PublicKey {
+	return s.publicKey
+}
+
+func (s *DKGSession) GetPrivateKey() *PrivateKey {
+	return s.privateKey
+}
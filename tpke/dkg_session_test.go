@@ -0,0 +1,348 @@
+package tpke
+
+import (
+	"crypto/rand"
+	"testing"
+
+	crypto "github.com/ethereum/go-ethereum/crypto"
+	ecies "github.com/ethereum/go-ethereum/crypto/ecies"
+	bls "github.com/txhsl/tpke/internal/bls"
+)
+
+type envelope struct {
+	from int
+	to   int
+	data []byte
+}
+
+// memoryNetwork delivers broadcast messages between sessions in order
+type memoryNetwork struct {
+	sessions map[int]*DKGSession
+	queue    []envelope
+	tamper   func(msg *DKGMessage) *DKGMessage // Returns nil to drop the message
+}
+
+func newMemoryNetwork(size int, threshold int) *memoryNetwork {
+	keys := make(map[int]*ecies.PrivateKey)
+	peers := make(map[int]*ecies.PublicKey)
+	for i := 1; i <= size; i++ {
+		key, _ := ecies.GenerateKey(rand.Reader, crypto.S256(), nil)
+		keys[i] = key
+		peers[i] = &key.PublicKey
+	}
+	sessions := make(map[int]*DKGSession)
+	for i := 1; i <= size; i++ {
+		sessions[i] = NewDKGSession(i, size, threshold, keys[i], peers)
+	}
+	return &memoryNetwork{
+		sessions: sessions,
+	}
+}
+
+func (n *memoryNetwork) broadcast(from int, msgs []*DKGMessage) {
+	for _, msg := range msgs {
+		if n.tamper != nil {
+			msg = n.tamper(msg)
+		}
+		// Dropped by the tamper
+		if msg == nil {
+			continue
+		}
+		for to := range n.sessions {
+			if to != from {
+				n.queue = append(n.queue, envelope{
+					from: from,
+					to:   to,
+					data: msg.ToBytes(),
+				})
+			}
+		}
+	}
+}
+
+func (n *memoryNetwork) run() error {
+	for len(n.queue) > 0 {
+		e := n.queue[0]
+		n.queue = n.queue[1:]
+		out, err := n.sessions[e.to].OnMessage(e.from, e.data)
+		if err != nil {
+			return err
+		}
+		n.broadcast(e.to, out)
+	}
+	return nil
+}
+
+func TestDKGSession(t *testing.T) {
+	size := 7
+	threshold := 5
+	network := newMemoryNetwork(size, threshold)
+	for i, session := range network.sessions {
+		out, err := session.Deal()
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		network.broadcast(i, out)
+	}
+	if err := network.run(); err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	pubkey := network.sessions[1].GetPublicKey()
+	prvkeys := make(map[int]*PrivateKey)
+	for i, session := range network.sessions {
+		if !session.Finished() {
+			t.Fatalf("dkg not finished.")
+		}
+		if !bls.NewG1().Equal(pubkey.pg1, session.GetPublicKey().pg1) {
+			t.Fatalf("public key mismatch.")
+		}
+		prvkeys[i] = session.GetPrivateKey()
+	}
+
+	// Decrypt with the generated keys
+	msg := []*bls.PointG1{RandPG1()}
+	cipherTexts := Encrypt(msg, pubkey)
+	shares := decryptShare(cipherTexts, prvkeys)
+	results, err := Decrypt(cipherTexts, shares, pubkey, threshold, 1)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if !bls.NewG1().Equal(msg[0], results[0]) {
+		t.Fatalf("decryption failed.")
+	}
+}
+
+func TestDKGSessionComplaint(t *testing.T) {
+	size := 7
+	threshold := 5
+	network := newMemoryNetwork(size, threshold)
+	network.tamper = func(msg *DKGMessage) *DKGMessage {
+		if msg.Type != DKGDealMessage {
+			return msg
+		}
+		switch msg.From {
+		case 1:
+			// Dealer 1 sends a wrong share to participant 2, but answers the complaint
+			pvss, rest, _ := readWithLength(msg.Payload)
+			payload := appendWithLength(nil, pvss)
+			for j := 1; j <= size; j++ {
+				var encrypted []byte
+				encrypted, rest, _ = readWithLength(rest)
+				if j == 2 {
+					garbage := make([]byte, 32)
+					rand.Read(garbage)
+					encrypted, _ = ecies.Encrypt(rand.Reader, network.sessions[2].peers[2], garbage, nil, nil)
+				}
+				payload = appendWithLength(payload, encrypted)
+			}
+			return &DKGMessage{Type: msg.Type, From: msg.From, Payload: payload}
+		case 3:
+			// Dealer 3 sends a malformed deal
+			return &DKGMessage{Type: msg.Type, From: msg.From, Payload: msg.Payload[:100]}
+		}
+		return msg
+	}
+	for i, session := range network.sessions {
+		out, err := session.Deal()
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		network.broadcast(i, out)
+	}
+	if err := network.run(); err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	// Dealer 3 is excluded from the honest view
+	pubkey := network.sessions[1].GetPublicKey()
+	prvkeys := make(map[int]*PrivateKey)
+	for i, session := range network.sessions {
+		if i == 3 {
+			continue
+		}
+		if !session.Finished() {
+			t.Fatalf("dkg not finished.")
+		}
+		qual := session.GetQualifiedDealers()
+		if len(qual) != size-1 || qual[2] != 4 {
+			t.Fatalf("unexpected qual %v.", qual)
+		}
+		if !bls.NewG1().Equal(pubkey.pg1, session.GetPublicKey().pg1) {
+			t.Fatalf("public key mismatch.")
+		}
+		prvkeys[i] = session.GetPrivateKey()
+	}
+
+	msg := []byte("pizza pizza pizza pizza pizza pizza pizza pizza pizza pizza pizza pizza pizza")
+	inputs := make(map[int]*SignatureShare)
+	for i, sk := range prvkeys {
+		inputs[i] = sk.SignShare(msg)
+		if !pubkey.VerifySigShare(i, msg, inputs[i]) {
+			t.Fatalf("invalid share from %d.", i)
+		}
+	}
+	if _, err := AggregateAndVerifySig(pubkey, msg, threshold, inputs, 1); err != nil {
+		t.Fatalf(err.Error())
+	}
+}
+
+func TestDKGSessionSender(t *testing.T) {
+	network := newMemoryNetwork(3, 2)
+	out, err := network.sessions[1].Deal()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	// Sender does not match the transport
+	if _, err := network.sessions[2].OnMessage(3, out[0].ToBytes()); err == nil {
+		t.Fatalf("forged sender accepted.")
+	}
+	if _, err := network.sessions[2].OnMessage(1, out[0].ToBytes()); err != nil {
+		t.Fatalf(err.Error())
+	}
+	// Duplicate deal
+	if _, err := network.sessions[2].OnMessage(1, out[0].ToBytes()); err == nil {
+		t.Fatalf("duplicate deal accepted.")
+	}
+}
+
+func TestDKGSessionTimeout(t *testing.T) {
+	size := 7
+	threshold := 5
+	network := newMemoryNetwork(size, threshold)
+	// Participant 3 crashes before dealing
+	delete(network.sessions, 3)
+	for i, session := range network.sessions {
+		out, err := session.Deal()
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		network.broadcast(i, out)
+	}
+	// Every round waits for the silent participant until its deadline
+	for round := 0; round < 3; round++ {
+		if err := network.run(); err != nil {
+			t.Fatalf(err.Error())
+		}
+		for i, session := range network.sessions {
+			if session.Finished() {
+				t.Fatalf("dkg finished before the deadline.")
+			}
+			out, err := session.Timeout()
+			if err != nil {
+				t.Fatalf(err.Error())
+			}
+			network.broadcast(i, out)
+		}
+	}
+	if err := network.run(); err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	pubkey := network.sessions[1].GetPublicKey()
+	prvkeys := make(map[int]*PrivateKey)
+	for i, session := range network.sessions {
+		if !session.Finished() {
+			t.Fatalf("dkg not finished.")
+		}
+		if qual := session.GetQualifiedDealers(); len(qual) != size-1 || qual[2] != 4 {
+			t.Fatalf("unexpected qual %v.", qual)
+		}
+		if !bls.NewG1().Equal(pubkey.pg1, session.GetPublicKey().pg1) {
+			t.Fatalf("public key mismatch.")
+		}
+		prvkeys[i] = session.GetPrivateKey()
+	}
+	if _, err := network.sessions[1].Timeout(); err == nil {
+		t.Fatalf("finished dkg timed out.")
+	}
+
+	msg := []*bls.PointG1{RandPG1()}
+	cipherTexts := Encrypt(msg, pubkey)
+	shares := make(map[int]([]*DecryptionShare))
+	for i, sk := range prvkeys {
+		shares[i] = []*DecryptionShare{sk.DecryptShare(cipherTexts[0])}
+	}
+	results, err := Decrypt(cipherTexts, shares, pubkey, threshold, 1)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if !bls.NewG1().Equal(msg[0], results[0]) {
+		t.Fatalf("decryption failed.")
+	}
+}
+
+func TestDKGSessionSilentResponse(t *testing.T) {
+	size := 7
+	threshold := 5
+	network := newMemoryNetwork(size, threshold)
+	network.tamper = func(msg *DKGMessage) *DKGMessage {
+		switch {
+		case msg.Type == DKGDealMessage && msg.From == 1:
+			// Dealer 1 sends a wrong share to participant 2
+			pvss, rest, _ := readWithLength(msg.Payload)
+			payload := appendWithLength(nil, pvss)
+			for j := 1; j <= size; j++ {
+				var encrypted []byte
+				encrypted, rest, _ = readWithLength(rest)
+				if j == 2 {
+					garbage := make([]byte, 32)
+					rand.Read(garbage)
+					encrypted, _ = ecies.Encrypt(rand.Reader, network.sessions[2].peers[2], garbage, nil, nil)
+				}
+				payload = appendWithLength(payload, encrypted)
+			}
+			return &DKGMessage{Type: msg.Type, From: msg.From, Payload: payload}
+		case msg.Type == DKGResponseMessage && (msg.From == 1 || msg.From == 4):
+			// Dealer 1 leaves the complaint unanswered, and dealer 4 has nothing to answer
+			return nil
+		}
+		return msg
+	}
+	for i, session := range network.sessions {
+		out, err := session.Deal()
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		network.broadcast(i, out)
+	}
+	if err := network.run(); err != nil {
+		t.Fatalf(err.Error())
+	}
+	for i, session := range network.sessions {
+		if session.Finished() {
+			t.Fatalf("dkg finished before the deadline.")
+		}
+		out, err := session.Timeout()
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		network.broadcast(i, out)
+	}
+	if err := network.run(); err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	// Only dealer 1 is disqualified, it still counts its own response
+	pubkey := network.sessions[2].GetPublicKey()
+	inputs := make(map[int]*SignatureShare)
+	msg := []byte("pizza pizza pizza pizza pizza pizza pizza pizza pizza pizza pizza pizza pizza")
+	for i, session := range network.sessions {
+		if i == 1 {
+			continue
+		}
+		if !session.Finished() {
+			t.Fatalf("dkg not finished.")
+		}
+		if qual := session.GetQualifiedDealers(); len(qual) != size-1 || qual[0] != 2 {
+			t.Fatalf("unexpected qual %v.", qual)
+		}
+		if !bls.NewG1().Equal(pubkey.pg1, session.GetPublicKey().pg1) {
+			t.Fatalf("public key mismatch.")
+		}
+		inputs[i] = session.GetPrivateKey().SignShare(msg)
+	}
+	if _, err := AggregateAndVerifySig(pubkey, msg, threshold, inputs, 1); err != nil {
+		t.Fatalf(err.Error())
+	}
+}
@@ -0,0 +1,254 @@
+package tpke
+
+import (
+	"crypto/rand"
+	"testing"
+
+	ecies "github.com/ethereum/go-ethereum/crypto/ecies"
+	bls "github.com/txhsl/tpke/internal/bls"
+)
+
+func TestDKGComplaint(t *testing.T) {
+	size := 7
+	threshold := 5
+	dkg := NewDKG(size, threshold)
+	dkg.Prepare()
+
+	// Dealer 1 sends a wrong share to participant 2
+	garbage := make([]byte, 32)
+	rand.Read(garbage)
+	dkg.messageBox[1][0], _ = ecies.Encrypt(rand.Reader, dkg.participants[1].ethPubKey, garbage, nil, nil)
+
+	complaints := dkg.Complain()
+	if len(complaints) != 1 || complaints[0].Dealer != 1 || complaints[0].Receiver != 2 {
+		t.Fatalf("unexpected complaints.")
+	}
+	// Dealer 1 reveals the share, and stays qualified
+	responses := dkg.Respond(complaints)
+	if err := dkg.Finalize(complaints, responses); err != nil {
+		t.Fatalf(err.Error())
+	}
+	if len(dkg.GetQualifiedDealers()) != size {
+		t.Fatalf("honest dealer disqualified.")
+	}
+
+	pubkey := dkg.PublishGlobalPublicKey()
+	for i, sk := range dkg.GetPrivateKeys() {
+		if !bls.NewG1().Equal(pubkey.GetVerificationKey(i).pg1, sk.GetPublicKey().pg1) {
+			t.Fatalf("verification key mismatch.")
+		}
+	}
+}
+
+func TestDKGDisqualification(t *testing.T) {
+	size := 7
+	threshold := 5
+	dkg := NewDKG(size, threshold)
+	dkg.Prepare()
+
+	// Dealer 1 sends a wrong share and cannot answer the complaint
+	garbage := make([]byte, 32)
+	rand.Read(garbage)
+	dkg.messageBox[1][0], _ = ecies.Encrypt(rand.Reader, dkg.participants[1].ethPubKey, garbage, nil, nil)
+	dkg.participants[0].GenerateSecret(threshold)
+	// Dealer 3 publishes an invalid pvss
+	dkg.participants[2].pvss.bigf[0] = RandPG1()
+	// Participant 4 complains with fake evidence against dealer 5
+	complaints := dkg.Complain()
+	complaints = append(complaints, &Complaint{
+		Dealer:   5,
+		Receiver: 4,
+		Evidence: garbage,
+	})
+	responses := dkg.Respond(complaints)
+	if err := dkg.Finalize(complaints, responses); err != nil {
+		t.Fatalf(err.Error())
+	}
+	qual := dkg.GetQualifiedDealers()
+	if len(qual) != size-2 || qual[0] != 2 || qual[1] != 4 {
+		t.Fatalf("unexpected qual %v.", qual)
+	}
+
+	// Global public key is built from QUAL only
+	pubkey := dkg.PublishGlobalPublicKey()
+	prvkeys := dkg.GetPrivateKeys()
+	msg := []*bls.PointG1{RandPG1()}
+	cipherTexts := Encrypt(msg, pubkey)
+	shares := decryptShare(cipherTexts, prvkeys)
+	for i := range shares {
+		if !pubkey.VerifyDecryptionShare(i, cipherTexts[0], shares[i][0]) {
+			t.Fatalf("invalid share from %d.", i)
+		}
+	}
+	results, err := Decrypt(cipherTexts, shares, pubkey, threshold, dkg.GetScaler())
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if !bls.NewG1().Equal(msg[0], results[0]) {
+		t.Fatalf("decryption failed.")
+	}
+}
+
+func TestDKGSecretProof(t *testing.T) {
+	size := 5
+	threshold := 3
+	dkg := NewDKG(size, threshold)
+	dkg.SetSession([]byte("session 1"))
+	if err := dkg.Prepare(); err != nil {
+		t.Fatalf(err.Error())
+	}
+	for i, p := range dkg.participants {
+		pvss, err := BytesToPVSS(p.pvss.ToBytes())
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		if !pvss.VerifySecretProof([]byte("session 1"), i+1) {
+			t.Fatalf("valid proof rejected.")
+		}
+		if pvss.VerifySecretProof([]byte("session 2"), i+1) || pvss.VerifySecretProof([]byte("session 1"), i+2) {
+			t.Fatalf("proof accepted in another context.")
+		}
+	}
+
+	// Dealer 2 publishes no proof, dealer 4 replays the proof of dealer 3
+	dkg.participants[1].pvss.proof = nil
+	dkg.participants[3].pvss.proof = dkg.participants[2].pvss.proof
+	if err := dkg.Verify(); err != nil {
+		t.Fatalf(err.Error())
+	}
+	qual := dkg.GetQualifiedDealers()
+	if len(qual) != size-2 || qual[0] != 1 || qual[1] != 3 || qual[2] != 5 {
+		t.Fatalf("unexpected qual %v.", qual)
+	}
+
+	// Without a session id, proofs are bound to the committee
+	unbound := NewDKG(size, threshold)
+	if err := unbound.Prepare(); err != nil {
+		t.Fatalf(err.Error())
+	}
+	pvss := unbound.participants[0].pvss
+	if !pvss.VerifySecretProof(unbound.GetSession(), 1) {
+		t.Fatalf("valid proof rejected.")
+	}
+	if pvss.VerifySecretProof(nil, 1) || pvss.VerifySecretProof(NewDKG(size, threshold).GetSession(), 1) {
+		t.Fatalf("proof accepted for another committee.")
+	}
+}
+
+func TestDKGDegree(t *testing.T) {
+	size := 5
+	threshold := 3
+	dkg := NewDKG(size, threshold)
+	dkg.Prepare()
+	if err := dkg.Verify(); err != nil {
+		t.Fatalf(err.Error())
+	}
+	// Dealer 2 re-deals a polynomial of a higher degree, with valid shares and proof
+	redeal := func(dkg *DKG, zero bool) {
+		dealer := dkg.participants[1]
+		if zero {
+			dealer.GenerateZeroSecret(threshold + 2)
+		} else {
+			dealer.GenerateSecret(threshold + 2)
+		}
+		ss, _ := dealer.GenerateShares(size)
+		dealer.ProveSecret(dkg.GetSession(), 2)
+		for j := 0; j < size; j++ {
+			dkg.messageBox[j][1], _ = ecies.Encrypt(rand.Reader, dkg.participants[j].ethPubKey, ss[j].ToBytes(), nil, nil)
+		}
+	}
+	for _, refresh := range []bool{false, true} {
+		run := NewDKG(size, threshold)
+		if refresh {
+			run = dkg.NewRefresh()
+		}
+		run.Prepare()
+		redeal(run, refresh)
+		if err := run.Verify(); err != nil {
+			t.Fatalf(err.Error())
+		}
+		if qual := run.GetQualifiedDealers(); len(qual) != size-1 || qual[1] != 3 {
+			t.Fatalf("unexpected qual %v.", qual)
+		}
+	}
+}
+
+func TestDKGTwoPhase(t *testing.T) {
+	for _, scheme := range []Scheme{MinPubKey, MinSig} {
+		size := 7
+		threshold := 5
+		dkg := NewDKGWithScheme(size, threshold, scheme, nil)
+		dkg.SetTwoPhase()
+		if err := dkg.Prepare(); err != nil {
+			t.Fatalf(err.Error())
+		}
+		// Nothing about A0 is published in the first phase
+		for _, p := range dkg.participants {
+			if p.pvss != nil {
+				t.Fatalf("feldman commitment published before QUAL.")
+			}
+		}
+
+		// Dealer 1 sends a wrong share to participant 2, and answers the complaint
+		garbage := make([]byte, 64)
+		rand.Read(garbage)
+		dkg.messageBox[1][0], _ = ecies.Encrypt(rand.Reader, dkg.participants[1].ethPubKey, garbage, nil, nil)
+		// Dealer 3 publishes a malformed commitment
+		dkg.participants[2].pedersen.coeff = dkg.participants[2].pedersen.coeff[1:]
+		complaints := dkg.Complain()
+		responses := dkg.Respond(complaints)
+		if err := dkg.Finalize(complaints, responses); err != nil {
+			t.Fatalf(err.Error())
+		}
+		qual := dkg.GetQualifiedDealers()
+		if len(qual) != size-1 || qual[1] != 2 || qual[2] != 4 {
+			t.Fatalf("unexpected qual %v.", qual)
+		}
+		g1 := bls.NewG1()
+		expected := g1.Zero()
+		for _, j := range qual {
+			g1.Add(expected, expected, g1.MulScalar(g1.New(), &bls.G1One, dkg.participants[j-1].secret.poly.coeff[0]))
+		}
+
+		// Dealer 4 tries to bias the key by revealing another secret, and its dealt secret is recovered
+		dkg.participants[3].GenerateSecret(threshold)
+		// Dealer 5 reveals nothing
+		dkg.participants[4].secret = nil
+		// Dealer 6 reveals its pvss without a proof of knowledge
+		dealer := dkg.participants[5]
+		ss, _ := dealer.GenerateShares(size)
+		if scheme == MinSig {
+			dealer.pvss.setG2Shares(dealer.secret.poly.coeff[0], ss)
+		}
+		unproven := dealer.pvss
+		dealer.secret = nil
+		if err := dkg.Reveal(); err != nil {
+			t.Fatalf(err.Error())
+		}
+		if dealer.pvss == unproven {
+			t.Fatalf("pvss without proof accepted.")
+		}
+		for _, j := range qual {
+			if !dkg.participants[j-1].pvss.VerifySecretProof(dkg.GetSession(), j) {
+				t.Fatalf("revealed pvss without proof.")
+			}
+		}
+		pubkey := dkg.PublishGlobalPublicKey()
+		if !g1.Equal(pubkey.pg1, expected) {
+			t.Fatalf("global public key biased.")
+		}
+
+		prvkeys := dkg.GetPrivateKeys()
+		msg := []byte("pizza pizza pizza pizza pizza pizza pizza pizza pizza pizza pizza pizza pizza")
+		inputs := make(map[int]*SignatureShare)
+		for i, sk := range prvkeys {
+			if !g1.Equal(pubkey.GetVerificationKey(i).pg1, sk.GetPublicKey().pg1) {
+				t.Fatalf("verification key mismatch.")
+			}
+			inputs[i] = sk.SignShare(msg)
+		}
+		if _, err := AggregateAndVerifySig(pubkey, msg, threshold, inputs, 1); err != nil {
+			t.Fatalf(err.Error())
+		}
+	}
+}
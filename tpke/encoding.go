@@ -0,0 +1,236 @@
+package tpke
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"math/big"
+
+	bls "github.com/txhsl/tpke/internal/bls"
+)
+
+// Version of the binary and json encodings of keys and dkg state
+const EncodingVersion byte = 1
+
+// Type tags of the binary encodings
+const (
+	encodingPrivateKey byte = iota + 1
+	encodingPublicKey
+	encodingSecretCommitment
+	encodingPVSS
+	encodingParticipant
+	encodingEncryptedPVSS
+)
+
+// Binary encodings start with the version and the type tag
+func encodingHeader(tag byte) []byte {
+	return []byte{EncodingVersion, tag}
+}
+
+func readEncodingHeader(b []byte, tag byte) ([]byte, error) {
+	if len(b) < 2 {
+		return nil, NewEncodingLengthError()
+	}
+	if b[0] != EncodingVersion {
+		return nil, NewEncodingVersionError()
+	}
+	if b[1] != tag {
+		return nil, NewEncodingTypeError()
+	}
+	return b[2:], nil
+}
+
+func checkEncodingVersion(version byte) error {
+	if version != EncodingVersion {
+		return NewEncodingVersionError()
+	}
+	return nil
+}
+
+// Points are decoded with curve and subgroup checks
+func decodeG1(b []byte) (*bls.PointG1, error) {
+	pg1, err := bls.NewG1().FromCompressed(b)
+	if err != nil {
+		return nil, NewEncodingError(err.Error()).withCause(err)
+	}
+	return pg1, nil
+}
+
+func decodeG2(b []byte) (*bls.PointG2, error) {
+	pg2, err := bls.NewG2().FromCompressed(b)
+	if err != nil {
+		return nil, NewEncodingError(err.Error()).withCause(err)
+	}
+	return pg2, nil
+}
+
+// Order of the scalar field, some backends keep unreduced scalars from bytes
+var frOrder = func() *big.Int {
+	minusOne := bls.NewFr()
+	minusOne.Neg(bls.NewFr().One())
+	return new(big.Int).Add(new(big.Int).SetBytes(minusOne.ToBytes()), big.NewInt(1))
+}()
+
+// Scalars must be 32 bytes and reduced, so that every value has a single encoding
+func decodeFr(b []byte) (*bls.Fr, error) {
+	if len(b) != 32 {
+		return nil, NewEncodingLengthError()
+	}
+	if new(big.Int).SetBytes(b).Cmp(frOrder) >= 0 {
+		return nil, NewEncodingScalarError()
+	}
+	fr := bls.NewFr().FromBytes(b)
+	if !bytes.Equal(fr.ToBytes(), b) {
+		return nil, NewEncodingScalarError()
+	}
+	return fr, nil
+}
+
+// Encode a 4-byte count, then compressed points
+func appendG1s(out []byte, points []*bls.PointG1) []byte {
+	g1 := bls.NewG1()
+	out = binary.BigEndian.AppendUint32(out, uint32(len(points)))
+	for _, p := range points {
+		out = append(out, g1.ToCompressed(p)...)
+	}
+	return out
+}
+
+func readG1s(b []byte) ([]*bls.PointG1, []byte, error) {
+	if len(b) < 4 {
+		return nil, nil, NewEncodingLengthError()
+	}
+	n := int(binary.BigEndian.Uint32(b[:4]))
+	b = b[4:]
+	if n > len(b)/fpByteSize {
+		return nil, nil, NewEncodingLengthError()
+	}
+	points := make([]*bls.PointG1, n)
+	for i := range points {
+		pg1, err := decodeG1(b[:fpByteSize])
+		if err != nil {
+			return nil, nil, err
+		}
+		points[i] = pg1
+		b = b[fpByteSize:]
+	}
+	return points, b, nil
+}
+
+func appendG2s(out []byte, points []*bls.PointG2) []byte {
+	g2 := bls.NewG2()
+	out = binary.BigEndian.AppendUint32(out, uint32(len(points)))
+	for _, p := range points {
+		out = append(out, g2.ToCompressed(p)...)
+	}
+	return out
+}
+
+func readG2s(b []byte) ([]*bls.PointG2, []byte, error) {
+	if len(b) < 4 {
+		return nil, nil, NewEncodingLengthError()
+	}
+	n := int(binary.BigEndian.Uint32(b[:4]))
+	b = b[4:]
+	if n > len(b)/(2*fpByteSize) {
+		return nil, nil, NewEncodingLengthError()
+	}
+	points := make([]*bls.PointG2, n)
+	for i := range points {
+		pg2, err := decodeG2(b[:2*fpByteSize])
+		if err != nil {
+			return nil, nil, err
+		}
+		points[i] = pg2
+		b = b[2*fpByteSize:]
+	}
+	return points, b, nil
+}
+
+// Encode a 4-byte count, then 32-byte scalars
+func appendFrs(out []byte, frs []*bls.Fr) []byte {
+	out = binary.BigEndian.AppendUint32(out, uint32(len(frs)))
+	for _, fr := range frs {
+		out = append(out, fr.ToBytes()...)
+	}
+	return out
+}
+
+func readFrs(b []byte) ([]*bls.Fr, []byte, error) {
+	if len(b) < 4 {
+		return nil, nil, NewEncodingLengthError()
+	}
+	n := int(binary.BigEndian.Uint32(b[:4]))
+	b = b[4:]
+	if n > len(b)/32 {
+		return nil, nil, NewEncodingLengthError()
+	}
+	frs := make([]*bls.Fr, n)
+	for i := range frs {
+		fr, err := decodeFr(b[:32])
+		if err != nil {
+			return nil, nil, err
+		}
+		frs[i] = fr
+		b = b[32:]
+	}
+	return frs, b, nil
+}
+
+// hexBytes is a byte string in json, encoded as lowercase hex
+type hexBytes []byte
+
+func (h hexBytes) MarshalText() ([]byte, error) {
+	return []byte(hex.EncodeToString(h)), nil
+}
+
+func (h *hexBytes) UnmarshalText(text []byte) error {
+	b, err := hex.DecodeString(string(text))
+	if err != nil {
+		return NewEncodingError(err.Error()).withCause(err)
+	}
+	*h = b
+	return nil
+}
+
+func g1sToHex(points []*bls.PointG1) []hexBytes {
+	g1 := bls.NewG1()
+	out := make([]hexBytes, len(points))
+	for i, p := range points {
+		out[i] = g1.ToCompressed(p)
+	}
+	return out
+}
+
+func hexToG1s(in []hexBytes) ([]*bls.PointG1, error) {
+	points := make([]*bls.PointG1, len(in))
+	for i, h := range in {
+		pg1, err := decodeG1(h)
+		if err != nil {
+			return nil, err
+		}
+		points[i] = pg1
+	}
+	return points, nil
+}
+
+func g2sToHex(points []*bls.PointG2) []hexBytes {
+	g2 := bls.NewG2()
+	out := make([]hexBytes, len(points))
+	for i, p := range points {
+		out[i] = g2.ToCompressed(p)
+	}
+	return out
+}
+
+func hexToG2s(in []hexBytes) ([]*bls.PointG2, error) {
+	points := make([]*bls.PointG2, len(in))
+	for i, h := range in {
+		pg2, err := decodeG2(h)
+		if err != nil {
+			return nil, err
+		}
+		points[i] = pg2
+	}
+	return points, nil
+}
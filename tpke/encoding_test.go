@@ -0,0 +1,147 @@
+package tpke
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	bls "github.com/txhsl/tpke/internal/bls"
+)
+
+func TestEncoding(t *testing.T) {
+	size := 7
+	threshold := 5
+	dkg := NewDKG(size, threshold)
+	dkg.Prepare()
+	if err := dkg.Verify(); err != nil {
+		t.Fatalf(err.Error())
+	}
+	pubkey := dkg.PublishGlobalPublicKey()
+	prvkeys := dkg.GetPrivateKeys()
+
+	// Binary
+	pk, err := BytesToPublicKey(pubkey.ToBytes())
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if !bytes.Equal(pk.ToBytes(), pubkey.ToBytes()) || len(pk.vks) != size {
+		t.Fatalf("public key mismatch.")
+	}
+	restored := make(map[int]*PrivateKey)
+	for i, sk := range prvkeys {
+		restored[i], err = BytesToPrivateKey(sk.ToBytes())
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+	}
+	pvss, err := BytesToPVSS(dkg.participants[0].pvss.ToBytes())
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if !bytes.Equal(pvss.ToBytes(), dkg.participants[0].pvss.ToBytes()) {
+		t.Fatalf("pvss mismatch.")
+	}
+	sc, err := BytesToSecretCommitment(pvss.public.ToBytes())
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if !sc.Equals(dkg.participants[0].secret.Commitment()) {
+		t.Fatalf("commitment mismatch.")
+	}
+	p, err := BytesToParticipant(dkg.participants[0].ToBytes())
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if !bytes.Equal(p.ToBytes(), dkg.participants[0].ToBytes()) || !p.secret.Equals(dkg.participants[0].secret) {
+		t.Fatalf("participant mismatch.")
+	}
+
+	// Json
+	b, err := json.Marshal(pubkey)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	pk = &PublicKey{}
+	if err := json.Unmarshal(b, pk); err != nil {
+		t.Fatalf(err.Error())
+	}
+	if !bytes.Equal(pk.ToBytes(), pubkey.ToBytes()) {
+		t.Fatalf("public key mismatch.")
+	}
+	for i, sk := range restored {
+		b, err := json.Marshal(sk)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		restored[i] = &PrivateKey{}
+		if err := json.Unmarshal(b, restored[i]); err != nil {
+			t.Fatalf(err.Error())
+		}
+	}
+	for _, v := range []interface {
+		ToBytes() []byte
+	}{pvss, sc, dkg.participants[1]} {
+		b, err := json.Marshal(v)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		var out interface {
+			ToBytes() []byte
+		}
+		switch v.(type) {
+		case *PVSS:
+			out = &PVSS{}
+		case *SecretCommitment:
+			out = &SecretCommitment{}
+		case *Participant:
+			out = &Participant{}
+		}
+		if err := json.Unmarshal(b, out); err != nil {
+			t.Fatalf(err.Error())
+		}
+		if !bytes.Equal(out.ToBytes(), v.ToBytes()) {
+			t.Fatalf("json round trip mismatch.")
+		}
+	}
+
+	// Restored keys still work
+	msg := []*bls.PointG1{RandPG1()}
+	cipherTexts := Encrypt(msg, pk)
+	results, err := Decrypt(cipherTexts, decryptShare(cipherTexts, restored), pk, threshold, 1)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if !bls.NewG1().Equal(msg[0], results[0]) {
+		t.Fatalf("decryption failed.")
+	}
+}
+
+func TestEncodingInvalid(t *testing.T) {
+	sk := &PrivateKey{
+		fr: bls.NewFr().One(),
+	}
+	b := sk.ToBytes()
+	b[0] = EncodingVersion + 1
+	if _, err := BytesToPrivateKey(b); err == nil {
+		t.Fatalf("unknown version accepted.")
+	}
+	b[0] = EncodingVersion
+	if _, err := BytesToPublicKey(b); err == nil {
+		t.Fatalf("unexpected type accepted.")
+	}
+	// Scalar larger than the group order
+	for i := 2; i < len(b); i++ {
+		b[i] = 0xff
+	}
+	if _, err := BytesToPrivateKey(b); err == nil {
+		t.Fatalf("non-canonical scalar accepted.")
+	}
+	if err := json.Unmarshal([]byte(`{"version":1,"key":"00"}`), &PublicKey{}); err == nil {
+		t.Fatalf("invalid point accepted.")
+	}
+	pk := sk.GetPublicKey().ToBytes()
+	pk[5] ^= 1
+	if _, err := BytesToPublicKey(pk); err == nil {
+		t.Fatalf("invalid point accepted.")
+	}
+}
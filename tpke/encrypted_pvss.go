@@ -0,0 +1,391 @@
+package tpke
+
+import (
+	"encoding/binary"
+	"io"
+	"math/big"
+
+	bls "github.com/txhsl/tpke/internal/bls"
+)
+
+var (
+	shareBitProofDomain = []byte("TPKE_PVSS_SHARE_BIT_PROOF_")
+	shareSumProofDomain = []byte("TPKE_PVSS_SHARE_SUM_PROOF_")
+)
+
+// Shares are encrypted bit by bit, f(j)<q<2^255
+const shareBits = 255
+
+// ShareDecryptionKey is the key of a recipient of encrypted shares, x with EK=x*G1
+type ShareDecryptionKey struct {
+	fr *bls.Fr
+}
+
+type ShareEncryptionKey struct {
+	pg1 *bls.PointG1
+}
+
+func GenerateShareKey(random io.Reader) (*ShareDecryptionKey, error) {
+	fr, err := randomFr(random)
+	if err != nil {
+		return nil, err
+	}
+	return &ShareDecryptionKey{
+		fr: fr,
+	}, nil
+}
+
+func (dk *ShareDecryptionKey) GetEncryptionKey() *ShareEncryptionKey {
+	g1 := bls.NewG1()
+	return &ShareEncryptionKey{
+		pg1: g1.MulScalar(g1.New(), &bls.G1One, dk.fr),
+	}
+}
+
+func (ek *ShareEncryptionKey) ToBytes() []byte {
+	return bls.NewG1().ToCompressed(ek.pg1)
+}
+
+func BytesToShareEncryptionKey(b []byte) (*ShareEncryptionKey, error) {
+	pg1, err := decodeG1(b)
+	if err != nil {
+		return nil, err
+	}
+	return &ShareEncryptionKey{
+		pg1: pg1,
+	}, nil
+}
+
+// EncryptedShare encrypts f(j) to EK_j, bit k is an ElGamal ciphertext R_k=r_k*G1, C_k=b_k*G1+r_k*EK_j
+// with a proof that b_k is 0 or 1. A proof of log_G1(sum(2^k*R_k))==log_EK_j(sum(2^k*C_k)-F(j))
+// shows that the bits add up to f(j), so the recipient can always decrypt its share
+type EncryptedShare struct {
+	bigR  []*bls.PointG1
+	bigC  []*bls.PointG1
+	bits  []*bitProof
+	proof *DLEQProof
+}
+
+// EncryptedPVSS is a pvss with shares encrypted to the recipients, any observer checks it with Verify
+type EncryptedPVSS struct {
+	pvss   *PVSS
+	shares []*EncryptedShare // Recipient j at j-1
+}
+
+// GenerateEncryptedPVSS deals the secret to recipients in the order of keys, starting from 1
+func GenerateEncryptedPVSS(r *bls.Fr, secret *Secret, keys []*ShareEncryptionKey, random io.Reader) (*EncryptedPVSS, error) {
+	random = randomReader(random)
+	pvss, ss := GenerateSharedSecrets(r, len(keys), secret)
+	shares := make([]*EncryptedShare, len(keys))
+	for j := range keys {
+		es, err := encryptShare(random, j+1, keys[j], ss[j])
+		if err != nil {
+			return nil, err
+		}
+		shares[j] = es
+	}
+	return &EncryptedPVSS{
+		pvss:   pvss,
+		shares: shares,
+	}, nil
+}
+
+// GenerateEncryptedShares deals the secret of the participant with publicly verifiable encrypted shares
+func (p *Participant) GenerateEncryptedShares(keys []*ShareEncryptionKey) (*EncryptedPVSS, error) {
+	r, err := randomFr(p.random)
+	if err != nil {
+		return nil, err
+	}
+	ep, err := GenerateEncryptedPVSS(r, p.secret, keys, p.random)
+	if err != nil {
+		return nil, err
+	}
+	p.pvss = ep.pvss
+	return ep, nil
+}
+
+func (ep *EncryptedPVSS) GetPVSS() *PVSS {
+	return ep.pvss
+}
+
+// Verify checks the pvss, and that every recipient j gets f(j) with F(j)=f(j)*G1
+func (ep *EncryptedPVSS) Verify(keys []*ShareEncryptionKey) bool {
+	if ep.pvss == nil || len(ep.pvss.bigf) != len(keys) || len(ep.shares) != len(keys) || !ep.pvss.Verify() {
+		return false
+	}
+	ch := make(chan bool, len(keys))
+	for j := range keys {
+		go func(j int) {
+			ch <- ep.shares[j] != nil && keys[j] != nil && ep.shares[j].verify(j+1, keys[j], ep.pvss.bigf[j])
+		}(j)
+	}
+	valid := true
+	for range keys {
+		valid = <-ch && valid
+	}
+	return valid
+}
+
+// DecryptShare decrypts f(index) from a transcript that passed Verify
+func (dk *ShareDecryptionKey) DecryptShare(index int, ep *EncryptedPVSS) (*bls.Fr, error) {
+	if index <= 0 || index > len(ep.shares) || index > len(ep.pvss.bigf) || ep.shares[index-1] == nil {
+		return nil, NewDKGSecretError()
+	}
+	es := ep.shares[index-1]
+	if len(es.bigR) != shareBits || len(es.bigC) != shareBits {
+		return nil, NewDKGSecretError()
+	}
+	g1 := bls.NewG1()
+	bits := new(big.Int)
+	m := g1.New()
+	minor := g1.New()
+	for k := 0; k < shareBits; k++ {
+		// M_k=C_k-x*R_k is either 0 or G1
+		g1.Sub(m, es.bigC[k], g1.MulScalar(minor, es.bigR[k], dk.fr))
+		if g1.Equal(m, &bls.G1One) {
+			bits.SetBit(bits, k, 1)
+		} else if !g1.IsZero(m) {
+			return nil, NewDKGSecretError()
+		}
+	}
+	fi := bls.NewFr().FromBytes(bits.Bytes())
+	if !g1.Equal(g1.MulScalar(m, &bls.G1One, fi), ep.pvss.bigf[index-1]) {
+		return nil, NewDKGSecretError()
+	}
+	return fi, nil
+}
+
+// Powers of two 2^k in Fr, k<shareBits
+func bitWeights() []*bls.Fr {
+	two := frFromInt(2)
+	weights := make([]*bls.Fr, shareBits)
+	weights[0] = bls.NewFr().One()
+	for k := 1; k < shareBits; k++ {
+		weights[k] = bls.NewFr()
+		weights[k].Mul(weights[k-1], two)
+	}
+	return weights
+}
+
+func shareContext(index int, ek *ShareEncryptionKey) []byte {
+	return append(binary.BigEndian.AppendUint32(nil, uint32(index)), bls.NewG1().ToCompressed(ek.pg1)...)
+}
+
+func encryptShare(random io.Reader, index int, ek *ShareEncryptionKey, fi *bls.Fr) (*EncryptedShare, error) {
+	g1 := bls.NewG1()
+	context := shareContext(index, ek)
+	bits := new(big.Int).SetBytes(fi.ToBytes())
+	weights := bitWeights()
+	es := &EncryptedShare{
+		bigR: make([]*bls.PointG1, shareBits),
+		bigC: make([]*bls.PointG1, shareBits),
+		bits: make([]*bitProof, shareBits),
+	}
+	// r'=sum(2^k*r_k)
+	rSum := bls.NewFr().Zero()
+	minor := bls.NewFr()
+	for k := 0; k < shareBits; k++ {
+		rk, err := randomFr(random)
+		if err != nil {
+			return nil, err
+		}
+		bit := bits.Bit(k) == 1
+		es.bigR[k] = g1.MulScalar(g1.New(), &bls.G1One, rk)
+		es.bigC[k] = g1.MulScalar(g1.New(), ek.pg1, rk)
+		if bit {
+			g1.Add(es.bigC[k], es.bigC[k], &bls.G1One)
+		}
+		es.bits[k], err = newBitProof(random, binary.BigEndian.AppendUint32(context, uint32(k)), ek.pg1, es.bigR[k], es.bigC[k], rk, bit)
+		if err != nil {
+			return nil, err
+		}
+		minor.Mul(weights[k], rk)
+		rSum.Add(rSum, minor)
+	}
+	var err error
+	es.proof, err = newDLEQProof(random, shareSumProofDomain, context, &bls.G1One, ek.pg1, rSum)
+	if err != nil {
+		return nil, err
+	}
+	return es, nil
+}
+
+func (es *EncryptedShare) verify(index int, ek *ShareEncryptionKey, bigf *bls.PointG1) bool {
+	if len(es.bigR) != shareBits || len(es.bigC) != shareBits || len(es.bits) != shareBits {
+		return false
+	}
+	context := shareContext(index, ek)
+	for k := 0; k < shareBits; k++ {
+		if !es.bits[k].verify(binary.BigEndian.AppendUint32(context, uint32(k)), ek.pg1, es.bigR[k], es.bigC[k]) {
+			return false
+		}
+	}
+	g1 := bls.NewG1()
+	weights := bitWeights()
+	r := make([]*bls.PointG1, shareBits)
+	c := make([]*bls.PointG1, shareBits)
+	for k := 0; k < shareBits; k++ {
+		r[k] = g1.New().Set(es.bigR[k])
+		c[k] = g1.New().Set(es.bigC[k])
+	}
+	sumR, err := g1.MultiExp(g1.New(), r, weights)
+	if err != nil {
+		return false
+	}
+	sumC, err := g1.MultiExp(g1.New(), c, weights)
+	if err != nil {
+		return false
+	}
+	g1.Sub(sumC, sumC, bigf)
+	return es.proof.verify(shareSumProofDomain, context, &bls.G1One, sumR, ek.pg1, sumC)
+}
+
+// bitProof proves log_G1(R)==log_EK(C) or log_G1(R)==log_EK(C-G1), so that (R,C) encrypts 0 or 1.
+// The false branch is simulated with its own challenge, and e0+e1 is the hash
+type bitProof struct {
+	e0 *bls.Fr
+	f0 *bls.Fr
+	e1 *bls.Fr
+	f1 *bls.Fr
+}
+
+func newBitProof(random io.Reader, context []byte, ek, bigR, bigC *bls.PointG1, r *bls.Fr, bit bool) (*bitProof, error) {
+	g1 := bls.NewG1()
+	bigY := [2]*bls.PointG1{bigC, g1.Sub(g1.New(), bigC, &bls.G1One)}
+	t, o := 0, 1
+	if bit {
+		t, o = 1, 0
+	}
+	var e, f [2]*bls.Fr
+	var a, b [2]*bls.PointG1
+	// Simulate the false branch
+	var err error
+	if e[o], err = randomFr(random); err != nil {
+		return nil, err
+	}
+	if f[o], err = randomFr(random); err != nil {
+		return nil, err
+	}
+	a[o], b[o] = bitCommitments(ek, bigR, bigY[o], e[o], f[o])
+	// Commit the true branch
+	s, err := randomFr(random)
+	if err != nil {
+		return nil, err
+	}
+	a[t] = g1.MulScalar(g1.New(), &bls.G1One, s)
+	b[t] = g1.MulScalar(g1.New(), ek, s)
+	// e_t=e-e_o, f_t=s+e_t*r
+	e[t] = hashDLEQ(shareBitProofDomain, context, ek, bigR, bigC, a[0], b[0], a[1], b[1])
+	e[t].Sub(e[t], e[o])
+	f[t] = bls.NewFr()
+	f[t].Mul(e[t], r)
+	f[t].Add(f[t], s)
+	return &bitProof{
+		e0: e[0],
+		f0: f[0],
+		e1: e[1],
+		f1: f[1],
+	}, nil
+}
+
+// A=f*G1-e*R, B=f*EK-e*Y
+func bitCommitments(ek, bigR, bigY *bls.PointG1, e, f *bls.Fr) (*bls.PointG1, *bls.PointG1) {
+	g1 := bls.NewG1()
+	minor := g1.New()
+	a := g1.MulScalar(g1.New(), &bls.G1One, f)
+	g1.Sub(a, a, g1.MulScalar(minor, bigR, e))
+	b := g1.MulScalar(g1.New(), ek, f)
+	g1.Sub(b, b, g1.MulScalar(minor, bigY, e))
+	return a, b
+}
+
+func (p *bitProof) verify(context []byte, ek, bigR, bigC *bls.PointG1) bool {
+	if p == nil || p.e0 == nil || p.f0 == nil || p.e1 == nil || p.f1 == nil || bigR == nil || bigC == nil {
+		return false
+	}
+	g1 := bls.NewG1()
+	a0, b0 := bitCommitments(ek, bigR, bigC, p.e0, p.f0)
+	a1, b1 := bitCommitments(ek, bigR, g1.Sub(g1.New(), bigC, &bls.G1One), p.e1, p.f1)
+	e := bls.NewFr()
+	e.Add(p.e0, p.e1)
+	return hashDLEQ(shareBitProofDomain, context, ek, bigR, bigC, a0, b0, a1, b1).Equal(e)
+}
+
+// Encode the pvss, then for every recipient the bit ciphertexts with their proofs and the sum proof
+func (ep *EncryptedPVSS) ToBytes() []byte {
+	g1 := bls.NewG1()
+	out := appendWithLength(encodingHeader(encodingEncryptedPVSS), ep.pvss.ToBytes())
+	out = binary.BigEndian.AppendUint32(out, uint32(len(ep.shares)))
+	for _, es := range ep.shares {
+		for k := 0; k < shareBits; k++ {
+			out = append(out, g1.ToCompressed(es.bigR[k])...)
+			out = append(out, g1.ToCompressed(es.bigC[k])...)
+			out = append(out, es.bits[k].e0.ToBytes()...)
+			out = append(out, es.bits[k].f0.ToBytes()...)
+			out = append(out, es.bits[k].e1.ToBytes()...)
+			out = append(out, es.bits[k].f1.ToBytes()...)
+		}
+		out = append(out, es.proof.ToBytes()...)
+	}
+	return out
+}
+
+func BytesToEncryptedPVSS(b []byte) (*EncryptedPVSS, error) {
+	b, err := readEncodingHeader(b, encodingEncryptedPVSS)
+	if err != nil {
+		return nil, err
+	}
+	pvssBytes, b, ok := readWithLength(b)
+	if !ok || len(b) < 4 {
+		return nil, NewEncodingLengthError()
+	}
+	pvss, err := BytesToPVSS(pvssBytes)
+	if err != nil {
+		return nil, err
+	}
+	n := int(binary.BigEndian.Uint32(b[:4]))
+	b = b[4:]
+	bitSize := 2*fpByteSize + 4*32
+	shareSize := shareBits*bitSize + 64
+	if n != len(pvss.bigf) || len(b) != n*shareSize {
+		return nil, NewEncodingLengthError()
+	}
+	shares := make([]*EncryptedShare, n)
+	for j := range shares {
+		es := &EncryptedShare{
+			bigR: make([]*bls.PointG1, shareBits),
+			bigC: make([]*bls.PointG1, shareBits),
+			bits: make([]*bitProof, shareBits),
+		}
+		for k := 0; k < shareBits; k++ {
+			if es.bigR[k], err = decodeG1(b[:fpByteSize]); err != nil {
+				return nil, err
+			}
+			if es.bigC[k], err = decodeG1(b[fpByteSize : 2*fpByteSize]); err != nil {
+				return nil, err
+			}
+			frs := make([]*bls.Fr, 4)
+			for i := range frs {
+				if frs[i], err = decodeFr(b[2*fpByteSize+32*i : 2*fpByteSize+32*(i+1)]); err != nil {
+					return nil, err
+				}
+			}
+			es.bits[k] = &bitProof{
+				e0: frs[0],
+				f0: frs[1],
+				e1: frs[2],
+				f1: frs[3],
+			}
+			b = b[bitSize:]
+		}
+		if es.proof, err = BytesToDLEQProof(b[:64]); err != nil {
+			return nil, err
+		}
+		b = b[64:]
+		shares[j] = es
+	}
+	return &EncryptedPVSS{
+		pvss:   pvss,
+		shares: shares,
+	}, nil
+}
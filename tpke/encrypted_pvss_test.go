@@ -0,0 +1,108 @@
+package tpke
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"testing"
+
+	crypto "github.com/ethereum/go-ethereum/crypto"
+	ecies "github.com/ethereum/go-ethereum/crypto/ecies"
+	bls "github.com/txhsl/tpke/internal/bls"
+)
+
+func TestEncryptedPVSS(t *testing.T) {
+	size := 4
+	threshold := 3
+	dks := make([]*ShareDecryptionKey, size)
+	eks := make([]*ShareEncryptionKey, size)
+	for j := 0; j < size; j++ {
+		dk, err := GenerateShareKey(nil)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		dks[j] = dk
+		eks[j] = dk.GetEncryptionKey()
+	}
+
+	// Every participant deals, and observers check transcripts without any secret
+	transcripts := make([]*EncryptedPVSS, size)
+	for i := 0; i < size; i++ {
+		key, _ := ecies.GenerateKey(rand.Reader, crypto.S256(), nil)
+		p := NewParticipant(key)
+		if err := p.GenerateSecret(threshold); err != nil {
+			t.Fatalf(err.Error())
+		}
+		ep, err := p.GenerateEncryptedShares(eks)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		restored, err := BytesToEncryptedPVSS(ep.ToBytes())
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		if !restored.Verify(eks) {
+			t.Fatalf("valid transcript rejected.")
+		}
+		transcripts[i] = restored
+	}
+
+	// Recipients decrypt shares from all dealers
+	scs := make([]*SecretCommitment, size)
+	pvsss := make([]*PVSS, size)
+	for i, ep := range transcripts {
+		scs[i] = ep.GetPVSS().public
+		pvsss[i] = ep.GetPVSS()
+	}
+	pk := NewPublicKey(scs)
+	pk.SetVerificationKeys(NewVerificationKeys(pvsss))
+	msg := []byte("pizza pizza pizza pizza pizza pizza pizza pizza pizza pizza pizza pizza pizza")
+	inputs := make(map[int]*SignatureShare)
+	for j := 0; j < size; j++ {
+		secrets := make([]*bls.Fr, size)
+		for i, ep := range transcripts {
+			fi, err := dks[j].DecryptShare(j+1, ep)
+			if err != nil {
+				t.Fatalf(err.Error())
+			}
+			secrets[i] = fi
+		}
+		inputs[j+1] = NewPrivateKey(secrets).SignShare(msg)
+	}
+	if _, err := AggregateAndVerifySig(pk, msg, threshold, inputs, 1); err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	// Shares of another recipient are rejected
+	ep := transcripts[0]
+	if _, err := dks[1].DecryptShare(1, ep); err == nil {
+		t.Fatalf("share of another recipient decrypted.")
+	}
+	swapped := []*ShareEncryptionKey{eks[1], eks[0], eks[2], eks[3]}
+	if ep.Verify(swapped) {
+		t.Fatalf("transcript accepted with other keys.")
+	}
+	// A bit encrypting 2 is rejected
+	g1 := bls.NewG1()
+	c := ep.shares[2].bigC[7]
+	ep.shares[2].bigC[7] = g1.Add(g1.New(), c, &bls.G1One)
+	if ep.Verify(eks) {
+		t.Fatalf("tampered bit accepted.")
+	}
+	ep.shares[2].bigC[7] = c
+	// Bits of another value, with valid bit proofs, not adding up to F(j) are rejected
+	fi, _ := bls.NewFr().Rand(rand.Reader)
+	wrong, err := encryptShare(nil, 3, eks[2], fi)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	context := shareContext(3, eks[2])
+	for k := 0; k < shareBits; k++ {
+		if !wrong.bits[k].verify(binary.BigEndian.AppendUint32(context, uint32(k)), eks[2].pg1, wrong.bigR[k], wrong.bigC[k]) {
+			t.Fatalf("valid bit proof rejected.")
+		}
+	}
+	ep.shares[2] = wrong
+	if ep.Verify(eks) {
+		t.Fatalf("share of another value accepted.")
+	}
+}
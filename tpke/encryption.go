@@ -4,12 +4,12 @@ import (
 	"crypto/aes"
 	"crypto/cipher"
 	"crypto/sha256"
-	"math/rand"
-	"time"
+	"io"
 
-	bls "github.com/kilic/bls12-381"
+	bls "github.com/txhsl/tpke/internal/bls"
 )
 
+// Deprecated: AES-CBC with a key-derived IV and no MAC, use SealThreshold instead
 func AESEncrypt(pg1 *bls.PointG1, msg []byte) ([]byte, error) {
 	if len(msg) < 1 {
 		return nil, NewAESMessageError()
@@ -31,6 +31,7 @@ func AESEncrypt(pg1 *bls.PointG1, msg []byte) ([]byte, error) {
 	return encrypted, nil
 }
 
+// Deprecated: use OpenThreshold instead
 func AESDecrypt(pg1 *bls.PointG1, cipherText []byte) ([]byte, error) {
 	if len(cipherText) < 1 {
 		return nil, NewAESCiphertextError()
@@ -43,23 +44,32 @@ func AESDecrypt(pg1 *bls.PointG1, cipherText []byte) ([]byte, error) {
 		return nil, NewAESDecryptionError()
 	}
 	blockSize := block.BlockSize()
+	if len(cipherText)%blockSize != 0 {
+		return nil, NewAESCiphertextError()
+	}
 
 	blockMode := cipher.NewCBCDecrypter(block, hash[:blockSize])
 	decrypted := make([]byte, len(cipherText))
 	blockMode.CryptBlocks(decrypted, cipherText)
 	result, err := pkcs7UnPadding(decrypted)
 	if err != nil {
-		return nil, NewAESError(err.Error())
+		return nil, NewAESError(err.Error()).withCause(err)
 	}
 
 	return result, nil
 }
 
 func RandPG1() *bls.PointG1 {
-	s1 := rand.NewSource(time.Now().UnixNano())
-	r1 := rand.New(s1)
-	r, _ := bls.NewFr().Rand(r1)
+	pg1, _ := RandPG1WithReader(nil)
+	return pg1
+}
+
+func RandPG1WithReader(random io.Reader) (*bls.PointG1, error) {
+	r, err := randomFr(random)
+	if err != nil {
+		return nil, err
+	}
 	g1 := bls.NewG1()
 	pg1 := g1.New()
-	return g1.MulScalar(pg1, &bls.G1One, r)
+	return g1.MulScalar(pg1, &bls.G1One, r), nil
 }
@@ -1,82 +1,280 @@
 package tpke
 
+import (
+	"errors"
+	"fmt"
+)
+
+// Sentinel errors, every CustomError of a known failure wraps one of them for errors.Is
+var (
+	ErrInvalidMessage     = errors.New("invalid message")
+	ErrEncryption         = errors.New("encryption failed")
+	ErrInvalidCiphertext  = errors.New("invalid ciphertext")
+	ErrDecryption         = errors.New("decryption failed")
+	ErrUnsupportedVersion = errors.New("unsupported version")
+	ErrNotEnoughShares    = errors.New("not enough share")
+	ErrInvalidShare       = errors.New("invalid share")
+	ErrAggregation        = errors.New("aggregation failed")
+	ErrInvalidProof       = errors.New("invalid proof")
+	ErrInvalidPVSS        = errors.New("invalid pvss")
+	ErrInvalidSecret      = errors.New("invalid secret")
+	ErrInvalidIndex       = errors.New("invalid share index")
+	ErrDuplicateIndex     = errors.New("duplicate share index")
+	ErrUnknownSender      = errors.New("unknown sender")
+	ErrUnexpectedRound    = errors.New("unexpected round")
+	ErrNoQualifiedDealer  = errors.New("no qualified dealer")
+	ErrInvalidRefresh     = errors.New("invalid refresh")
+	ErrInvalidReshare     = errors.New("invalid reshare")
+	ErrEncoding           = errors.New("invalid encoding")
+	ErrUnknownKey         = errors.New("unknown key")
+	ErrRetiredKey         = errors.New("retired key")
+)
+
+// CustomError is the error type of the package, use errors.As to read the participants who caused it
 type CustomError struct {
-	Period  string
-	Message string
+	Period       string // Phase where the error happens
+	Message      string
+	Err          error // Sentinel, or the error of another package it wraps
+	Participants []int // Participants who caused the error, nil if unknown
+	CipherText   int   // Index of the ciphertext starting from 0, -1 if unrelated
 }
 
 func (err *CustomError) Error() string {
-	return err.Period + ": " + err.Message
+	msg := err.Period + ": " + err.Message
+	if len(err.Participants) > 0 {
+		msg += fmt.Sprintf(", participants %v", err.Participants)
+	}
+	if err.CipherText >= 0 {
+		msg += fmt.Sprintf(", ciphertext %d", err.CipherText)
+	}
+	return msg
+}
+
+func (err *CustomError) Unwrap() error {
+	return err.Err
+}
+
+func (err *CustomError) withParticipants(indices ...int) *CustomError {
+	err.Participants = append(err.Participants, indices...)
+	return err
+}
+
+func (err *CustomError) withCipherText(index int) *CustomError {
+	err.CipherText = index
+	return err
+}
+
+// Keep the underlying error for errors.Is and errors.As, joined with the sentinel if there is one
+func (err *CustomError) withCause(cause error) *CustomError {
+	if err.Err != nil {
+		err.Err = errors.Join(err.Err, cause)
+	} else {
+		err.Err = cause
+	}
+	return err
+}
+
+// Keep a more specific message than the sentinel
+func (err *CustomError) withMessage(msg string) *CustomError {
+	err.Message = msg
+	return err
+}
+
+func newError(period string, sentinel error) *CustomError {
+	return &CustomError{
+		Period:     period,
+		Message:    sentinel.Error(),
+		Err:        sentinel,
+		CipherText: -1,
+	}
 }
 
 func NewAESError(msg string) *CustomError {
 	return &CustomError{
-		Period:  "aes encryption",
-		Message: msg,
+		Period:     "aes encryption",
+		Message:    msg,
+		CipherText: -1,
 	}
 }
 
 func NewTPKEError(msg string) *CustomError {
 	return &CustomError{
-		Period:  "threshold encryption",
-		Message: msg,
+		Period:     "threshold encryption",
+		Message:    msg,
+		CipherText: -1,
 	}
 }
 
 func NewDKGError(msg string) *CustomError {
 	return &CustomError{
-		Period:  "dkg",
-		Message: msg,
+		Period:     "dkg",
+		Message:    msg,
+		CipherText: -1,
 	}
 }
 
 func NewSigError(msg string) *CustomError {
 	return &CustomError{
-		Period:  "threshold signature",
-		Message: msg,
+		Period:     "threshold signature",
+		Message:    msg,
+		CipherText: -1,
+	}
+}
+
+func NewInterpolationError(msg string) *CustomError {
+	return &CustomError{
+		Period:     "lagrange interpolation",
+		Message:    msg,
+		CipherText: -1,
 	}
 }
 
 func NewAESMessageError() *CustomError {
-	return NewAESError("empty message")
+	return newError("aes encryption", ErrInvalidMessage).withMessage("empty message")
 }
 
 func NewAESEncryptionError() *CustomError {
-	return NewAESError("encryption faild")
+	return newError("aes encryption", ErrEncryption)
 }
 
 func NewAESCiphertextError() *CustomError {
-	return NewAESError("empty ciphertext")
+	return newError("aes encryption", ErrInvalidCiphertext).withMessage("empty ciphertext")
 }
 
 func NewAESDecryptionError() *CustomError {
-	return NewAESError("decryption failed")
+	return newError("aes encryption", ErrDecryption)
 }
 
 func NewTPKENotEnoughShareError() *CustomError {
-	return NewTPKEError("not enough share")
+	return newError("threshold encryption", ErrNotEnoughShares)
 }
 
 func NewTPKECiphertextError() *CustomError {
-	return NewTPKEError("invalid ciphertext")
+	return newError("threshold encryption", ErrInvalidCiphertext)
 }
 
 func NewTPKEDecryptionError() *CustomError {
-	return NewTPKEError("decryption failed")
+	return newError("threshold encryption", ErrDecryption)
 }
 
 func NewSigNotEnoughShareError() *CustomError {
-	return NewSigError("not enough share")
+	return newError("threshold signature", ErrNotEnoughShares)
 }
 
 func NewSigAggregationError() *CustomError {
-	return NewSigError("aggregation failed")
+	return newError("threshold signature", ErrAggregation)
 }
 
 func NewDKGPVSSError() *CustomError {
-	return NewDKGError("invalid pvss")
+	return newError("dkg", ErrInvalidPVSS)
 }
 
 func NewDKGSecretError() *CustomError {
-	return NewDKGError("invalid secret")
+	return newError("dkg", ErrInvalidSecret)
+}
+
+func NewInterpolationIndexError() *CustomError {
+	return newError("lagrange interpolation", ErrInvalidIndex)
+}
+
+func NewInterpolationDuplicateError() *CustomError {
+	return newError("lagrange interpolation", ErrDuplicateIndex)
+}
+
+func NewDKGMessageError() *CustomError {
+	return newError("dkg", ErrInvalidMessage)
+}
+
+func NewDKGSenderError() *CustomError {
+	return newError("dkg", ErrUnknownSender)
+}
+
+func NewDKGRoundError() *CustomError {
+	return newError("dkg", ErrUnexpectedRound)
+}
+
+func NewDKGQualError() *CustomError {
+	return newError("dkg", ErrNoQualifiedDealer)
+}
+
+func NewDKGRefreshError() *CustomError {
+	return newError("dkg", ErrInvalidRefresh)
+}
+
+func NewDKGReshareError() *CustomError {
+	return newError("dkg", ErrInvalidReshare)
+}
+
+func NewTPKEDecryptionShareError() *CustomError {
+	return newError("threshold encryption", ErrInvalidShare).withMessage("invalid decryption share")
+}
+
+func NewTPKEProofError() *CustomError {
+	return newError("threshold encryption", ErrInvalidProof)
+}
+
+func NewAESVersionError() *CustomError {
+	return newError("aes encryption", ErrUnsupportedVersion).withMessage("unsupported envelope version")
+}
+
+// Encoding errors wrap ErrEncoding, except unsupported versions
+func NewEncodingError(msg string) *CustomError {
+	return &CustomError{
+		Period:     "encoding",
+		Message:    msg,
+		Err:        ErrEncoding,
+		CipherText: -1,
+	}
+}
+
+func NewEncodingVersionError() *CustomError {
+	return newError("encoding", ErrUnsupportedVersion)
+}
+
+func NewEncodingTypeError() *CustomError {
+	return NewEncodingError("unexpected type")
+}
+
+func NewEncodingLengthError() *CustomError {
+	return NewEncodingError("invalid length")
+}
+
+func NewEncodingScalarError() *CustomError {
+	return NewEncodingError("non-canonical scalar")
+}
+
+func NewBeaconError(msg string) *CustomError {
+	return &CustomError{
+		Period:     "randomness beacon",
+		Message:    msg,
+		CipherText: -1,
+	}
+}
+
+func NewBeaconRoundError() *CustomError {
+	return newError("randomness beacon", ErrUnexpectedRound)
+}
+
+func NewBeaconShareError() *CustomError {
+	return newError("randomness beacon", ErrInvalidShare)
+}
+
+func NewSigShareError() *CustomError {
+	return newError("threshold signature", ErrInvalidShare)
+}
+
+func NewKeyringError(msg string) *CustomError {
+	return &CustomError{
+		Period:     "keyring",
+		Message:    msg,
+		CipherText: -1,
+	}
+}
+
+func NewKeyringUnknownError() *CustomError {
+	return newError("keyring", ErrUnknownKey)
+}
+
+func NewKeyringRetiredError() *CustomError {
+	return newError("keyring", ErrRetiredKey)
 }
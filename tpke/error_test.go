@@ -0,0 +1,97 @@
+package tpke
+
+import (
+	"encoding/hex"
+	"errors"
+	"reflect"
+	"testing"
+
+	bls "github.com/txhsl/tpke/internal/bls"
+)
+
+func TestErrors(t *testing.T) {
+	size := 7
+	threshold := 5
+	dkg := NewDKG(size, threshold)
+	if err := dkg.Prepare(); err != nil {
+		t.Fatalf(err.Error())
+	}
+	if err := dkg.Verify(); err != nil {
+		t.Fatalf(err.Error())
+	}
+	pubkey := dkg.PublishGlobalPublicKey()
+	prvkeys := dkg.GetPrivateKeys()
+
+	// Participants 2, 4 and 6 send wrong decryption shares of the second ciphertext
+	cts := Encrypt([]*bls.PointG1{RandPG1(), RandPG1()}, pubkey)
+	shares := decryptShare(cts, prvkeys)
+	for _, index := range []int{2, 4, 6} {
+		shares[index][1].pg1 = RandPG1()
+	}
+	_, err := Decrypt(cts, shares, pubkey, threshold, dkg.GetScaler())
+	var e *CustomError
+	if !errors.Is(err, ErrNotEnoughShares) || !errors.As(err, &e) {
+		t.Fatalf("unexpected error %v.", err)
+	}
+	if !reflect.DeepEqual(e.Participants, []int{2, 4, 6}) || e.CipherText != 1 {
+		t.Fatalf("unexpected error %v.", err)
+	}
+
+	// Participants 3, 5 and 7 send wrong signature shares
+	msg := []byte("pizza pizza pizza pizza pizza pizza pizza pizza pizza pizza pizza pizza pizza")
+	inputs := make(map[int]*SignatureShare)
+	for i, sk := range prvkeys {
+		inputs[i] = sk.SignShare(msg)
+	}
+	for _, index := range []int{3, 5, 7} {
+		inputs[index] = prvkeys[1].SignShare(msg)
+	}
+	_, err = AggregateAndVerifySig(pubkey, msg, threshold, inputs, 1)
+	if !errors.Is(err, ErrNotEnoughShares) || !errors.As(err, &e) || !reflect.DeepEqual(e.Participants, []int{3, 5, 7}) {
+		t.Fatalf("unexpected error %v.", err)
+	}
+	if e.CipherText != -1 {
+		t.Fatalf("unexpected ciphertext index.")
+	}
+
+	// All dealers are disqualified
+	dkg = NewDKG(3, 2)
+	if err := dkg.Prepare(); err != nil {
+		t.Fatalf(err.Error())
+	}
+	for _, p := range dkg.participants {
+		p.pvss.bigf[0] = RandPG1()
+	}
+	err = dkg.Verify()
+	if !errors.Is(err, ErrNoQualifiedDealer) || !errors.As(err, &e) || !reflect.DeepEqual(e.Participants, []int{1, 2, 3}) {
+		t.Fatalf("unexpected error %v.", err)
+	}
+	if errors.Is(err, ErrInvalidShare) {
+		t.Fatalf("unexpected sentinel.")
+	}
+}
+
+func TestErrorMessages(t *testing.T) {
+	for _, c := range []struct {
+		err      *CustomError
+		sentinel error
+		msg      string
+	}{
+		{NewAESMessageError(), ErrInvalidMessage, "aes encryption: empty message"},
+		{NewAESCiphertextError(), ErrInvalidCiphertext, "aes encryption: empty ciphertext"},
+		{NewAESVersionError(), ErrUnsupportedVersion, "aes encryption: unsupported envelope version"},
+		{NewTPKEDecryptionShareError(), ErrInvalidShare, "threshold encryption: invalid decryption share"},
+	} {
+		if c.err.Error() != c.msg || !errors.Is(c.err, c.sentinel) {
+			t.Fatalf("unexpected error %v.", c.err)
+		}
+	}
+
+	// Errors of other packages are kept as the cause
+	var h hexBytes
+	err := h.UnmarshalText([]byte("zz"))
+	var cause hex.InvalidByteError
+	if !errors.Is(err, ErrEncoding) || !errors.As(err, &cause) {
+		t.Fatalf("unexpected error %v.", err)
+	}
+}
@@ -3,14 +3,24 @@ module github.com/txhsl/tpke
 go 1.20
 
 require (
+	github.com/coinbase/kryptology v0.0.0
+	github.com/consensys/gnark-crypto v0.12.1
 	github.com/ethereum/go-ethereum v1.13.5
 	github.com/kilic/bls12-381 v0.1.0
+	golang.org/x/crypto v0.15.0
 )
 
 require (
+	filippo.io/edwards25519 v1.0.0-rc.1 // indirect
+	github.com/bits-and-blooms/bitset v1.7.0 // indirect
 	github.com/btcsuite/btcd/btcec/v2 v2.3.2 // indirect
+	github.com/consensys/bavard v0.1.13 // indirect
 	github.com/decred/dcrd/dcrec/secp256k1/v4 v4.2.0 // indirect
 	github.com/holiman/uint256 v1.2.3 // indirect
-	golang.org/x/crypto v0.15.0 // indirect
+	github.com/mmcloughlin/addchain v0.4.0 // indirect
+	github.com/pkg/errors v0.9.1 // indirect
 	golang.org/x/sys v0.14.0 // indirect
+	rsc.io/tmplfunc v0.0.3 // indirect
 )
+
+replace github.com/coinbase/kryptology => ../Coinbase-Kryptology
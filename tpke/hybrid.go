@@ -0,0 +1,177 @@
+package tpke
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"io"
+	"sort"
+
+	bls "github.com/txhsl/tpke/internal/bls"
+	"golang.org/x/crypto/hkdf"
+)
+
+const EnvelopeVersion byte = 1
+
+var envelopeKeyInfo = []byte("TPKE_SEAL_AES256GCM_V1")
+
+// Envelope is a hybrid ciphertext, a CCA threshold encryption of a random point M bound to aad,
+// and an AES-256-GCM encryption of the payload with a key derived from M
+type Envelope struct {
+	version byte
+	kem     *CCACipherText
+	nonce   []byte
+	sealed  []byte
+}
+
+// SealThreshold encrypts an arbitrary payload to the committee, aad is authenticated but not encrypted
+func SealThreshold(pub *PublicKey, plaintext []byte, aad []byte) (*Envelope, error) {
+	return SealThresholdWithReader(pub, plaintext, aad, nil)
+}
+
+// Seal with the seed, the kem randomness and the nonce sampled from random, crypto/rand if nil
+func SealThresholdWithReader(pub *PublicKey, plaintext []byte, aad []byte, random io.Reader) (*Envelope, error) {
+	random = randomReader(random)
+	seed, err := RandPG1WithReader(random)
+	if err != nil {
+		return nil, err
+	}
+	kem, err := pub.EncryptCCAWithReader(seed, aad, random)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newEnvelopeAEAD(seed)
+	if err != nil {
+		return nil, err
+	}
+	env := &Envelope{
+		version: EnvelopeVersion,
+		kem:     kem,
+		nonce:   make([]byte, aead.NonceSize()),
+	}
+	if _, err := io.ReadFull(random, env.nonce); err != nil {
+		return nil, err
+	}
+	env.sealed = aead.Seal(nil, env.nonce, plaintext, env.header())
+	return env, nil
+}
+
+// OpenShare verifies the envelope and returns the decryption share of a participant
+func (sk *PrivateKey) OpenShare(env *Envelope) (*DecryptionShare, error) {
+	return sk.DecryptShareCCA(env.kem)
+}
+
+func (pk *PublicKey) VerifyOpenShare(index int, env *Envelope, share *DecryptionShare) bool {
+	return pk.VerifyDecryptionShareCCA(index, env.kem, share)
+}
+
+// OpenThreshold combines the first threshold shares with valid proofs, participants with invalid shares
+// are reported in the error if not enough shares are left
+func OpenThreshold(env *Envelope, shares map[int]*DecryptionShare, pk *PublicKey, threshold int) ([]byte, error) {
+	if env.version != EnvelopeVersion {
+		return nil, NewAESVersionError()
+	}
+	if err := env.kem.Verify(); err != nil {
+		return nil, err
+	}
+	if threshold < 1 || len(shares) < threshold {
+		return nil, NewTPKENotEnoughShareError()
+	}
+	sorted := make([]int, 0, len(shares))
+	for index := range shares {
+		sorted = append(sorted, index)
+	}
+	sort.Ints(sorted)
+	indices := make([]int, 0, threshold)
+	s := make([]*DecryptionShare, 0, threshold)
+	invalid := make([]int, 0)
+	for _, index := range sorted {
+		if len(indices) == threshold {
+			break
+		}
+		if pk.VerifyOpenShare(index, env, shares[index]) {
+			indices = append(indices, index)
+			s = append(s, shares[index])
+		} else {
+			invalid = append(invalid, index)
+		}
+	}
+	if len(indices) < threshold {
+		return nil, NewTPKENotEnoughShareError().withParticipants(invalid...)
+	}
+	coeff, err := decryptionCoefficients(indices, 1)
+	if err != nil {
+		return nil, err
+	}
+	rpk, err := combineDecryptionShares(coeff, s)
+	if err != nil {
+		return nil, err
+	}
+	g1 := bls.NewG1()
+	seed := g1.Add(g1.New(), env.kem.cMsg, rpk)
+	aead, err := newEnvelopeAEAD(seed)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := aead.Open(nil, env.nonce, env.sealed, env.header())
+	if err != nil {
+		return nil, NewAESDecryptionError()
+	}
+	return plaintext, nil
+}
+
+func (env *Envelope) GetAAD() []byte {
+	return env.kem.label
+}
+
+// Version and kem are authenticated as additional data
+func (env *Envelope) header() []byte {
+	return appendWithLength([]byte{env.version}, env.kem.ToBytes())
+}
+
+func (env *Envelope) ToBytes() []byte {
+	out := env.header()
+	out = append(out, env.nonce...)
+	return append(out, env.sealed...)
+}
+
+func BytesToEnvelope(b []byte) (*Envelope, error) {
+	if len(b) < 1 {
+		return nil, NewEncodingLengthError()
+	}
+	if b[0] != EnvelopeVersion {
+		return nil, NewAESVersionError()
+	}
+	kemBytes, rest, ok := readWithLength(b[1:])
+	if !ok {
+		return nil, NewEncodingLengthError()
+	}
+	kem, err := BytesToCCACipherText(kemBytes)
+	if err != nil {
+		return nil, err
+	}
+	// 12-byte nonce and 16-byte tag of gcm
+	if len(rest) < 12+16 {
+		return nil, NewEncodingLengthError()
+	}
+	return &Envelope{
+		version: b[0],
+		kem:     kem,
+		nonce:   append([]byte{}, rest[:12]...),
+		sealed:  append([]byte{}, rest[12:]...),
+	}, nil
+}
+
+// Derive an aes-256 key from M with hkdf-sha256
+func newEnvelopeAEAD(seed *bls.PointG1) (cipher.AEAD, error) {
+	key := make([]byte, 32)
+	kdf := hkdf.New(sha256.New, bls.NewG1().ToBytes(seed), nil, envelopeKeyInfo)
+	if _, err := io.ReadFull(kdf, key); err != nil {
+		return nil, NewAESError(err.Error()).withCause(err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, NewAESEncryptionError()
+	}
+	return cipher.NewGCM(block)
+}
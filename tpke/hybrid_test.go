@@ -0,0 +1,120 @@
+package tpke
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestSealThreshold(t *testing.T) {
+	size := 7
+	threshold := 5
+	dkg := NewDKG(size, threshold)
+	dkg.Prepare()
+	if err := dkg.Verify(); err != nil {
+		t.Fatalf(err.Error())
+	}
+	pubkey := dkg.PublishGlobalPublicKey()
+	prvkeys := dkg.GetPrivateKeys()
+
+	plaintext := []byte("pizza pizza pizza pizza pizza pizza pizza pizza pizza pizza pizza pizza pizza")
+	aad := []byte("tx 0x01")
+	env, err := SealThreshold(pubkey, plaintext, aad)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	env, err = BytesToEnvelope(env.ToBytes())
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if !bytes.Equal(env.GetAAD(), aad) {
+		t.Fatalf("aad mismatch.")
+	}
+
+	shares := make(map[int]*DecryptionShare)
+	for i := 1; i <= threshold; i++ {
+		share, err := prvkeys[i].OpenShare(env)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		if !pubkey.VerifyOpenShare(i, env, share) {
+			t.Fatalf("valid share rejected.")
+		}
+		shares[i] = share
+	}
+	result, err := OpenThreshold(env, shares, pubkey, threshold)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if !bytes.Equal(plaintext, result) {
+		t.Fatalf("decryption failed.")
+	}
+
+	// Tampered payload
+	b := env.ToBytes()
+	b[len(b)-1] ^= 1
+	tampered, err := BytesToEnvelope(b)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if _, err := OpenThreshold(tampered, shares, pubkey, threshold); err == nil {
+		t.Fatalf("tampered payload accepted.")
+	}
+
+	// Unknown version
+	b[0] = 2
+	if _, err := BytesToEnvelope(b); err == nil {
+		t.Fatalf("unknown version accepted.")
+	}
+	// Truncated envelope
+	b = env.ToBytes()
+	if _, err := BytesToEnvelope(b[:len(b)-len(env.sealed)]); !errors.Is(err, ErrEncoding) {
+		t.Fatalf("truncated envelope accepted.")
+	}
+
+	// A tampered share is dropped, the next valid share is used instead
+	share, err := prvkeys[threshold+1].OpenShare(env)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	shares[threshold+1] = share
+	shares[2] = &DecryptionShare{
+		pg1:   RandPG1(),
+		proof: shares[2].proof,
+	}
+	result, err = OpenThreshold(env, shares, pubkey, threshold)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if !bytes.Equal(plaintext, result) {
+		t.Fatalf("decryption failed.")
+	}
+
+	// Not enough valid shares, the tampered one is reported
+	delete(shares, 1)
+	_, err = OpenThreshold(env, shares, pubkey, threshold)
+	var e *CustomError
+	if !errors.Is(err, ErrNotEnoughShares) || !errors.As(err, &e) || len(e.Participants) != 1 || e.Participants[0] != 2 {
+		t.Fatalf("unexpected error %v.", err)
+	}
+	delete(shares, 2)
+	if _, err := OpenThreshold(env, shares, pubkey, threshold); err == nil {
+		t.Fatalf("decryption should fail.")
+	}
+}
+
+func TestPKCS7UnPadding(t *testing.T) {
+	data := pkcs7Padding([]byte("pizza"), 16)
+	result, err := pkcs7UnPadding(data)
+	if err != nil || !bytes.Equal(result, []byte("pizza")) {
+		t.Fatalf("unpadding failed.")
+	}
+	data[len(data)-2] ^= 1
+	if _, err := pkcs7UnPadding(data); err == nil {
+		t.Fatalf("invalid padding accepted.")
+	}
+	data[len(data)-1] = 0
+	if _, err := pkcs7UnPadding(data); err == nil {
+		t.Fatalf("zero padding accepted.")
+	}
+}
@@ -0,0 +1,7 @@
+// Package bls is the group backend of tpke. It exposes the subset of the kilic/bls12-381 api used by tpke,
+// so that the same code runs on different BLS12-381 implementations.
+//
+// The kilic backend is the default. Build with -tags gnark to run on gnark-crypto, or with -tags kryptology
+// to run on the native BLS12-381 implementation of Kryptology, all backends share the same encodings and
+// hash to curve.
+package bls
@@ -0,0 +1,111 @@
+package bls
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+)
+
+// Known answers shared by all backends, hash to curve vectors are from RFC 9380
+const (
+	g1OneHex  = "97f1d3a73197d7942695638c4fa9ac0fc3688c4f9774b905a14e3a3f171bac586c55e83ff97a1aeffb3af00adb22c6bb"
+	g2OneHex  = "93e02b6052719f607dacd3a088274f65596bd0d09920b61ab5da61bbdc7f5049334cf11213945d57e5ac7d055d042b7e024aa2b2f08f0a91260805272dc51051c6e47ad4fa403b02b4510b647ae3d1770bac0326a805bbefd48056c8c121bdb8"
+	hashG1Hex = "03567bc5ef9c690c2ab2ecdf6a96ef1c139cc0b2f284dca0a9a7943388a49a3aee664ba5379a7655d3c68900be2f69030b9c15f3fe6e5cf4211f346271d7b01c8f3b28be689c8429c85b67af215533311f0b8dfaaa154fa6b88176c229f2885d"
+	hashG2Hex = "139cddbccdc5e91b9623efd38c49f81a6f83f175e80b06fc374de9eb4b41dfe4ca3a230ed250fbe3a2acf73a41177fd802c2d18e033b960562aae3cab37a27ce00d80ccd5ba4b7fe0e7a210245129dbec7780ccc7954725f4168aff2787776e600aa65dae3c8d732d10ecd2c50f8a1baf3001578f71c694e03866e9f3d49ac1e1ce70dd94a733534f106d4cec0eddd161787327b68159716a37440985269cf584bcb1e621d3a7202be6ea05c4cfe244aeb197642555a0645fb87bf7466b2ba48"
+	// sha256 of e(G1,G2) in bytes
+	pairingHex = "300e47c99502f3af33ad2080847d528cabd90365a90ab98bc174565c27928591"
+	wideFrHex  = "0748d9d99f59ff1105d314967254398f2b6cedcb87925c23c999e990f3f29c6c"
+)
+
+func TestBackendEncoding(t *testing.T) {
+	g1 := NewG1()
+	g2 := NewG2()
+	if hex.EncodeToString(g1.ToCompressed(&G1One)) != g1OneHex {
+		t.Fatalf("%s: g1 generator mismatch.", Backend)
+	}
+	if hex.EncodeToString(g2.ToCompressed(&G2One)) != g2OneHex {
+		t.Fatalf("%s: g2 generator mismatch.", Backend)
+	}
+	h1, err := g1.HashToCurve([]byte("abc"), []byte("QUUX-V01-CS02-with-BLS12381G1_XMD:SHA-256_SSWU_RO_"))
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if hex.EncodeToString(g1.ToBytes(h1)) != hashG1Hex {
+		t.Fatalf("%s: g1 hash to curve mismatch.", Backend)
+	}
+	h2, err := g2.HashToCurve([]byte("abc"), []byte("QUUX-V01-CS02-with-BLS12381G2_XMD:SHA-256_SSWU_RO_"))
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if hex.EncodeToString(g2.ToBytes(h2)) != hashG2Hex {
+		t.Fatalf("%s: g2 hash to curve mismatch.", Backend)
+	}
+	e := NewEngine().AddPair(&G1One, &G2One).Result()
+	if h := sha256.Sum256(NewGT().ToBytes(e)); hex.EncodeToString(h[:]) != pairingHex {
+		t.Fatalf("%s: pairing mismatch.", Backend)
+	}
+	// Wide inputs are reduced
+	if hex.EncodeToString(NewFr().FromBytes(bytes.Repeat([]byte{0xff}, 64)).ToBytes()) != wideFrHex {
+		t.Fatalf("%s: scalar reduction mismatch.", Backend)
+	}
+
+	// Round trips, infinity is all zeros
+	p, _ := g1.FromBytes(g1.ToBytes(h1))
+	q, _ := g1.FromCompressed(g1.ToCompressed(h1))
+	if !g1.Equal(p, h1) || !g1.Equal(q, h1) {
+		t.Fatalf("%s: g1 round trip failed.", Backend)
+	}
+	if !bytes.Equal(g1.ToBytes(g1.Zero()), make([]byte, 96)) {
+		t.Fatalf("%s: g1 infinity encoding mismatch.", Backend)
+	}
+	zero, err := g2.FromBytes(make([]byte, 192))
+	if err != nil || !g2.IsZero(zero) {
+		t.Fatalf("%s: g2 infinity decoding failed.", Backend)
+	}
+	if _, err := g1.FromCompressed(make([]byte, 47)); err == nil {
+		t.Fatalf("%s: short input accepted.", Backend)
+	}
+}
+
+func TestBackendArithmetic(t *testing.T) {
+	g1 := NewG1()
+	g2 := NewG2()
+	a, _ := NewFr().Rand(rand.Reader)
+	b, _ := NewFr().Rand(rand.Reader)
+
+	// (a+b)G1 == aG1+bG1 == MultiExp
+	ab := NewFr()
+	ab.Add(a, b)
+	left := g1.MulScalar(g1.New(), &G1One, ab)
+	right := g1.Add(g1.New(), g1.MulScalar(g1.New(), &G1One, a), g1.MulScalar(g1.New(), &G1One, b))
+	multi, err := g1.MultiExp(g1.New(), []*PointG1{g1.One(), g1.One()}, []*Fr{a, b})
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if !g1.Equal(left, right) || !g1.Equal(left, multi) {
+		t.Fatalf("%s: g1 arithmetic mismatch.", Backend)
+	}
+	inv := NewFr()
+	inv.Inverse(a)
+	inv.Mul(inv, a)
+	if !inv.IsOne() {
+		t.Fatalf("%s: scalar inverse mismatch.", Backend)
+	}
+
+	// e(aG1, bG2) == e(abG1, G2)
+	ab.Mul(a, b)
+	e := NewEngine()
+	e.AddPair(g1.MulScalar(g1.New(), &G1One, a), g2.MulScalar(g2.New(), &G2One, b))
+	e.AddPairInv(g1.MulScalar(g1.New(), &G1One, ab), &G2One)
+	if !e.Check() {
+		t.Fatalf("%s: pairing is not bilinear.", Backend)
+	}
+	e.Reset()
+	e.AddPair(g1.MulScalar(g1.New(), &G1One, a), &G2One)
+	e.AddPairInv(&G1One, &G2One)
+	if e.Check() {
+		t.Fatalf("%s: pairing check passed on unequal inputs.", Backend)
+	}
+}
@@ -0,0 +1,561 @@
+//go:build gnark && !kryptology
+
+package bls
+
+import (
+	"crypto/rand"
+	"errors"
+	"io"
+	"math/big"
+
+	"github.com/consensys/gnark-crypto/ecc"
+	bls12381 "github.com/consensys/gnark-crypto/ecc/bls12-381"
+	"github.com/consensys/gnark-crypto/ecc/bls12-381/fp"
+	"github.com/consensys/gnark-crypto/ecc/bls12-381/fr"
+)
+
+const Backend = "gnark"
+
+const (
+	fpByteSize = fp.Bytes
+	frByteSize = fr.Bytes
+)
+
+var (
+	G1One = func() PointG1 {
+		g1, _, _, _ := bls12381.Generators()
+		return PointG1{p: g1}
+	}()
+	G2One = func() PointG2 {
+		_, g2, _, _ := bls12381.Generators()
+		return PointG2{p: g2}
+	}()
+)
+
+// Fr is an element of the scalar field, the zero value is zero
+type Fr struct {
+	f fr.Element
+}
+
+func NewFr() *Fr {
+	return new(Fr)
+}
+
+func (e *Fr) Rand(r io.Reader) (*Fr, error) {
+	bi, err := rand.Int(r, fr.Modulus())
+	if err != nil {
+		return nil, err
+	}
+	e.f.SetBigInt(bi)
+	return e, nil
+}
+
+func (e *Fr) Set(e2 *Fr) *Fr {
+	e.f.Set(&e2.f)
+	return e
+}
+
+func (e *Fr) Zero() *Fr {
+	e.f.SetZero()
+	return e
+}
+
+func (e *Fr) One() *Fr {
+	e.f.SetOne()
+	return e
+}
+
+// FromBytes reads a big-endian integer of any length and reduces it
+func (e *Fr) FromBytes(in []byte) *Fr {
+	e.f.SetBigInt(new(big.Int).SetBytes(in))
+	return e
+}
+
+// ToBytes returns 32 bytes in big-endian
+func (e *Fr) ToBytes() []byte {
+	out := e.f.Bytes()
+	return out[:]
+}
+
+func (e *Fr) IsZero() bool {
+	return e.f.IsZero()
+}
+
+func (e *Fr) IsOne() bool {
+	return e.f.IsOne()
+}
+
+func (e *Fr) Equal(e2 *Fr) bool {
+	return e.f.Equal(&e2.f)
+}
+
+func (e *Fr) Add(a, b *Fr) {
+	e.f.Add(&a.f, &b.f)
+}
+
+func (e *Fr) Double(a *Fr) {
+	e.f.Double(&a.f)
+}
+
+func (e *Fr) Sub(a, b *Fr) {
+	e.f.Sub(&a.f, &b.f)
+}
+
+func (e *Fr) Neg(a *Fr) {
+	e.f.Neg(&a.f)
+}
+
+func (e *Fr) Mul(a, b *Fr) {
+	e.f.Mul(&a.f, &b.f)
+}
+
+func (e *Fr) Square(a *Fr) {
+	e.f.Square(&a.f)
+}
+
+func (e *Fr) Inverse(a *Fr) {
+	e.f.Inverse(&a.f)
+}
+
+func (e *Fr) bigInt() *big.Int {
+	return e.f.BigInt(new(big.Int))
+}
+
+// PointG1 is a point of G1 in jacobian coordinates, the zero value is infinity
+type PointG1 struct {
+	p bls12381.G1Jac
+}
+
+func (p *PointG1) Set(p2 *PointG1) *PointG1 {
+	p.p.Set(&p2.p)
+	return p
+}
+
+func (p *PointG1) Zero() *PointG1 {
+	p.p.X.SetOne()
+	p.p.Y.SetOne()
+	p.p.Z.SetZero()
+	return p
+}
+
+func (p *PointG1) affine() *bls12381.G1Affine {
+	return new(bls12381.G1Affine).FromJacobian(&p.p)
+}
+
+type G1 struct{}
+
+func NewG1() *G1 {
+	return &G1{}
+}
+
+func (g *G1) New() *PointG1 {
+	return g.Zero()
+}
+
+func (g *G1) Zero() *PointG1 {
+	return new(PointG1).Zero()
+}
+
+func (g *G1) One() *PointG1 {
+	return new(PointG1).Set(&G1One)
+}
+
+func (g *G1) IsZero(p *PointG1) bool {
+	return p.p.Z.IsZero()
+}
+
+func (g *G1) Equal(p1, p2 *PointG1) bool {
+	return p1.p.Equal(&p2.p)
+}
+
+func (g *G1) IsOnCurve(p *PointG1) bool {
+	return p.p.IsOnCurve()
+}
+
+func (g *G1) InCorrectSubgroup(p *PointG1) bool {
+	return p.p.IsInSubGroup()
+}
+
+func (g *G1) Affine(p *PointG1) *PointG1 {
+	if !g.IsZero(p) {
+		p.p.FromAffine(p.affine())
+	}
+	return p
+}
+
+func (g *G1) Add(r, p1, p2 *PointG1) *PointG1 {
+	sum := p1.p
+	sum.AddAssign(&p2.p)
+	r.p = sum
+	return r
+}
+
+func (g *G1) Double(r, p *PointG1) *PointG1 {
+	r.p.Double(&p.p)
+	return r
+}
+
+func (g *G1) Neg(r, p *PointG1) *PointG1 {
+	r.p.Neg(&p.p)
+	return r
+}
+
+func (g *G1) Sub(c, a, b *PointG1) *PointG1 {
+	diff := a.p
+	diff.SubAssign(&b.p)
+	c.p = diff
+	return c
+}
+
+func (g *G1) MulScalar(r, p *PointG1, e *Fr) *PointG1 {
+	r.p.ScalarMultiplication(&p.p, e.bigInt())
+	return r
+}
+
+func (g *G1) MultiExp(r *PointG1, points []*PointG1, scalars []*Fr) (*PointG1, error) {
+	if len(points) != len(scalars) {
+		return nil, errors.New("point and scalar vectors should be in same length")
+	}
+	if len(points) == 0 {
+		return r.Zero(), nil
+	}
+	ps := make([]bls12381.G1Affine, len(points))
+	ss := make([]fr.Element, len(scalars))
+	for i := range points {
+		ps[i].FromJacobian(&points[i].p)
+		ss[i] = scalars[i].f
+	}
+	if _, err := r.p.MultiExp(ps, ss, ecc.MultiExpConfig{}); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (g *G1) ToCompressed(p *PointG1) []byte {
+	out := p.affine().Bytes()
+	return out[:]
+}
+
+func (g *G1) FromCompressed(compressed []byte) (*PointG1, error) {
+	if len(compressed) != fpByteSize {
+		return nil, errors.New("input string length must be equal to 48 bytes")
+	}
+	var a bls12381.G1Affine
+	if _, err := a.SetBytes(compressed); err != nil {
+		return nil, err
+	}
+	p := new(PointG1)
+	p.p.FromAffine(&a)
+	return p, nil
+}
+
+// ToBytes returns x and y without flags, and zeros for infinity
+func (g *G1) ToBytes(p *PointG1) []byte {
+	if g.IsZero(p) {
+		return make([]byte, 2*fpByteSize)
+	}
+	out := p.affine().RawBytes()
+	return out[:]
+}
+
+func (g *G1) FromBytes(in []byte) (*PointG1, error) {
+	if len(in) != 2*fpByteSize {
+		return nil, errors.New("input string length must be equal to 96 bytes")
+	}
+	if isZeroBytes(in) {
+		return g.Zero(), nil
+	}
+	if in[0]&0xe0 != 0 {
+		return nil, errors.New("invalid point encoding")
+	}
+	var a bls12381.G1Affine
+	if _, err := a.SetBytes(in); err != nil {
+		return nil, err
+	}
+	p := new(PointG1)
+	p.p.FromAffine(&a)
+	return p, nil
+}
+
+// HashToCurve implements hash_to_curve with expand_message_xmd and sha256
+func (g *G1) HashToCurve(msg, domain []byte) (*PointG1, error) {
+	a, err := bls12381.HashToG1(msg, domain)
+	if err != nil {
+		return nil, err
+	}
+	p := new(PointG1)
+	p.p.FromAffine(&a)
+	return p, nil
+}
+
+// PointG2 is a point of G2 in jacobian coordinates, the zero value is infinity
+type PointG2 struct {
+	p bls12381.G2Jac
+}
+
+func (p *PointG2) Set(p2 *PointG2) *PointG2 {
+	p.p.Set(&p2.p)
+	return p
+}
+
+func (p *PointG2) Zero() *PointG2 {
+	p.p.X.SetOne()
+	p.p.Y.SetOne()
+	p.p.Z.SetZero()
+	return p
+}
+
+func (p *PointG2) affine() *bls12381.G2Affine {
+	return new(bls12381.G2Affine).FromJacobian(&p.p)
+}
+
+type G2 struct{}
+
+func NewG2() *G2 {
+	return &G2{}
+}
+
+func (g *G2) New() *PointG2 {
+	return g.Zero()
+}
+
+func (g *G2) Zero() *PointG2 {
+	return new(PointG2).Zero()
+}
+
+func (g *G2) One() *PointG2 {
+	return new(PointG2).Set(&G2One)
+}
+
+func (g *G2) IsZero(p *PointG2) bool {
+	return p.p.Z.IsZero()
+}
+
+func (g *G2) Equal(p1, p2 *PointG2) bool {
+	return p1.p.Equal(&p2.p)
+}
+
+func (g *G2) IsOnCurve(p *PointG2) bool {
+	return p.p.IsOnCurve()
+}
+
+func (g *G2) InCorrectSubgroup(p *PointG2) bool {
+	return p.p.IsInSubGroup()
+}
+
+func (g *G2) Affine(p *PointG2) *PointG2 {
+	if !g.IsZero(p) {
+		p.p.FromAffine(p.affine())
+	}
+	return p
+}
+
+func (g *G2) Add(r, p1, p2 *PointG2) *PointG2 {
+	sum := p1.p
+	sum.AddAssign(&p2.p)
+	r.p = sum
+	return r
+}
+
+func (g *G2) Double(r, p *PointG2) *PointG2 {
+	r.p.Double(&p.p)
+	return r
+}
+
+func (g *G2) Neg(r, p *PointG2) *PointG2 {
+	r.p.Neg(&p.p)
+	return r
+}
+
+func (g *G2) Sub(c, a, b *PointG2) *PointG2 {
+	diff := a.p
+	diff.SubAssign(&b.p)
+	c.p = diff
+	return c
+}
+
+func (g *G2) MulScalar(r, p *PointG2, e *Fr) *PointG2 {
+	r.p.ScalarMultiplication(&p.p, e.bigInt())
+	return r
+}
+
+func (g *G2) MultiExp(r *PointG2, points []*PointG2, scalars []*Fr) (*PointG2, error) {
+	if len(points) != len(scalars) {
+		return nil, errors.New("point and scalar vectors should be in same length")
+	}
+	if len(points) == 0 {
+		return r.Zero(), nil
+	}
+	ps := make([]bls12381.G2Affine, len(points))
+	ss := make([]fr.Element, len(scalars))
+	for i := range points {
+		ps[i].FromJacobian(&points[i].p)
+		ss[i] = scalars[i].f
+	}
+	if _, err := r.p.MultiExp(ps, ss, ecc.MultiExpConfig{}); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (g *G2) ToCompressed(p *PointG2) []byte {
+	out := p.affine().Bytes()
+	return out[:]
+}
+
+func (g *G2) FromCompressed(compressed []byte) (*PointG2, error) {
+	if len(compressed) != 2*fpByteSize {
+		return nil, errors.New("input string length must be equal to 96 bytes")
+	}
+	var a bls12381.G2Affine
+	if _, err := a.SetBytes(compressed); err != nil {
+		return nil, err
+	}
+	p := new(PointG2)
+	p.p.FromAffine(&a)
+	return p, nil
+}
+
+// ToBytes returns x and y without flags, and zeros for infinity
+func (g *G2) ToBytes(p *PointG2) []byte {
+	if g.IsZero(p) {
+		return make([]byte, 4*fpByteSize)
+	}
+	out := p.affine().RawBytes()
+	return out[:]
+}
+
+func (g *G2) FromBytes(in []byte) (*PointG2, error) {
+	if len(in) != 4*fpByteSize {
+		return nil, errors.New("input string length must be equal to 192 bytes")
+	}
+	if isZeroBytes(in) {
+		return g.Zero(), nil
+	}
+	if in[0]&0xe0 != 0 {
+		return nil, errors.New("invalid point encoding")
+	}
+	var a bls12381.G2Affine
+	if _, err := a.SetBytes(in); err != nil {
+		return nil, err
+	}
+	p := new(PointG2)
+	p.p.FromAffine(&a)
+	return p, nil
+}
+
+// HashToCurve implements hash_to_curve with expand_message_xmd and sha256
+func (g *G2) HashToCurve(msg, domain []byte) (*PointG2, error) {
+	a, err := bls12381.HashToG2(msg, domain)
+	if err != nil {
+		return nil, err
+	}
+	p := new(PointG2)
+	p.p.FromAffine(&a)
+	return p, nil
+}
+
+// E is an element of the target group
+type E struct {
+	gt bls12381.GT
+}
+
+func (e *E) Set(e2 *E) *E {
+	e.gt.Set(&e2.gt)
+	return e
+}
+
+func (e *E) One() *E {
+	e.gt.SetOne()
+	return e
+}
+
+func (e *E) IsOne() bool {
+	return e.gt.IsOne()
+}
+
+func (e *E) Equal(e2 *E) bool {
+	return e.gt.Equal(&e2.gt)
+}
+
+type GT struct{}
+
+func NewGT() *GT {
+	return &GT{}
+}
+
+// ToBytes returns the coefficients in the order of kilic, from the highest to the lowest
+func (g *GT) ToBytes(e *E) []byte {
+	out := e.gt.Bytes()
+	return out[:]
+}
+
+// Engine collects pairs and computes their product with one final exponentiation
+type Engine struct {
+	g1s []bls12381.G1Affine
+	g2s []bls12381.G2Affine
+}
+
+func NewEngine() *Engine {
+	return &Engine{}
+}
+
+func (e *Engine) AddPair(g1 *PointG1, g2 *PointG2) *Engine {
+	if g1.p.Z.IsZero() || g2.p.Z.IsZero() {
+		return e
+	}
+	e.g1s = append(e.g1s, *g1.affine())
+	e.g2s = append(e.g2s, *g2.affine())
+	return e
+}
+
+// AddPairInv adds a pair with the G1 point negated
+func (e *Engine) AddPairInv(g1 *PointG1, g2 *PointG2) *Engine {
+	neg := new(PointG1)
+	neg.p.Neg(&g1.p)
+	return e.AddPair(neg, g2)
+}
+
+func (e *Engine) Reset() *Engine {
+	e.g1s = nil
+	e.g2s = nil
+	return e
+}
+
+// Check computes the pairing product and checks if it is one
+func (e *Engine) Check() bool {
+	r, err := e.product()
+	return err == nil && r.IsOne()
+}
+
+// Result computes the pairing product and deletes added pairs
+func (e *Engine) Result() *E {
+	r, err := e.product()
+	e.Reset()
+	if err != nil {
+		return nil
+	}
+	return r
+}
+
+func (e *Engine) product() (*E, error) {
+	r := new(E).One()
+	if len(e.g1s) == 0 {
+		return r, nil
+	}
+	gt, err := bls12381.Pair(e.g1s, e.g2s)
+	if err != nil {
+		return nil, err
+	}
+	r.gt = gt
+	return r, nil
+}
+
+func isZeroBytes(b []byte) bool {
+	for _, v := range b {
+		if v != 0 {
+			return false
+		}
+	}
+	return true
+}
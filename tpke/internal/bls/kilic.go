@@ -0,0 +1,45 @@
+//go:build !kryptology && !gnark
+
+package bls
+
+import (
+	kilic "github.com/kilic/bls12-381"
+)
+
+const Backend = "kilic"
+
+type (
+	Fr      = kilic.Fr
+	PointG1 = kilic.PointG1
+	PointG2 = kilic.PointG2
+	G1      = kilic.G1
+	G2      = kilic.G2
+	E       = kilic.E
+	GT      = kilic.GT
+	Engine  = kilic.Engine
+)
+
+var (
+	G1One = kilic.G1One
+	G2One = kilic.G2One
+)
+
+func NewFr() *Fr {
+	return kilic.NewFr()
+}
+
+func NewG1() *G1 {
+	return kilic.NewG1()
+}
+
+func NewG2() *G2 {
+	return kilic.NewG2()
+}
+
+func NewGT() *GT {
+	return kilic.NewGT()
+}
+
+func NewEngine() *Engine {
+	return kilic.NewEngine()
+}
@@ -0,0 +1,500 @@
+//go:build kryptology
+
+package bls
+
+import (
+	"crypto/rand"
+	"errors"
+	"io"
+	"math/big"
+
+	"github.com/coinbase/kryptology/pkg/core/curves/native"
+	"github.com/coinbase/kryptology/pkg/core/curves/native/bls12381"
+)
+
+const Backend = "kryptology"
+
+const (
+	fpByteSize = bls12381.FieldBytes
+	frByteSize = native.FieldBytes
+)
+
+var (
+	G1One = PointG1{p: *new(bls12381.G1).Generator()}
+	G2One = PointG2{p: *new(bls12381.G2).Generator()}
+)
+
+// Fr is an element of the scalar field, the zero value is zero
+type Fr struct {
+	f native.Field
+}
+
+func NewFr() *Fr {
+	return new(Fr).init()
+}
+
+func (e *Fr) init() *Fr {
+	if e.f.Arithmetic == nil {
+		e.f = *bls12381.Bls12381FqNew()
+	}
+	return e
+}
+
+func (e *Fr) field() *native.Field {
+	return &e.init().f
+}
+
+func (e *Fr) Rand(r io.Reader) (*Fr, error) {
+	bi, err := rand.Int(r, e.field().Params.BiModulus)
+	if err != nil {
+		return nil, err
+	}
+	e.field().SetBigInt(bi)
+	return e, nil
+}
+
+func (e *Fr) Set(e2 *Fr) *Fr {
+	e.field().Set(e2.field())
+	return e
+}
+
+func (e *Fr) Zero() *Fr {
+	e.field().SetZero()
+	return e
+}
+
+func (e *Fr) One() *Fr {
+	e.field().SetOne()
+	return e
+}
+
+// FromBytes reads a big-endian integer of any length and reduces it
+func (e *Fr) FromBytes(in []byte) *Fr {
+	e.field().SetBigInt(new(big.Int).SetBytes(in))
+	return e
+}
+
+// ToBytes returns 32 bytes in big-endian
+func (e *Fr) ToBytes() []byte {
+	return e.field().BigInt().FillBytes(make([]byte, frByteSize))
+}
+
+func (e *Fr) IsZero() bool {
+	return e.field().IsZero() == 1
+}
+
+func (e *Fr) IsOne() bool {
+	return e.field().IsOne() == 1
+}
+
+func (e *Fr) Equal(e2 *Fr) bool {
+	return e.field().Equal(e2.field()) == 1
+}
+
+func (e *Fr) Add(a, b *Fr) {
+	e.field().Add(a.field(), b.field())
+}
+
+func (e *Fr) Double(a *Fr) {
+	e.field().Double(a.field())
+}
+
+func (e *Fr) Sub(a, b *Fr) {
+	e.field().Sub(a.field(), b.field())
+}
+
+func (e *Fr) Neg(a *Fr) {
+	e.field().Neg(a.field())
+}
+
+func (e *Fr) Mul(a, b *Fr) {
+	e.field().Mul(a.field(), b.field())
+}
+
+func (e *Fr) Square(a *Fr) {
+	e.field().Square(a.field())
+}
+
+func (e *Fr) Inverse(a *Fr) {
+	e.field().Invert(a.field())
+}
+
+// PointG1 is a point of G1 in projective coordinates, the zero value is infinity
+type PointG1 struct {
+	p bls12381.G1
+}
+
+func (p *PointG1) Set(p2 *PointG1) *PointG1 {
+	p.p.Set(&p2.p)
+	return p
+}
+
+func (p *PointG1) Zero() *PointG1 {
+	p.p.Identity()
+	return p
+}
+
+type G1 struct{}
+
+func NewG1() *G1 {
+	return &G1{}
+}
+
+func (g *G1) New() *PointG1 {
+	return g.Zero()
+}
+
+func (g *G1) Zero() *PointG1 {
+	return new(PointG1).Zero()
+}
+
+func (g *G1) One() *PointG1 {
+	return new(PointG1).Set(&G1One)
+}
+
+func (g *G1) IsZero(p *PointG1) bool {
+	return p.p.IsIdentity() == 1
+}
+
+func (g *G1) Equal(p1, p2 *PointG1) bool {
+	return p1.p.Equal(&p2.p) == 1
+}
+
+func (g *G1) IsOnCurve(p *PointG1) bool {
+	return p.p.IsOnCurve() == 1
+}
+
+func (g *G1) InCorrectSubgroup(p *PointG1) bool {
+	return p.p.InCorrectSubgroup() == 1
+}
+
+func (g *G1) Affine(p *PointG1) *PointG1 {
+	if !g.IsZero(p) {
+		p.p.ToAffine(&p.p)
+	}
+	return p
+}
+
+func (g *G1) Add(r, p1, p2 *PointG1) *PointG1 {
+	r.p.Add(&p1.p, &p2.p)
+	return r
+}
+
+func (g *G1) Double(r, p *PointG1) *PointG1 {
+	r.p.Double(&p.p)
+	return r
+}
+
+func (g *G1) Neg(r, p *PointG1) *PointG1 {
+	r.p.Neg(&p.p)
+	return r
+}
+
+func (g *G1) Sub(c, a, b *PointG1) *PointG1 {
+	c.p.Sub(&a.p, &b.p)
+	return c
+}
+
+func (g *G1) MulScalar(r, p *PointG1, e *Fr) *PointG1 {
+	r.p.Mul(&p.p, e.field())
+	return r
+}
+
+func (g *G1) MultiExp(r *PointG1, points []*PointG1, scalars []*Fr) (*PointG1, error) {
+	if len(points) != len(scalars) {
+		return nil, errors.New("point and scalar vectors should be in same length")
+	}
+	ps := make([]*bls12381.G1, len(points))
+	ss := make([]*native.Field, len(scalars))
+	for i := range points {
+		ps[i] = &points[i].p
+		ss[i] = scalars[i].field()
+	}
+	if _, err := r.p.SumOfProducts(ps, ss); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (g *G1) ToCompressed(p *PointG1) []byte {
+	out := p.p.ToCompressed()
+	return out[:]
+}
+
+func (g *G1) FromCompressed(compressed []byte) (*PointG1, error) {
+	if len(compressed) != fpByteSize {
+		return nil, errors.New("input string length must be equal to 48 bytes")
+	}
+	var in [fpByteSize]byte
+	copy(in[:], compressed)
+	p := new(PointG1)
+	if _, err := p.p.FromCompressed(&in); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// ToBytes returns x and y without flags, and zeros for infinity
+func (g *G1) ToBytes(p *PointG1) []byte {
+	if g.IsZero(p) {
+		return make([]byte, 2*fpByteSize)
+	}
+	out := p.p.ToUncompressed()
+	return out[:]
+}
+
+func (g *G1) FromBytes(in []byte) (*PointG1, error) {
+	if len(in) != 2*fpByteSize {
+		return nil, errors.New("input string length must be equal to 96 bytes")
+	}
+	if isZeroBytes(in) {
+		return g.Zero(), nil
+	}
+	var b [2 * fpByteSize]byte
+	copy(b[:], in)
+	p := new(PointG1)
+	if _, err := p.p.FromUncompressed(&b); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// HashToCurve implements hash_to_curve with expand_message_xmd and sha256
+func (g *G1) HashToCurve(msg, domain []byte) (*PointG1, error) {
+	p := new(PointG1)
+	p.p.Hash(native.EllipticPointHasherSha256(), msg, domain)
+	return p, nil
+}
+
+// PointG2 is a point of G2 in projective coordinates, the zero value is infinity
+type PointG2 struct {
+	p bls12381.G2
+}
+
+func (p *PointG2) Set(p2 *PointG2) *PointG2 {
+	p.p.Set(&p2.p)
+	return p
+}
+
+func (p *PointG2) Zero() *PointG2 {
+	p.p.Identity()
+	return p
+}
+
+type G2 struct{}
+
+func NewG2() *G2 {
+	return &G2{}
+}
+
+func (g *G2) New() *PointG2 {
+	return g.Zero()
+}
+
+func (g *G2) Zero() *PointG2 {
+	return new(PointG2).Zero()
+}
+
+func (g *G2) One() *PointG2 {
+	return new(PointG2).Set(&G2One)
+}
+
+func (g *G2) IsZero(p *PointG2) bool {
+	return p.p.IsIdentity() == 1
+}
+
+func (g *G2) Equal(p1, p2 *PointG2) bool {
+	return p1.p.Equal(&p2.p) == 1
+}
+
+func (g *G2) IsOnCurve(p *PointG2) bool {
+	return p.p.IsOnCurve() == 1
+}
+
+func (g *G2) InCorrectSubgroup(p *PointG2) bool {
+	return p.p.InCorrectSubgroup() == 1
+}
+
+func (g *G2) Affine(p *PointG2) *PointG2 {
+	if !g.IsZero(p) {
+		p.p.ToAffine(&p.p)
+	}
+	return p
+}
+
+func (g *G2) Add(r, p1, p2 *PointG2) *PointG2 {
+	r.p.Add(&p1.p, &p2.p)
+	return r
+}
+
+func (g *G2) Double(r, p *PointG2) *PointG2 {
+	r.p.Double(&p.p)
+	return r
+}
+
+func (g *G2) Neg(r, p *PointG2) *PointG2 {
+	r.p.Neg(&p.p)
+	return r
+}
+
+func (g *G2) Sub(c, a, b *PointG2) *PointG2 {
+	c.p.Sub(&a.p, &b.p)
+	return c
+}
+
+func (g *G2) MulScalar(r, p *PointG2, e *Fr) *PointG2 {
+	r.p.Mul(&p.p, e.field())
+	return r
+}
+
+func (g *G2) MultiExp(r *PointG2, points []*PointG2, scalars []*Fr) (*PointG2, error) {
+	if len(points) != len(scalars) {
+		return nil, errors.New("point and scalar vectors should be in same length")
+	}
+	ps := make([]*bls12381.G2, len(points))
+	ss := make([]*native.Field, len(scalars))
+	for i := range points {
+		ps[i] = &points[i].p
+		ss[i] = scalars[i].field()
+	}
+	if _, err := r.p.SumOfProducts(ps, ss); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (g *G2) ToCompressed(p *PointG2) []byte {
+	out := p.p.ToCompressed()
+	return out[:]
+}
+
+func (g *G2) FromCompressed(compressed []byte) (*PointG2, error) {
+	if len(compressed) != 2*fpByteSize {
+		return nil, errors.New("input string length must be equal to 96 bytes")
+	}
+	var in [2 * fpByteSize]byte
+	copy(in[:], compressed)
+	p := new(PointG2)
+	if _, err := p.p.FromCompressed(&in); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// ToBytes returns x and y without flags, and zeros for infinity
+func (g *G2) ToBytes(p *PointG2) []byte {
+	if g.IsZero(p) {
+		return make([]byte, 4*fpByteSize)
+	}
+	out := p.p.ToUncompressed()
+	return out[:]
+}
+
+func (g *G2) FromBytes(in []byte) (*PointG2, error) {
+	if len(in) != 4*fpByteSize {
+		return nil, errors.New("input string length must be equal to 192 bytes")
+	}
+	if isZeroBytes(in) {
+		return g.Zero(), nil
+	}
+	var b [4 * fpByteSize]byte
+	copy(b[:], in)
+	p := new(PointG2)
+	if _, err := p.p.FromUncompressed(&b); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// HashToCurve implements hash_to_curve with expand_message_xmd and sha256
+func (g *G2) HashToCurve(msg, domain []byte) (*PointG2, error) {
+	p := new(PointG2)
+	p.p.Hash(native.EllipticPointHasherSha256(), msg, domain)
+	return p, nil
+}
+
+// E is an element of the target group
+type E struct {
+	gt bls12381.Gt
+}
+
+func (e *E) Set(e2 *E) *E {
+	e.gt.Set(&e2.gt)
+	return e
+}
+
+func (e *E) One() *E {
+	e.gt.SetOne()
+	return e
+}
+
+func (e *E) IsOne() bool {
+	return e.gt.IsOne() == 1
+}
+
+func (e *E) Equal(e2 *E) bool {
+	return e.gt.Equal(&e2.gt) == 1
+}
+
+type GT struct{}
+
+func NewGT() *GT {
+	return &GT{}
+}
+
+// ToBytes returns the coefficients in the order of kilic, from the highest to the lowest
+func (g *GT) ToBytes(e *E) []byte {
+	in := e.gt.Bytes()
+	out := make([]byte, 12*fpByteSize)
+	for i := 0; i < 12; i++ {
+		copy(out[i*fpByteSize:(i+1)*fpByteSize], in[(11-i)*fpByteSize:(12-i)*fpByteSize])
+	}
+	return out
+}
+
+type Engine struct {
+	e bls12381.Engine
+}
+
+func NewEngine() *Engine {
+	return &Engine{}
+}
+
+func (e *Engine) AddPair(g1 *PointG1, g2 *PointG2) *Engine {
+	e.e.AddPair(&g1.p, &g2.p)
+	return e
+}
+
+// AddPairInv adds a pair with the G1 point negated
+func (e *Engine) AddPairInv(g1 *PointG1, g2 *PointG2) *Engine {
+	e.e.AddPairInvG1(&g1.p, &g2.p)
+	return e
+}
+
+func (e *Engine) Reset() *Engine {
+	e.e.Reset()
+	return e
+}
+
+// Check computes the pairing product and checks if it is one
+func (e *Engine) Check() bool {
+	return e.e.Check()
+}
+
+// Result computes the pairing product and deletes added pairs
+func (e *Engine) Result() *E {
+	r := &E{
+		gt: *e.e.Result(),
+	}
+	e.e.Reset()
+	return r
+}
+
+func isZeroBytes(b []byte) bool {
+	for _, v := range b {
+		if v != 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,144 @@
+package tpke
+
+import (
+	"crypto/sha512"
+	"encoding/binary"
+	"io"
+
+	bls "github.com/txhsl/tpke/internal/bls"
+)
+
+var batchMaskDomain = []byte("TPKE_BATCH_MASK_V1")
+
+// BatchCipherText encrypts many field elements under a single R=rG1, c_i=m_i+H(rPK,i).
+// One decryption share per participant opens the whole batch, so it suits a block of seeds prepared by
+// the same party, e.g. a sequencer. Whoever knows r can decrypt every item of the batch
+type BatchCipherText struct {
+	bigR       *bls.PointG1
+	commitment *bls.PointG2 // rG2, binds R as in CipherText
+	cs         []*bls.Fr
+}
+
+func EncryptBatch(msgs []*bls.Fr, pub *PublicKey) (*BatchCipherText, error) {
+	return EncryptBatchWithReader(msgs, pub, nil)
+}
+
+// Random seeds in msgs can be used as aes-256 keys with ToBytes. r is sampled from random, crypto/rand if nil
+func EncryptBatchWithReader(msgs []*bls.Fr, pub *PublicKey, random io.Reader) (*BatchCipherText, error) {
+	r, err := randomFr(random)
+	if err != nil {
+		return nil, err
+	}
+	g1 := bls.NewG1()
+	g2 := bls.NewG2()
+	rpk := g1.MulScalar(g1.New(), pub.pg1, r)
+	ct := &BatchCipherText{
+		bigR:       g1.MulScalar(g1.New(), &bls.G1One, r),
+		commitment: g2.MulScalar(g2.New(), &bls.G2One, r),
+		cs:         make([]*bls.Fr, len(msgs)),
+	}
+	masks := batchMasks(rpk, len(msgs))
+	for i, m := range msgs {
+		ct.cs[i] = bls.NewFr()
+		ct.cs[i].Add(m, masks[i])
+	}
+	return ct, nil
+}
+
+func (ct *BatchCipherText) Len() int {
+	return len(ct.cs)
+}
+
+// Verify checks e(R,G2)==e(G1,rG2) like CipherText.Verify
+func (ct *BatchCipherText) Verify() error {
+	return ct.cipherText().Verify()
+}
+
+// Decryption shares, verification and combination are the same as a single ciphertext with C=0,
+// where the decryption is C-rPK=-rPK
+func (ct *BatchCipherText) cipherText() *CipherText {
+	return &CipherText{
+		cMsg:       bls.NewG1().Zero(),
+		bigR:       ct.bigR,
+		commitment: ct.commitment,
+	}
+}
+
+// S=R*sk, once for all items of the batch
+func (sk *PrivateKey) DecryptBatchShare(ct *BatchCipherText) *DecryptionShare {
+	return sk.DecryptShare(ct.cipherText())
+}
+
+func (pk *PublicKey) VerifyBatchDecryptionShare(index int, ct *BatchCipherText, share *DecryptionShare) bool {
+	return pk.VerifyDecryptionShare(index, ct.cipherText(), share)
+}
+
+// DecryptBatch combines shares as Decrypt does, then removes the masks m_i=c_i-H(rPK,i)
+func DecryptBatch(ct *BatchCipherText, inputs map[int]*DecryptionShare, pub *PublicKey, threshold int, scaler int) ([]*bls.Fr, error) {
+	shares := make(map[int]([]*DecryptionShare), len(inputs))
+	for index, share := range inputs {
+		shares[index] = []*DecryptionShare{share}
+	}
+	results, err := Decrypt([]*CipherText{ct.cipherText()}, shares, pub, threshold, scaler)
+	if err != nil {
+		return nil, err
+	}
+	g1 := bls.NewG1()
+	rpk := g1.Neg(g1.New(), results[0])
+	masks := batchMasks(rpk, len(ct.cs))
+	msgs := make([]*bls.Fr, len(ct.cs))
+	for i, c := range ct.cs {
+		msgs[i] = bls.NewFr()
+		msgs[i].Sub(c, masks[i])
+	}
+	return msgs, nil
+}
+
+// H(rPK,i) is sha512 of the domain, rPK and i in 4 bytes, reduced to Fr
+func batchMasks(rpk *bls.PointG1, n int) []*bls.Fr {
+	prefix := append(append([]byte{}, batchMaskDomain...), bls.NewG1().ToBytes(rpk)...)
+	masks := make([]*bls.Fr, n)
+	for i := 0; i < n; i++ {
+		h := sha512.Sum512(binary.BigEndian.AppendUint32(append([]byte{}, prefix...), uint32(i)))
+		masks[i] = bls.NewFr().FromBytes(h[:])
+	}
+	return masks
+}
+
+// Encode R and rG2 compressed, followed by the items in 32 bytes
+func (ct *BatchCipherText) ToBytes() []byte {
+	out := make([]byte, 0, 3*fpByteSize+32*len(ct.cs))
+	out = append(out, bls.NewG1().ToCompressed(ct.bigR)...)
+	out = append(out, bls.NewG2().ToCompressed(ct.commitment)...)
+	for _, c := range ct.cs {
+		out = append(out, c.ToBytes()...)
+	}
+	return out
+}
+
+func BytesToBatchCipherText(b []byte) (*BatchCipherText, error) {
+	if len(b) < 3*fpByteSize || (len(b)-3*fpByteSize)%32 != 0 {
+		return nil, NewTPKECiphertextError()
+	}
+	bigR, err := decodeG1(b[:fpByteSize])
+	if err != nil {
+		return nil, err
+	}
+	commitment, err := decodeG2(b[fpByteSize : 3*fpByteSize])
+	if err != nil {
+		return nil, err
+	}
+	ct := &BatchCipherText{
+		bigR:       bigR,
+		commitment: commitment,
+		cs:         make([]*bls.Fr, 0, (len(b)-3*fpByteSize)/32),
+	}
+	for rest := b[3*fpByteSize:]; len(rest) > 0; rest = rest[32:] {
+		c, err := decodeFr(rest[:32])
+		if err != nil {
+			return nil, err
+		}
+		ct.cs = append(ct.cs, c)
+	}
+	return ct, nil
+}
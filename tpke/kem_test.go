@@ -0,0 +1,71 @@
+package tpke
+
+import (
+	"testing"
+
+	bls "github.com/txhsl/tpke/internal/bls"
+)
+
+func TestBatchEncryption(t *testing.T) {
+	size := 7
+	threshold := 5
+	dkg := NewDKG(size, threshold)
+	dkg.Prepare()
+	if err := dkg.Verify(); err != nil {
+		t.Fatalf(err.Error())
+	}
+	pubkey := dkg.PublishGlobalPublicKey()
+	prvkeys := dkg.GetPrivateKeys()
+
+	// Seeds of a block share one R
+	seeds := make([]*bls.Fr, 1000)
+	for i := range seeds {
+		fr, err := randomFr(nil)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		seeds[i] = fr
+	}
+	encrypted, err := EncryptBatch(seeds, pubkey)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	ct, err := BytesToBatchCipherText(encrypted.ToBytes())
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if err := ct.Verify(); err != nil || ct.Len() != len(seeds) {
+		t.Fatalf("invalid ciphertext.")
+	}
+
+	// One share per participant for the whole batch
+	shares := make(map[int]*DecryptionShare)
+	for i, sk := range prvkeys {
+		shares[i] = sk.DecryptBatchShare(ct)
+		if !pubkey.VerifyBatchDecryptionShare(i, ct, shares[i]) {
+			t.Fatalf("valid share rejected.")
+		}
+	}
+	// Put a wrong share
+	shares[2].pg1 = RandPG1()
+	if pubkey.VerifyBatchDecryptionShare(2, ct, shares[2]) {
+		t.Fatalf("invalid share accepted.")
+	}
+
+	results, err := DecryptBatch(ct, shares, pubkey, threshold, dkg.GetScaler())
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	for i := range seeds {
+		if !seeds[i].Equal(results[i]) {
+			t.Fatalf("batch decryption failed.")
+		}
+	}
+
+	// Not enough valid shares
+	delete(shares, 1)
+	delete(shares, 3)
+	if _, err := DecryptBatch(ct, shares, pubkey, threshold, dkg.GetScaler()); err == nil {
+		t.Fatalf("decrypted without enough shares.")
+	}
+}
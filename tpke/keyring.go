@@ -0,0 +1,175 @@
+package tpke
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"sort"
+	"sync"
+
+	bls "github.com/txhsl/tpke/internal/bls"
+)
+
+var keyIDDomain = []byte("TPKE_KEY_ID_V1")
+
+var keyIDSize = 40
+
+// KeyID tells the key generation of ciphertexts and signatures, an epoch number and a hash of the global public key
+type KeyID struct {
+	Epoch       uint64
+	Fingerprint [32]byte
+}
+
+// KeyID of the global public key in an epoch, the fingerprint hashes PK, and PK' in G2 for the min-sig scheme
+func (pk *PublicKey) KeyID(epoch uint64) KeyID {
+	h := sha256.New()
+	h.Write(keyIDDomain)
+	h.Write(bls.NewG1().ToCompressed(pk.pg1))
+	if pk.GetScheme() == MinSig {
+		h.Write(bls.NewG2().ToCompressed(pk.pg2))
+	}
+	id := KeyID{
+		Epoch: epoch,
+	}
+	copy(id.Fingerprint[:], h.Sum(nil))
+	return id
+}
+
+// 8-byte epoch, then 32-byte fingerprint
+func (id KeyID) ToBytes() []byte {
+	out := binary.BigEndian.AppendUint64(make([]byte, 0, keyIDSize), id.Epoch)
+	return append(out, id.Fingerprint[:]...)
+}
+
+func BytesToKeyID(b []byte) (KeyID, error) {
+	if len(b) != keyIDSize {
+		return KeyID{}, NewEncodingLengthError()
+	}
+	id := KeyID{
+		Epoch: binary.BigEndian.Uint64(b[:8]),
+	}
+	copy(id.Fingerprint[:], b[8:])
+	return id, nil
+}
+
+type keyringEntry struct {
+	id KeyID
+	sk *PrivateKey
+	pk *PublicKey
+}
+
+// Keyring holds the shares of a node for concurrent epochs, such as the current and the next one during
+// a handover, and routes ciphertexts and messages to the share of their key. Retired epochs are rejected
+type Keyring struct {
+	mu      sync.RWMutex
+	keys    map[uint64]*keyringEntry
+	retired map[uint64]bool
+}
+
+func NewKeyring() *Keyring {
+	return &Keyring{
+		keys:    make(map[uint64]*keyringEntry),
+		retired: make(map[uint64]bool),
+	}
+}
+
+// Add the share of an epoch with the global public key of the epoch
+func (kr *Keyring) Add(epoch uint64, sk *PrivateKey, pk *PublicKey) (KeyID, error) {
+	kr.mu.Lock()
+	defer kr.mu.Unlock()
+	if kr.retired[epoch] {
+		return KeyID{}, NewKeyringRetiredError()
+	}
+	id := pk.KeyID(epoch)
+	if entry, ok := kr.keys[epoch]; ok && entry.id != id {
+		return KeyID{}, NewKeyringError("epoch has another key")
+	}
+	kr.keys[epoch] = &keyringEntry{
+		id: id,
+		sk: sk,
+		pk: pk,
+	}
+	return id, nil
+}
+
+// Retire drops the share of an epoch, later requests and additions of the epoch fail
+func (kr *Keyring) Retire(epoch uint64) {
+	kr.mu.Lock()
+	defer kr.mu.Unlock()
+	delete(kr.keys, epoch)
+	kr.retired[epoch] = true
+}
+
+// Epochs returns active epochs in ascending order
+func (kr *Keyring) Epochs() []uint64 {
+	kr.mu.RLock()
+	defer kr.mu.RUnlock()
+	epochs := make([]uint64, 0, len(kr.keys))
+	for epoch := range kr.keys {
+		epochs = append(epochs, epoch)
+	}
+	sort.Slice(epochs, func(i, j int) bool {
+		return epochs[i] < epochs[j]
+	})
+	return epochs
+}
+
+func (kr *Keyring) entry(id *KeyID) (*keyringEntry, error) {
+	if id == nil {
+		return nil, NewKeyringUnknownError()
+	}
+	kr.mu.RLock()
+	defer kr.mu.RUnlock()
+	if kr.retired[id.Epoch] {
+		return nil, NewKeyringRetiredError()
+	}
+	entry, ok := kr.keys[id.Epoch]
+	if !ok || entry.id != *id {
+		return nil, NewKeyringUnknownError()
+	}
+	return entry, nil
+}
+
+func (kr *Keyring) GetPrivateKey(id KeyID) (*PrivateKey, error) {
+	entry, err := kr.entry(&id)
+	if err != nil {
+		return nil, err
+	}
+	return entry.sk, nil
+}
+
+func (kr *Keyring) GetPublicKey(id KeyID) (*PublicKey, error) {
+	entry, err := kr.entry(&id)
+	if err != nil {
+		return nil, err
+	}
+	return entry.pk, nil
+}
+
+// DecryptShare decrypts with the share of the key id of the ciphertext
+func (kr *Keyring) DecryptShare(ct *CipherText) (*DecryptionShare, error) {
+	entry, err := kr.entry(ct.keyID)
+	if err != nil {
+		return nil, err
+	}
+	return entry.sk.DecryptShare(ct), nil
+}
+
+func (kr *Keyring) SignShare(id KeyID, msg []byte) (*SignatureShare, error) {
+	entry, err := kr.entry(&id)
+	if err != nil {
+		return nil, err
+	}
+	return entry.sk.SignShare(msg), nil
+}
+
+// VerifySig verifies with the global public key of the key id of the signature
+func (kr *Keyring) VerifySig(msg []byte, sig *Signature) bool {
+	if sig == nil {
+		return false
+	}
+	entry, err := kr.entry(sig.keyID)
+	if err != nil {
+		return false
+	}
+	return entry.pk.VerifySig(msg, sig)
+}
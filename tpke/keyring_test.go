@@ -0,0 +1,106 @@
+package tpke
+
+import (
+	"errors"
+	"testing"
+
+	bls "github.com/txhsl/tpke/internal/bls"
+)
+
+func TestKeyring(t *testing.T) {
+	size := 7
+	threshold := 4
+	_, sks1, pk1, scaler := dkg(size, threshold)
+	_, sks2, pk2, _ := dkg(size, threshold)
+
+	// Every participant holds the current and the next key
+	keyrings := make(map[int]*Keyring, size)
+	for i := 1; i <= size; i++ {
+		keyrings[i] = NewKeyring()
+		if _, err := keyrings[i].Add(1, sks1[i], pk1); err != nil {
+			t.Fatalf(err.Error())
+		}
+		if _, err := keyrings[i].Add(2, sks2[i], pk2); err != nil {
+			t.Fatalf(err.Error())
+		}
+	}
+	id1 := pk1.KeyID(1)
+	id2 := pk2.KeyID(2)
+	if id1 == id2 || id1 != pk1.KeyID(1) || id1 == pk1.KeyID(2) {
+		t.Fatalf("unexpected key ids.")
+	}
+	if _, err := keyrings[1].Add(1, sks2[1], pk2); err == nil {
+		t.Fatalf("key of an occupied epoch accepted.")
+	}
+
+	// Ciphertexts are routed by their key ids
+	msg := bls.NewG1().One()
+	ct := pk2.Encrypt(msg)
+	if _, err := keyrings[1].DecryptShare(ct); !errors.Is(err, ErrUnknownKey) {
+		t.Fatalf("ciphertext without key id accepted.")
+	}
+	ct.SetKeyID(id2)
+	ct, err := BytesToCipherText(ct.ToBytes())
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if ct.GetKeyID() == nil || *ct.GetKeyID() != id2 {
+		t.Fatalf("key id mismatch.")
+	}
+	shares := make(map[int]([]*DecryptionShare), size)
+	for i, kr := range keyrings {
+		share, err := kr.DecryptShare(ct)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		shares[i] = []*DecryptionShare{share}
+	}
+	results, err := Decrypt([]*CipherText{ct}, shares, pk2, threshold, scaler)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if !bls.NewG1().Equal(results[0], msg) {
+		t.Fatalf("decryption mismatch.")
+	}
+
+	// So are messages to sign
+	data := []byte("pizza pizza pizza pizza pizza pizza pizza pizza pizza pizza pizza pizza pizza")
+	inputs := make(map[int]*SignatureShare, size)
+	for i, kr := range keyrings {
+		inputs[i], err = kr.SignShare(id1, data)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+	}
+	sig, err := AggregateAndVerifySig(pk1, data, threshold, inputs, scaler)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	sig.SetKeyID(id1)
+	sig, err = BytesToSig(sig.ToBytes())
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if !keyrings[1].VerifySig(data, sig) {
+		t.Fatalf("signature rejected.")
+	}
+	sig.SetKeyID(pk1.KeyID(2))
+	if keyrings[1].VerifySig(data, sig) {
+		t.Fatalf("signature of another key accepted.")
+	}
+
+	// Retired epochs are rejected
+	keyrings[1].Retire(1)
+	if epochs := keyrings[1].Epochs(); len(epochs) != 1 || epochs[0] != 2 {
+		t.Fatalf("unexpected epochs %v.", epochs)
+	}
+	if _, err := keyrings[1].SignShare(id1, data); !errors.Is(err, ErrRetiredKey) {
+		t.Fatalf("retired key used.")
+	}
+	if _, err := keyrings[1].Add(1, sks1[1], pk1); !errors.Is(err, ErrRetiredKey) {
+		t.Fatalf("retired epoch added.")
+	}
+	if _, err := keyrings[1].GetPrivateKey(id2); err != nil {
+		t.Fatalf(err.Error())
+	}
+}
@@ -0,0 +1,231 @@
+package tpke
+
+import (
+	ecies "github.com/ethereum/go-ethereum/crypto/ecies"
+	bls "github.com/txhsl/tpke/internal/bls"
+)
+
+// SetTwoPhase enables the two-phase dkg of Gennaro et al. Dealers first share with Pedersen commitments
+// C_k=a_k*G1+b_k*H, which hide A0, and publish Feldman commitments only after QUAL is fixed, so that
+// a rushing dealer can not bias the global public key. Call it before Prepare.
+// A refresh keeps the global public key, so it has nothing to bias and stays in one phase
+func (dkg *DKG) SetTwoPhase() {
+	dkg.twoPhase = !dkg.refresh
+}
+
+func (dkg *DKG) IsTwoPhase() bool {
+	return dkg.twoPhase
+}
+
+// C_k=a_k*G1+b_k*H with the second generator H
+func pedersenCommitment(secret *Secret, blinding *Secret) *Commitment {
+	g1 := bls.NewG1()
+	h := secondGenerator()
+	coeff := make([]*bls.PointG1, len(secret.poly.coeff))
+	for k := range coeff {
+		coeff[k] = g1.MulScalar(g1.New(), &bls.G1One, secret.poly.coeff[k])
+		g1.Add(coeff[k], coeff[k], g1.MulScalar(g1.New(), h, blinding.poly.coeff[k]))
+	}
+	return &Commitment{
+		coeff: coeff,
+	}
+}
+
+// Verify f(i)*G1+f'(i)*H==sum(C_k*i^k), index starts from 1
+func verifyPedersenShare(c *Commitment, index int, fi *bls.Fr, blinding *bls.Fr) bool {
+	if c == nil || fi == nil || blinding == nil || index <= 0 {
+		return false
+	}
+	g1 := bls.NewG1()
+	left := g1.MulScalar(g1.New(), &bls.G1One, fi)
+	g1.Add(left, left, g1.MulScalar(g1.New(), secondGenerator(), blinding))
+	return g1.Equal(left, c.evaluate(*frFromInt(index)))
+}
+
+// First phase, every dealer sends f(j) and f'(j) to participant j, and publishes only the Pedersen commitment
+func (dkg *DKG) preparePedersen() error {
+	for i := 0; i < dkg.size; i++ {
+		dealer := dkg.participants[i]
+		if err := dealer.GenerateSecret(dkg.threshold); err != nil {
+			return NewDKGError(err.Error()).withCause(err)
+		}
+		blinding, err := RandomSecretWithReader(dkg.threshold, dealer.random)
+		if err != nil {
+			return NewDKGError(err.Error()).withCause(err)
+		}
+		dealer.blinding = blinding
+		dealer.pedersen = pedersenCommitment(dealer.secret, blinding)
+		dealer.pvss = nil
+		for j := 0; j < dkg.size; j++ {
+			x := frFromInt(j + 1)
+			plaintext := append(dealer.secret.Evaluate(*x).ToBytes(), blinding.Evaluate(*x).ToBytes()...)
+			msg, err := ecies.Encrypt(dkg.random, dkg.participants[j].ethPubKey, plaintext, nil, nil)
+			if err != nil {
+				return NewDKGError(err.Error()).withCause(err)
+			}
+			dkg.messageBox[j][i] = msg
+		}
+	}
+	return nil
+}
+
+// Decrypt and verify the share and its blinding from a dealer, returns nil if invalid
+func (p *Participant) receivePedersenSecret(index int, c *Commitment, encrypted []byte) *bls.Fr {
+	ss, err := p.ethPrvKey.Decrypt(encrypted, nil, nil)
+	if err != nil || len(ss) != 64 {
+		return nil
+	}
+	fi := bls.NewFr().FromBytes(ss[:32])
+	if !verifyPedersenShare(c, index, fi, bls.NewFr().FromBytes(ss[32:])) {
+		return nil
+	}
+	return fi
+}
+
+func (dkg *DKG) complainPedersen() []*Complaint {
+	complaints := make([]*Complaint, 0)
+	for i := 0; i < dkg.size; i++ {
+		dkg.participants[i].receivedSecrets = make([]*bls.Fr, dkg.size)
+		for j := 0; j < dkg.size; j++ {
+			fi := dkg.participants[i].receivePedersenSecret(i+1, dkg.participants[j].pedersen, dkg.messageBox[i][j])
+			if fi == nil {
+				complaints = append(complaints, &Complaint{
+					Dealer:   j + 1,
+					Receiver: i + 1,
+					Evidence: dkg.messageBox[i][j],
+				})
+				continue
+			}
+			dkg.participants[i].receivedSecrets[j] = fi
+		}
+	}
+	return complaints
+}
+
+// QUAL of the first phase, a dealer is disqualified if its commitment is malformed, or if any complaint
+// against it is not answered with a share and a blinding matching the commitment
+func (dkg *DKG) finalizePedersen(accepted []*Complaint, responses []*ComplaintResponse) error {
+	qual := make([]int, 0, dkg.size)
+	for i := 0; i < dkg.size; i++ {
+		c := dkg.participants[i].pedersen
+		if c == nil || len(c.coeff) != dkg.threshold {
+			continue
+		}
+		answered := true
+		for _, complaint := range accepted {
+			if complaint.Dealer != i+1 {
+				continue
+			}
+			r := findResponse(responses, i+1, complaint.Receiver)
+			if r == nil || !verifyPedersenShare(c, complaint.Receiver, r.Secret, r.Blinding) {
+				answered = false
+				break
+			}
+		}
+		if answered {
+			qual = append(qual, i+1)
+		}
+	}
+	if len(qual) == 0 {
+		return NewDKGQualError().withParticipants(disqualified(dkg.size, qual)...)
+	}
+	for _, dealer := range qual {
+		for _, c := range accepted {
+			if c.Dealer == dealer {
+				r := findResponse(responses, dealer, c.Receiver)
+				dkg.participants[c.Receiver-1].receivedSecrets[dealer-1] = bls.NewFr().Set(r.Secret)
+			}
+		}
+	}
+	dkg.qual = qual
+	return nil
+}
+
+// Reveal is the second phase of the two-phase dkg, run after Finalize. Qualified dealers publish the pvss
+// of the secrets fixed in the first phase. If the pvss does not match the shares a dealer sent, its
+// polynomial is reconstructed from the shares of receivers, so no dealer can change A0 once QUAL is known
+func (dkg *DKG) Reveal() error {
+	if !dkg.twoPhase || dkg.qual == nil {
+		return NewDKGRoundError()
+	}
+	for _, j := range dkg.qual {
+		dealer := dkg.participants[j-1]
+		if dealer.secret != nil {
+			sharedSecrets, err := dealer.GenerateShares(dkg.size)
+			if err != nil {
+				return NewDKGError(err.Error()).withCause(err)
+			}
+			if dkg.scheme == MinSig {
+				dealer.pvss.setG2Shares(dealer.secret.poly.coeff[0], sharedSecrets)
+			}
+			if err := dealer.ProveSecret(dkg.GetSession(), j); err != nil {
+				return NewDKGError(err.Error()).withCause(err)
+			}
+		}
+		if dkg.verifyReveal(j) {
+			continue
+		}
+		if err := dkg.reconstructDealer(j); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// The revealed pvss must be valid with a proof of knowledge, and its public shares must match the shares of all receivers
+func (dkg *DKG) verifyReveal(dealer int) bool {
+	pvss := dkg.participants[dealer-1].pvss
+	if pvss == nil || len(pvss.bigf) != dkg.size || len(pvss.public.commitment.coeff) != dkg.threshold || !pvss.Verify() {
+		return false
+	}
+	if dkg.scheme == MinSig && !pvss.HasG2Shares() {
+		return false
+	}
+	if !pvss.VerifySecretProof(dkg.GetSession(), dealer) {
+		return false
+	}
+	g1 := bls.NewG1()
+	for i := 0; i < dkg.size; i++ {
+		fi := dkg.participants[i].receivedSecrets[dealer-1]
+		if fi == nil || !g1.Equal(pvss.bigf[i], g1.MulScalar(g1.New(), &bls.G1One, fi)) {
+			return false
+		}
+	}
+	return true
+}
+
+// Interpolate the polynomial of a dealer from threshold shares checked in the first phase, and rebuild its pvss.
+// In a network, receivers broadcast their shares of the dealer, so its secret becomes public
+func (dkg *DKG) reconstructDealer(dealer int) error {
+	indices := make([]int, 0, dkg.threshold)
+	values := make([]*bls.Fr, 0, dkg.threshold)
+	for i := 0; i < dkg.size && len(indices) < dkg.threshold; i++ {
+		if fi := dkg.participants[i].receivedSecrets[dealer-1]; fi != nil {
+			indices = append(indices, i+1)
+			values = append(values, fi)
+		}
+	}
+	if len(indices) < dkg.threshold {
+		return NewDKGSecretError().withParticipants(dealer)
+	}
+	poly, err := interpolatePoly(indices, values)
+	if err != nil {
+		return err
+	}
+	r, err := randomFr(dkg.random)
+	if err != nil {
+		return NewDKGError(err.Error()).withCause(err)
+	}
+	pvss, shares := GenerateSharedSecrets(r, dkg.size, &Secret{
+		poly: *poly,
+	})
+	if dkg.scheme == MinSig {
+		pvss.setG2Shares(poly.coeff[0], shares)
+	}
+	// The secret is public now, so anyone can prove knowledge of it and transcripts stay uniform
+	if err := pvss.proveSecret(dkg.random, dkg.GetSession(), dealer, poly.coeff[0]); err != nil {
+		return NewDKGError(err.Error()).withCause(err)
+	}
+	dkg.participants[dealer-1].pvss = pvss
+	return nil
+}
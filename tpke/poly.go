@@ -1,28 +1,27 @@
 package tpke
 
 import (
-	"math/rand"
-	"time"
+	"io"
 
-	bls "github.com/kilic/bls12-381"
+	bls "github.com/txhsl/tpke/internal/bls"
 )
 
 type Poly struct {
 	coeff []*bls.Fr
 }
 
-func randomPoly(degree int) *Poly {
+func randomPoly(degree int, random io.Reader) (*Poly, error) {
 	coeff := make([]*bls.Fr, degree)
-	s1 := rand.NewSource(time.Now().UnixNano())
-	r1 := rand.New(s1)
-
 	for i := range coeff {
-		fr, _ := bls.NewFr().Rand(r1)
+		fr, err := randomFr(random)
+		if err != nil {
+			return nil, err
+		}
 		coeff[i] = fr
 	}
 	return &Poly{
 		coeff: coeff,
-	}
+	}, nil
 }
 
 func (p *Poly) evaluate(x bls.Fr) *bls.Fr {
@@ -123,3 +122,51 @@ func (c *Commitment) AddAssign(op *Commitment) {
 		g1.Add(c.coeff[i], c.coeff[i], op.coeff[i])
 	}
 }
+
+// Interpolate the polynomial through (index, value) pairs, with len(indices) coefficients
+func interpolatePoly(indices []int, values []*bls.Fr) (*Poly, error) {
+	n := len(indices)
+	coeff := make([]*bls.Fr, n)
+	for k := range coeff {
+		coeff[k] = bls.NewFr().Zero()
+	}
+	minor := bls.NewFr()
+	for i := range indices {
+		if indices[i] <= 0 {
+			return nil, NewInterpolationIndexError().withParticipants(indices[i])
+		}
+		xi := frFromInt(indices[i])
+		// Basis l_i(x)=prod((x-x_j)/(x_i-x_j)), j!=i
+		basis := []*bls.Fr{bls.NewFr().One()}
+		denominator := bls.NewFr().One()
+		for j := range indices {
+			if i == j {
+				continue
+			}
+			xj := frFromInt(indices[j])
+			next := make([]*bls.Fr, len(basis)+1)
+			next[0] = bls.NewFr().Zero()
+			for k, c := range basis {
+				next[k+1] = bls.NewFr().Set(c)
+				minor.Mul(c, xj)
+				next[k].Sub(next[k], minor)
+			}
+			basis = next
+			minor.Sub(xi, xj)
+			if minor.IsZero() {
+				return nil, NewInterpolationDuplicateError().withParticipants(indices[i])
+			}
+			denominator.Mul(denominator, minor)
+		}
+		denominator.Inverse(denominator)
+		scale := bls.NewFr()
+		scale.Mul(values[i], denominator)
+		for k, c := range basis {
+			minor.Mul(c, scale)
+			coeff[k].Add(coeff[k], minor)
+		}
+	}
+	return &Poly{
+		coeff: coeff,
+	}, nil
+}
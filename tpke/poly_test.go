@@ -1,12 +1,18 @@
 package tpke
 
 import (
+	"encoding/hex"
 	"math/big"
 	"testing"
 
-	bls "github.com/kilic/bls12-381"
+	bls "github.com/txhsl/tpke/internal/bls"
 )
 
+func frFromHex(s string) *bls.Fr {
+	b, _ := hex.DecodeString(s)
+	return bls.NewFr().FromBytes(b)
+}
+
 func TestPoly_AddAssign(t *testing.T) {
 	fr1 := bls.NewFr().FromBytes(big.NewInt(1).Bytes())
 	poly := &Poly{
@@ -31,10 +37,10 @@ func TestPoly_AddAssign(t *testing.T) {
 }
 
 func TestPoly_evaluate(t *testing.T) {
-	expectedFr := &bls.Fr{5181237716180834938, 12933092709012868809, 7449062242929247980, 4519714088997883368}
-	fr1 := &bls.Fr{18254824737299055921, 12301176899088639156, 11044415995378916883, 357667238319585097}
-	fr2 := &bls.Fr{12824654784770937420, 5378575280977611710, 2705578970549845177, 4801150429553808887}
-	fr3 := &bls.Fr{4520685442247980328, 10536932062350377723, 4028339353248801528, 1646571793378913296}
+	expectedFr := frFromHex("3eb94004790c0de8676062a4a39286ecb37b934bdfe80ec947e7744061d09a7a")
+	fr1 := frFromHex("04f6b0754fd577499945a5d4fc33de13aab68f371d6b64b4fd562a615fb94531")
+	fr2 := frFromHex("42a11ce0cac4d5f7258c25bd2b63fcb94aa489b89c5e0bbeb1fa53942d49124c")
+	fr3 := frFromHex("16d9cc5279872c1037e7894c791f22f8923ab3e236d182fb3ebcb37546787528")
 	poly := &Poly{
 		[]*bls.Fr{
 			fr1,
@@ -51,9 +57,9 @@ func TestPoly_evaluate(t *testing.T) {
 }
 
 func TestPoly_commitment(t *testing.T) {
-	fr1 := &bls.Fr{3430707088094777087, 2455690239785479458, 5507155159914335843, 7341630481516121204}
-	fr2 := &bls.Fr{360679164676216945, 589008160366285188, 18428004055273428688, 2723678784642027464}
-	fr3 := &bls.Fr{5511946094596868462, 16040801034001542498, 9453513069589497919, 2081802114026746926}
+	fr1 := frFromHex("65e2b6067af2cc744c6d587095a74e6322145d4324cead222f9c51eb824dceff")
+	fr2 := frFromHex("25cc736c88db0fc8ffbd6c0d7207d6d0082c93cd18705184050163ca10691871")
+	fr3 := frFromHex("1ce40bfe2b38b42e8331a00cc865083fde9c5f8d78616d624c7e5dc4fd8c496e")
 
 	poly := &Poly{
 		coeff: []*bls.Fr{
@@ -68,3 +74,24 @@ func TestPoly_commitment(t *testing.T) {
 	t.Logf("%v", com)
 	t.Logf("%v", result)
 }
+
+func TestInterpolatePoly(t *testing.T) {
+	poly, _ := randomPoly(5, nil)
+	indices := []int{2, 3, 5, 8, 13}
+	values := make([]*bls.Fr, len(indices))
+	for i, index := range indices {
+		values[i] = poly.evaluate(*frFromInt(index))
+	}
+	result, err := interpolatePoly(indices, values)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	for k := range poly.coeff {
+		if !poly.coeff[k].Equal(result.coeff[k]) {
+			t.Fatalf("interpolation failed.")
+		}
+	}
+	if _, err := interpolatePoly([]int{1, 1}, values[:2]); err == nil {
+		t.Fatalf("duplicate index accepted.")
+	}
+}
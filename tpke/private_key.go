@@ -1,11 +1,16 @@
 package tpke
 
 import (
-	bls "github.com/kilic/bls12-381"
+	"encoding/json"
+
+	bls "github.com/txhsl/tpke/internal/bls"
 )
 
 type PrivateKey struct {
-	fr *bls.Fr
+	fr     *bls.Fr
+	scheme Scheme
+	suite  Ciphersuite
+	augKey []byte // Global public key prefixed by the aug suite
 }
 
 func NewPrivateKey(secretShares []*bls.Fr) *PrivateKey {
@@ -22,9 +27,14 @@ func NewPrivateKey(secretShares []*bls.Fr) *PrivateKey {
 func (sk *PrivateKey) GetPublicKey() *PublicKey {
 	g1 := bls.NewG1()
 	pg1 := g1.New()
-	return &PublicKey{
+	pk := &PublicKey{
 		pg1: g1.MulScalar(pg1, &bls.G1One, sk.fr),
 	}
+	if sk.scheme == MinSig {
+		g2 := bls.NewG2()
+		pk.pg2 = g2.MulScalar(g2.New(), &bls.G2One, sk.fr)
+	}
+	return pk
 }
 
 func (sk *PrivateKey) DecryptShare(ct *CipherText) *DecryptionShare {
@@ -37,13 +47,116 @@ func (sk *PrivateKey) DecryptShare(ct *CipherText) *DecryptionShare {
 	}
 }
 
+func (sk *PrivateKey) GetScheme() Scheme {
+	return sk.scheme
+}
+
 func (sk *PrivateKey) SignShare(msg []byte) *SignatureShare {
+	input, domain := sk.signInput(msg)
+	if sk.scheme == MinSig {
+		// S=H(msg)*sk in G1
+		g1 := bls.NewG1()
+		g1Hash, _ := g1.HashToCurve(input, domain)
+		return &SignatureShare{
+			pg1: g1.MulScalar(g1.New(), g1Hash, sk.fr),
+		}
+	}
 	// S=H(msg)*sk
 	g2 := bls.NewG2()
-	g2Hash, _ := g2.HashToCurve(msg, Domain)
+	g2Hash, _ := g2.HashToCurve(input, domain)
 	sig := g2.New()
 	g2.MulScalar(sig, g2Hash, sk.fr)
 	return &SignatureShare{
 		pg2: sig,
 	}
 }
+
+// Encode the secret, followed by the scheme only if it is not the default, then the ciphersuite and
+// the global key of the aug suite only if the suite is not the default
+func (sk *PrivateKey) ToBytes() []byte {
+	out := append(encodingHeader(encodingPrivateKey), sk.fr.ToBytes()...)
+	if sk.scheme != MinPubKey || sk.suite != SuitePOP {
+		out = append(out, byte(sk.scheme))
+	}
+	if sk.suite != SuitePOP {
+		out = append(append(out, byte(sk.suite)), sk.augKey...)
+	}
+	return out
+}
+
+func BytesToPrivateKey(b []byte) (*PrivateKey, error) {
+	b, err := readEncodingHeader(b, encodingPrivateKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) < 32 {
+		return nil, NewEncodingLengthError()
+	}
+	sk := &PrivateKey{}
+	if len(b) > 32 {
+		sk.scheme = Scheme(b[32])
+		if !sk.scheme.valid() || (sk.scheme == MinPubKey && len(b) == 33) {
+			return nil, NewEncodingError("invalid scheme")
+		}
+	}
+	if len(b) > 33 {
+		sk.suite = Ciphersuite(b[33])
+		if sk.suite == SuitePOP || !sk.suite.valid() {
+			return nil, NewEncodingError("invalid ciphersuite")
+		}
+		if sk.augKey, err = decodeAugKey(sk.scheme, sk.suite, b[34:]); err != nil {
+			return nil, err
+		}
+	}
+	if sk.fr, err = decodeFr(b[:32]); err != nil {
+		return nil, err
+	}
+	return sk, nil
+}
+
+type privateKeyJSON struct {
+	Version byte        `json:"version"`
+	Secret  hexBytes    `json:"secret"`
+	Scheme  Scheme      `json:"scheme,omitempty"`
+	Suite   Ciphersuite `json:"suite,omitempty"`
+	AugKey  hexBytes    `json:"aug_key,omitempty"`
+}
+
+func (sk *PrivateKey) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&privateKeyJSON{
+		Version: EncodingVersion,
+		Secret:  sk.fr.ToBytes(),
+		Scheme:  sk.scheme,
+		Suite:   sk.suite,
+		AugKey:  sk.augKey,
+	})
+}
+
+func (sk *PrivateKey) UnmarshalJSON(b []byte) error {
+	var v privateKeyJSON
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	if err := checkEncodingVersion(v.Version); err != nil {
+		return err
+	}
+	if !v.Scheme.valid() {
+		return NewEncodingError("invalid scheme")
+	}
+	fr, err := decodeFr(v.Secret)
+	if err != nil {
+		return err
+	}
+	if !v.Suite.valid() {
+		return NewEncodingError("invalid ciphersuite")
+	}
+	augKey, err := decodeAugKey(v.Scheme, v.Suite, v.AugKey)
+	if err != nil {
+		return err
+	}
+	sk.fr = fr
+	sk.scheme = v.Scheme
+	sk.suite = v.Suite
+	sk.augKey = augKey
+	return nil
+}
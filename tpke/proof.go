@@ -0,0 +1,171 @@
+package tpke
+
+import (
+	"crypto/sha512"
+	"io"
+	"sync"
+
+	bls "github.com/txhsl/tpke/internal/bls"
+)
+
+var generatorDomain = []byte("TPKE_BLS12381G1_XMD:SHA-256_SSWU_RO_GENERATOR_")
+
+var (
+	generatorOnce sync.Once
+	generatorBar  *bls.PointG1
+)
+
+// Second generator of G1 with unknown discrete log to G1One
+func secondGenerator() *bls.PointG1 {
+	generatorOnce.Do(func() {
+		generatorBar, _ = bls.NewG1().HashToCurve([]byte("tpke second generator"), generatorDomain)
+	})
+	return bls.NewG1().New().Set(generatorBar)
+}
+
+// Hash inputs to Fr, 64 bytes are reduced to keep the bias negligible
+func hashToFr(domain []byte, inputs ...[]byte) *bls.Fr {
+	h := sha512.New()
+	h.Write(domain)
+	for _, in := range inputs {
+		h.Write(appendWithLength(nil, in))
+	}
+	return bls.NewFr().FromBytes(h.Sum(nil))
+}
+
+// DLEQProof proves log_g(X)==log_h(Y) without revealing x, by e=H(ctx,g,h,X,Y,A,B), f=s+e*x
+type DLEQProof struct {
+	e *bls.Fr
+	f *bls.Fr
+}
+
+func newDLEQProof(random io.Reader, domain []byte, context []byte, g, h *bls.PointG1, x *bls.Fr) (*DLEQProof, error) {
+	g1 := bls.NewG1()
+	s, err := randomFr(random)
+	if err != nil {
+		return nil, err
+	}
+	bigX := g1.MulScalar(g1.New(), g, x)
+	bigY := g1.MulScalar(g1.New(), h, x)
+	a := g1.MulScalar(g1.New(), g, s)
+	b := g1.MulScalar(g1.New(), h, s)
+	e := hashDLEQ(domain, context, g, h, bigX, bigY, a, b)
+	// f=s+e*x
+	f := bls.NewFr()
+	f.Mul(e, x)
+	f.Add(f, s)
+	return &DLEQProof{
+		e: e,
+		f: f,
+	}, nil
+}
+
+func (p *DLEQProof) verify(domain []byte, context []byte, g, bigX, h, bigY *bls.PointG1) bool {
+	if p == nil || p.e == nil || p.f == nil {
+		return false
+	}
+	g1 := bls.NewG1()
+	// A=f*g-e*X, B=f*h-e*Y
+	minor := g1.New()
+	a := g1.MulScalar(g1.New(), g, p.f)
+	g1.Sub(a, a, g1.MulScalar(minor, bigX, p.e))
+	b := g1.MulScalar(g1.New(), h, p.f)
+	g1.Sub(b, b, g1.MulScalar(minor, bigY, p.e))
+	return hashDLEQ(domain, context, g, h, bigX, bigY, a, b).Equal(p.e)
+}
+
+func hashDLEQ(domain []byte, context []byte, points ...*bls.PointG1) *bls.Fr {
+	g1 := bls.NewG1()
+	inputs := make([][]byte, 0, len(points)+1)
+	inputs = append(inputs, context)
+	for _, p := range points {
+		inputs = append(inputs, g1.ToCompressed(p))
+	}
+	return hashToFr(domain, inputs...)
+}
+
+func (p *DLEQProof) ToBytes() []byte {
+	out := make([]byte, 0, 64)
+	out = append(out, p.e.ToBytes()...)
+	return append(out, p.f.ToBytes()...)
+}
+
+// Scalars must be canonical, so that a proof has a single encoding
+func BytesToDLEQProof(b []byte) (*DLEQProof, error) {
+	if len(b) != 64 {
+		return nil, NewTPKEProofError()
+	}
+	e, err := decodeFr(b[:32])
+	if err != nil {
+		return nil, err
+	}
+	f, err := decodeFr(b[32:])
+	if err != nil {
+		return nil, err
+	}
+	return &DLEQProof{
+		e: e,
+		f: f,
+	}, nil
+}
+
+// SchnorrProof proves knowledge of x in X=x*g, by e=H(ctx,g,X,A), f=s+e*x
+type SchnorrProof struct {
+	e *bls.Fr
+	f *bls.Fr
+}
+
+func newSchnorrProof(random io.Reader, domain []byte, context []byte, g *bls.PointG1, x *bls.Fr) (*SchnorrProof, error) {
+	g1 := bls.NewG1()
+	s, err := randomFr(random)
+	if err != nil {
+		return nil, err
+	}
+	bigX := g1.MulScalar(g1.New(), g, x)
+	a := g1.MulScalar(g1.New(), g, s)
+	e := hashDLEQ(domain, context, g, bigX, a)
+	// f=s+e*x
+	f := bls.NewFr()
+	f.Mul(e, x)
+	f.Add(f, s)
+	return &SchnorrProof{
+		e: e,
+		f: f,
+	}, nil
+}
+
+func (p *SchnorrProof) verify(domain []byte, context []byte, g, bigX *bls.PointG1) bool {
+	if p == nil || p.e == nil || p.f == nil {
+		return false
+	}
+	g1 := bls.NewG1()
+	// A=f*g-e*X
+	a := g1.MulScalar(g1.New(), g, p.f)
+	g1.Sub(a, a, g1.MulScalar(g1.New(), bigX, p.e))
+	return hashDLEQ(domain, context, g, bigX, a).Equal(p.e)
+}
+
+func (p *SchnorrProof) ToBytes() []byte {
+	out := make([]byte, 0, 64)
+	out = append(out, p.e.ToBytes()...)
+	return append(out, p.f.ToBytes()...)
+}
+
+// Scalars must be canonical
+func BytesToSchnorrProof(b []byte) (*SchnorrProof, error) {
+	if len(b) != 64 {
+		return nil, NewTPKEProofError()
+	}
+	e, err := decodeFr(b[:32])
+	if err != nil {
+		return nil, err
+	}
+	f, err := decodeFr(b[32:])
+	if err != nil {
+		return nil, err
+	}
+	return &SchnorrProof{
+		e: e,
+		f: f,
+	}, nil
+}
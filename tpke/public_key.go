@@ -1,34 +1,126 @@
 package tpke
 
 import (
-	"math/big"
-	"math/rand"
-	"time"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"sort"
 
-	bls "github.com/kilic/bls12-381"
+	bls "github.com/txhsl/tpke/internal/bls"
 )
 
 type PublicKey struct {
-	pg1 *bls.PointG1
+	pg1   *bls.PointG1
+	vks   map[int]*bls.PointG1 // Verification keys of participants, start from 1
+	pg2   *bls.PointG2         // Key in G2, only for the min-sig scheme
+	vks2  map[int]*bls.PointG2 // Verification keys in G2, only for the min-sig scheme
+	suite Ciphersuite
 }
 
-func NewGlobalPublicKey(scs []*SecretCommitment, scaler int) *PublicKey {
+func NewPublicKey(scs []*SecretCommitment) *PublicKey {
 	g1 := bls.NewG1()
 	pg1 := g1.New().Set(scs[0].commitment.coeff[0])
 	// Add up A0
 	for i := 1; i < len(scs); i++ {
 		g1.Add(pg1, pg1, scs[i].commitment.coeff[0])
 	}
-	g1.MulScalar(pg1, pg1, bls.NewFr().FromBytes(big.NewInt(int64(scaler)).Bytes()))
 	return &PublicKey{
 		pg1: pg1,
 	}
 }
 
+// Scaled public key, only for callers that still combine shares with a scaler
+func NewGlobalPublicKey(scs []*SecretCommitment, scaler int) *PublicKey {
+	pk := NewPublicKey(scs)
+	if scaler > 1 {
+		g1 := bls.NewG1()
+		g1.MulScalar(pk.pg1, pk.pg1, frFromInt(scaler))
+	}
+	return pk
+}
+
+// Verification key of participant i is VK_i=sum(F_j(i)), where F_j comes from the pvss of dealer j.
+// If all pvss have public shares in G2, the verification keys are also in G2 for the min-sig scheme
+func NewVerificationKeys(pvsss []*PVSS) map[int]*PublicKey {
+	g1 := bls.NewG1()
+	g2 := bls.NewG2()
+	minSig := true
+	for _, pvss := range pvsss {
+		minSig = minSig && pvss.HasG2Shares()
+	}
+	vks := make(map[int]*PublicKey)
+	for i := 0; i < len(pvsss[0].bigf); i++ {
+		pg1 := g1.New().Set(pvsss[0].bigf[i])
+		for j := 1; j < len(pvsss); j++ {
+			g1.Add(pg1, pg1, pvsss[j].bigf[i])
+		}
+		vks[i+1] = &PublicKey{
+			pg1: pg1,
+		}
+		if minSig {
+			pg2 := g2.New().Set(pvsss[0].bigfG2[i])
+			for j := 1; j < len(pvsss); j++ {
+				g2.Add(pg2, pg2, pvsss[j].bigfG2[i])
+			}
+			vks[i+1].pg2 = pg2
+		}
+	}
+	return vks
+}
+
+// Verification keys in G2 are kept only if all of them are given
+func (pk *PublicKey) SetVerificationKeys(vks map[int]*PublicKey) {
+	pk.vks = make(map[int]*bls.PointG1)
+	pk.vks2 = make(map[int]*bls.PointG2)
+	for index, vk := range vks {
+		pk.vks[index] = bls.NewG1().New().Set(vk.pg1)
+		if vk.pg2 != nil {
+			pk.vks2[index] = bls.NewG2().New().Set(vk.pg2)
+		}
+	}
+	if len(pk.vks2) != len(pk.vks) {
+		pk.vks2 = nil
+	}
+}
+
+func (pk *PublicKey) HasVerificationKeys() bool {
+	return len(pk.vks) > 0
+}
+
+func (pk *PublicKey) GetVerificationKey(index int) *PublicKey {
+	vk, ok := pk.vks[index]
+	if !ok {
+		return nil
+	}
+	result := &PublicKey{
+		pg1:   bls.NewG1().New().Set(vk),
+		suite: pk.suite,
+	}
+	if vk2, ok := pk.vks2[index]; ok {
+		result.pg2 = bls.NewG2().New().Set(vk2)
+	}
+	return result
+}
+
+// Scheme of signatures verified by the key, min-sig if the key is also in G2
+func (pk *PublicKey) GetScheme() Scheme {
+	if pk.pg2 != nil {
+		return MinSig
+	}
+	return MinPubKey
+}
+
 func (pk *PublicKey) Encrypt(msg *bls.PointG1) *CipherText {
-	s1 := rand.NewSource(time.Now().UnixNano())
-	r1 := rand.New(s1)
-	r, _ := bls.NewFr().Rand(r1)
+	ct, _ := pk.EncryptWithReader(msg, nil)
+	return ct
+}
+
+// Encrypt with r sampled from random, crypto/rand if nil
+func (pk *PublicKey) EncryptWithReader(msg *bls.PointG1, random io.Reader) (*CipherText, error) {
+	r, err := randomFr(random)
+	if err != nil {
+		return nil, err
+	}
 
 	// C=M+rpk, R1=rG1, R2=rG2
 	g1 := bls.NewG1()
@@ -47,25 +139,265 @@ func (pk *PublicKey) Encrypt(msg *bls.PointG1) *CipherText {
 		cMsg:       cMsg,
 		bigR:       bigR1,
 		commitment: bigR2,
-	}
+	}, nil
 }
 
 func (pk *PublicKey) VerifySig(msg []byte, sig *Signature) bool {
-	g2 := bls.NewG2()
-	g2Hash, _ := g2.HashToCurve(msg, Domain)
+	if sig == nil {
+		return false
+	}
+	input, domain := pk.suite.input(pk.GetScheme(), pk.schemeKey(), msg)
+	if pk.GetScheme() == MinSig {
+		g1Hash, _ := bls.NewG1().HashToCurve(input, domain)
+		return verifyG1Sig(pk.pg2, g1Hash, sig.pg1)
+	}
+	g2Hash, _ := bls.NewG2().HashToCurve(input, domain)
+	return verifyG2Sig(pk.pg1, g2Hash, sig.pg2)
+}
+
+// Shares are signed on the same input as the global signature, so the aug suite prefixes the global key
+func (pk *PublicKey) VerifySigShare(index int, msg []byte, share *SignatureShare) bool {
+	if share == nil {
+		return false
+	}
+	input, domain := pk.suite.input(pk.GetScheme(), pk.schemeKey(), msg)
+	if pk.GetScheme() == MinSig {
+		vk, ok := pk.vks2[index]
+		if !ok {
+			return false
+		}
+		g1Hash, _ := bls.NewG1().HashToCurve(input, domain)
+		return verifyG1Sig(vk, g1Hash, share.pg1)
+	}
+	vk, ok := pk.vks[index]
+	if !ok {
+		return false
+	}
+	g2Hash, _ := bls.NewG2().HashToCurve(input, domain)
+	return verifyG2Sig(vk, g2Hash, share.pg2)
+}
 
+// e(PK,H(msg))==e(G1,S), where the signature is in G2 and the key is in G1
+func verifyG2Sig(pg1 *bls.PointG1, g2Hash *bls.PointG2, sig *bls.PointG2) bool {
+	if pg1 == nil || sig == nil {
+		return false
+	}
 	pairing := bls.NewEngine()
-	e1 := pairing.AddPair(pk.pg1, g2Hash).Result()
-	e2 := pairing.AddPair(&bls.G1One, sig.pg2).Result()
+	e1 := pairing.AddPair(pg1, g2Hash).Result()
+	e2 := pairing.AddPair(&bls.G1One, sig).Result()
 	return e1.Equal(e2)
 }
 
-func (pk *PublicKey) VerifySigShare(msg []byte, sig *SignatureShare) bool {
-	g2 := bls.NewG2()
-	g2Hash, _ := g2.HashToCurve(msg, Domain)
+// e(S,G2)==e(H(msg),PK), where the signature is in G1 and the key is in G2
+func verifyG1Sig(pg2 *bls.PointG2, g1Hash *bls.PointG1, sig *bls.PointG1) bool {
+	if pg2 == nil || sig == nil {
+		return false
+	}
+	pairing := bls.NewEngine()
+	return pairing.AddPair(sig, &bls.G2One).AddPairInv(g1Hash, pg2).Check()
+}
 
+// Ciphertext is expected to pass CipherText.Verify, so that its commitment matches bigR
+func (pk *PublicKey) VerifyDecryptionShare(index int, ct *CipherText, share *DecryptionShare) bool {
+	vk, ok := pk.vks[index]
+	if !ok || share == nil || share.pg1 == nil || ct == nil {
+		return false
+	}
+	// e(S_i,G2)==e(VK_i,rG2)
 	pairing := bls.NewEngine()
-	e1 := pairing.AddPair(pk.pg1, g2Hash).Result()
-	e2 := pairing.AddPair(&bls.G1One, sig.pg2).Result()
+	e1 := pairing.AddPair(share.pg1, &bls.G2One).Result()
+	e2 := pairing.AddPair(vk, ct.commitment).Result()
 	return e1.Equal(e2)
 }
+
+// Encode pg1, then verification keys sorted by index, then pg2 and verification keys in G2 for the min-sig scheme,
+// then the ciphersuite only if it is not the default. Verification keys in G2 are left out unless all of them are set
+func (pk *PublicKey) ToBytes() []byte {
+	g1 := bls.NewG1()
+	indices := make([]int, 0, len(pk.vks))
+	for index := range pk.vks {
+		indices = append(indices, index)
+	}
+	sort.Ints(indices)
+	out := append(encodingHeader(encodingPublicKey), g1.ToCompressed(pk.pg1)...)
+	out = binary.BigEndian.AppendUint32(out, uint32(len(indices)))
+	for _, index := range indices {
+		out = binary.BigEndian.AppendUint32(out, uint32(index))
+		out = append(out, g1.ToCompressed(pk.vks[index])...)
+	}
+	if pk.GetScheme() == MinSig {
+		g2 := bls.NewG2()
+		out = append(out, g2.ToCompressed(pk.pg2)...)
+		if len(pk.vks2) == len(indices) {
+			for _, index := range indices {
+				out = append(out, g2.ToCompressed(pk.vks2[index])...)
+			}
+		}
+	}
+	if pk.suite != SuitePOP {
+		out = append(out, byte(pk.suite))
+	}
+	return out
+}
+
+func BytesToPublicKey(b []byte) (*PublicKey, error) {
+	b, err := readEncodingHeader(b, encodingPublicKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) < fpByteSize+4 {
+		return nil, NewEncodingLengthError()
+	}
+	pg1, err := decodeG1(b[:fpByteSize])
+	if err != nil {
+		return nil, err
+	}
+	n := int(binary.BigEndian.Uint32(b[fpByteSize : fpByteSize+4]))
+	b = b[fpByteSize+4:]
+	if n > len(b)/(4+fpByteSize) {
+		return nil, NewEncodingLengthError()
+	}
+	indices := make([]int, n)
+	vks := make(map[int]*bls.PointG1)
+	for i := 0; i < n; i++ {
+		index := int(binary.BigEndian.Uint32(b[:4]))
+		if _, ok := vks[index]; ok || index <= 0 {
+			return nil, NewEncodingError("invalid verification key index")
+		}
+		vk, err := decodeG1(b[4 : 4+fpByteSize])
+		if err != nil {
+			return nil, err
+		}
+		vks[index] = vk
+		indices[i] = index
+		b = b[4+fpByteSize:]
+	}
+	pk := &PublicKey{
+		pg1: pg1,
+	}
+	if n > 0 {
+		pk.vks = vks
+	}
+	// G2 points are 96 bytes, so a single trailing byte is the ciphersuite
+	if len(b)%(2*fpByteSize) == 1 {
+		pk.suite = Ciphersuite(b[len(b)-1])
+		if pk.suite == SuitePOP || !pk.suite.valid() {
+			return nil, NewEncodingError("invalid ciphersuite")
+		}
+		b = b[:len(b)-1]
+	}
+	if len(b) == 0 {
+		return pk, nil
+	}
+	if len(b) != 2*fpByteSize && len(b) != (n+1)*2*fpByteSize {
+		return nil, NewEncodingLengthError()
+	}
+	if pk.pg2, err = decodeG2(b[:2*fpByteSize]); err != nil {
+		return nil, err
+	}
+	b = b[2*fpByteSize:]
+	if len(b) == 0 {
+		return pk, nil
+	}
+	pk.vks2 = make(map[int]*bls.PointG2)
+	for _, index := range indices {
+		if pk.vks2[index], err = decodeG2(b[:2*fpByteSize]); err != nil {
+			return nil, err
+		}
+		b = b[2*fpByteSize:]
+	}
+	return pk, nil
+}
+
+type publicKeyJSON struct {
+	Version            byte             `json:"version"`
+	Key                hexBytes         `json:"key"`
+	VerificationKeys   map[int]hexBytes `json:"verification_keys,omitempty"`
+	KeyG2              hexBytes         `json:"key_g2,omitempty"`
+	VerificationKeysG2 map[int]hexBytes `json:"verification_keys_g2,omitempty"`
+	Suite              Ciphersuite      `json:"suite,omitempty"`
+}
+
+func (pk *PublicKey) MarshalJSON() ([]byte, error) {
+	g1 := bls.NewG1()
+	v := &publicKeyJSON{
+		Version: EncodingVersion,
+		Key:     g1.ToCompressed(pk.pg1),
+		Suite:   pk.suite,
+	}
+	if len(pk.vks) > 0 {
+		v.VerificationKeys = make(map[int]hexBytes)
+		for index, vk := range pk.vks {
+			v.VerificationKeys[index] = g1.ToCompressed(vk)
+		}
+	}
+	if pk.GetScheme() == MinSig {
+		g2 := bls.NewG2()
+		v.KeyG2 = g2.ToCompressed(pk.pg2)
+		if len(pk.vks2) > 0 {
+			v.VerificationKeysG2 = make(map[int]hexBytes)
+			for index, vk := range pk.vks2 {
+				v.VerificationKeysG2[index] = g2.ToCompressed(vk)
+			}
+		}
+	}
+	return json.Marshal(v)
+}
+
+func (pk *PublicKey) UnmarshalJSON(b []byte) error {
+	var v publicKeyJSON
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	if err := checkEncodingVersion(v.Version); err != nil {
+		return err
+	}
+	pg1, err := decodeG1(v.Key)
+	if err != nil {
+		return err
+	}
+	var vks map[int]*bls.PointG1
+	if len(v.VerificationKeys) > 0 {
+		vks = make(map[int]*bls.PointG1)
+		for index, h := range v.VerificationKeys {
+			if index <= 0 {
+				return NewEncodingError("invalid verification key index")
+			}
+			vk, err := decodeG1(h)
+			if err != nil {
+				return err
+			}
+			vks[index] = vk
+		}
+	}
+	var pg2 *bls.PointG2
+	var vks2 map[int]*bls.PointG2
+	if len(v.KeyG2) > 0 {
+		if pg2, err = decodeG2(v.KeyG2); err != nil {
+			return err
+		}
+		if len(v.VerificationKeysG2) > 0 && len(v.VerificationKeysG2) != len(vks) {
+			return NewEncodingError("invalid verification key index")
+		}
+		if len(v.VerificationKeysG2) > 0 {
+			vks2 = make(map[int]*bls.PointG2)
+		}
+		for index, h := range v.VerificationKeysG2 {
+			if _, ok := vks[index]; !ok {
+				return NewEncodingError("invalid verification key index")
+			}
+			if vks2[index], err = decodeG2(h); err != nil {
+				return err
+			}
+		}
+	}
+	if !v.Suite.valid() {
+		return NewEncodingError("invalid ciphersuite")
+	}
+	pk.pg1 = pg1
+	pk.vks = vks
+	pk.pg2 = pg2
+	pk.vks2 = vks2
+	pk.suite = v.Suite
+	return nil
+}
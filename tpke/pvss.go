@@ -1,9 +1,12 @@
 package tpke
 
 import (
+	"encoding/binary"
+	"encoding/json"
+	"io"
 	"math/big"
 
-	bls "github.com/kilic/bls12-381"
+	bls "github.com/txhsl/tpke/internal/bls"
 )
 
 type PVSS struct {
@@ -11,6 +14,37 @@ type PVSS struct {
 	r1     *bls.PointG1
 	r2     *bls.PointG2
 	bigf   []*bls.PointG1
+	// A0 and public shares in G2, only for the min-sig scheme
+	a0G2   *bls.PointG2
+	bigfG2 []*bls.PointG2
+	// Proof of knowledge of a0, bound to the dealer and the session
+	proof *SchnorrProof
+}
+
+var secretProofDomain = []byte("TPKE_SECRET_KNOWLEDGE_PROOF_V1")
+
+// Context of the proof of knowledge, the session context followed by the dealer index
+func secretProofContext(session []byte, dealer int) []byte {
+	return binary.BigEndian.AppendUint32(appendWithLength(nil, session), uint32(dealer))
+}
+
+// Prove knowledge of a0 in A0=a0*G1, so that a dealer can not publish A0 as a function of others
+func (pvss *PVSS) proveSecret(random io.Reader, session []byte, dealer int, a0 *bls.Fr) error {
+	proof, err := newSchnorrProof(random, secretProofDomain, secretProofContext(session, dealer), &bls.G1One, a0)
+	if err != nil {
+		return err
+	}
+	pvss.proof = proof
+	return nil
+}
+
+// VerifySecretProof checks the proof of knowledge of a0 of the dealer, a missing proof is invalid
+func (pvss *PVSS) VerifySecretProof(session []byte, dealer int) bool {
+	coeff := pvss.public.commitment.coeff
+	if len(coeff) == 0 {
+		return false
+	}
+	return pvss.proof.verify(secretProofDomain, secretProofContext(session, dealer), &bls.G1One, coeff[0])
 }
 
 func GenerateSharedSecrets(r *bls.Fr, size int, secret *Secret) (*PVSS, []*bls.Fr) {
@@ -57,5 +91,231 @@ func (pvss *PVSS) Verify() bool {
 		}
 
 	}
+	if pvss.a0G2 != nil || pvss.bigfG2 != nil {
+		return pvss.verifyG2Shares()
+	}
 	return true
 }
+
+// Publish A0'=a0*G2 and F'(i)=f(i)*G2 for the min-sig scheme
+func (pvss *PVSS) setG2Shares(a0 *bls.Fr, shares []*bls.Fr) {
+	g2 := bls.NewG2()
+	pvss.a0G2 = g2.MulScalar(g2.New(), &bls.G2One, a0)
+	pvss.bigfG2 = make([]*bls.PointG2, len(shares))
+	for i, fi := range shares {
+		pvss.bigfG2[i] = g2.MulScalar(g2.New(), &bls.G2One, fi)
+	}
+}
+
+func (pvss *PVSS) HasG2Shares() bool {
+	return pvss.a0G2 != nil && pvss.bigfG2 != nil
+}
+
+// Verify e(A0,G2)==e(G1,A0') and e(F(i),G2)==e(G1,F'(i)), combined with random weights in one pairing check
+func (pvss *PVSS) verifyG2Shares() bool {
+	if !pvss.HasG2Shares() || len(pvss.bigfG2) != len(pvss.bigf) {
+		return false
+	}
+	weights := batchWeights(len(pvss.bigf) + 1)
+	if weights == nil {
+		return false
+	}
+	g1 := bls.NewG1()
+	g2 := bls.NewG2()
+	p1 := []*bls.PointG1{g1.New().Set(pvss.public.commitment.coeff[0])}
+	p2 := []*bls.PointG2{g2.New().Set(pvss.a0G2)}
+	for i := range pvss.bigf {
+		p1 = append(p1, g1.New().Set(pvss.bigf[i]))
+		p2 = append(p2, g2.New().Set(pvss.bigfG2[i]))
+	}
+	sum1, err := g1.MultiExp(g1.New(), p1, weights)
+	if err != nil {
+		return false
+	}
+	sum2, err := g2.MultiExp(g2.New(), p2, weights)
+	if err != nil {
+		return false
+	}
+	return bls.NewEngine().AddPair(sum1, &bls.G2One).AddPairInv(&bls.G1One, sum2).Check()
+}
+
+// Check A0 is the identity, so that the dealt secret is zero
+func (pvss *PVSS) IsZeroSecret() bool {
+	coeff := pvss.public.commitment.coeff
+	return len(coeff) > 0 && bls.NewG1().IsZero(coeff[0])
+}
+
+// Verify e(r1*fi,G2)==e(F(i),r2), index starts from 1
+func (pvss *PVSS) VerifySecretShare(index int, fi *bls.Fr) bool {
+	if index <= 0 || index > len(pvss.bigf) {
+		return false
+	}
+	g1 := bls.NewG1()
+	pairing := bls.NewEngine()
+	r1 := g1.New().Set(pvss.r1)
+	e1 := pairing.AddPair(g1.MulScalar(r1, r1, fi), &bls.G2One).Result()
+	e2 := pairing.AddPair(pvss.bigf[index-1], pvss.r2).Result()
+	return e1.Equal(e2)
+}
+
+// Encode the commitment, r1, r2, public shares, then A0' and public shares in G2 if any,
+// then the 64-byte proof of knowledge if any
+func (pvss *PVSS) ToBytes() []byte {
+	out := appendG1s(encodingHeader(encodingPVSS), pvss.public.commitment.coeff)
+	out = append(out, bls.NewG1().ToCompressed(pvss.r1)...)
+	out = append(out, bls.NewG2().ToCompressed(pvss.r2)...)
+	out = appendG1s(out, pvss.bigf)
+	if pvss.HasG2Shares() {
+		out = append(out, bls.NewG2().ToCompressed(pvss.a0G2)...)
+		out = appendG2s(out, pvss.bigfG2)
+	}
+	if pvss.proof != nil {
+		out = append(out, pvss.proof.ToBytes()...)
+	}
+	return out
+}
+
+func BytesToPVSS(b []byte) (*PVSS, error) {
+	b, err := readEncodingHeader(b, encodingPVSS)
+	if err != nil {
+		return nil, err
+	}
+	coeff, b, err := readG1s(b)
+	if err != nil {
+		return nil, err
+	}
+	if len(coeff) == 0 || len(b) < 3*fpByteSize {
+		return nil, NewEncodingLengthError()
+	}
+	r1, err := decodeG1(b[:fpByteSize])
+	if err != nil {
+		return nil, err
+	}
+	r2, err := decodeG2(b[fpByteSize : 3*fpByteSize])
+	if err != nil {
+		return nil, err
+	}
+	bigf, b, err := readG1s(b[3*fpByteSize:])
+	if err != nil {
+		return nil, err
+	}
+	var a0G2 *bls.PointG2
+	var bigfG2 []*bls.PointG2
+	// G2 shares are longer than a proof
+	if len(b) > 64 {
+		if len(b) < 2*fpByteSize {
+			return nil, NewEncodingLengthError()
+		}
+		if a0G2, err = decodeG2(b[:2*fpByteSize]); err != nil {
+			return nil, err
+		}
+		if bigfG2, b, err = readG2s(b[2*fpByteSize:]); err != nil {
+			return nil, err
+		}
+	}
+	var proof *SchnorrProof
+	if len(b) > 0 {
+		if proof, err = BytesToSchnorrProof(b); err != nil {
+			return nil, err
+		}
+	}
+	return &PVSS{
+		public: &SecretCommitment{
+			commitment: &Commitment{
+				coeff: coeff,
+			},
+		},
+		r1:     r1,
+		r2:     r2,
+		bigf:   bigf,
+		a0G2:   a0G2,
+		bigfG2: bigfG2,
+		proof:  proof,
+	}, nil
+}
+
+type pvssJSON struct {
+	Version    byte       `json:"version"`
+	Commitment []hexBytes `json:"commitment"`
+	R1         hexBytes   `json:"r1"`
+	R2         hexBytes   `json:"r2"`
+	Shares     []hexBytes `json:"shares"`
+	KeyG2      hexBytes   `json:"key_g2,omitempty"`
+	SharesG2   []hexBytes `json:"shares_g2,omitempty"`
+	Proof      hexBytes   `json:"proof,omitempty"`
+}
+
+func (pvss *PVSS) MarshalJSON() ([]byte, error) {
+	v := &pvssJSON{
+		Version:    EncodingVersion,
+		Commitment: g1sToHex(pvss.public.commitment.coeff),
+		R1:         bls.NewG1().ToCompressed(pvss.r1),
+		R2:         bls.NewG2().ToCompressed(pvss.r2),
+		Shares:     g1sToHex(pvss.bigf),
+	}
+	if pvss.HasG2Shares() {
+		v.KeyG2 = bls.NewG2().ToCompressed(pvss.a0G2)
+		v.SharesG2 = g2sToHex(pvss.bigfG2)
+	}
+	if pvss.proof != nil {
+		v.Proof = pvss.proof.ToBytes()
+	}
+	return json.Marshal(v)
+}
+
+func (pvss *PVSS) UnmarshalJSON(b []byte) error {
+	var v pvssJSON
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	if err := checkEncodingVersion(v.Version); err != nil {
+		return err
+	}
+	coeff, err := hexToG1s(v.Commitment)
+	if err != nil {
+		return err
+	}
+	if len(coeff) == 0 {
+		return NewEncodingLengthError()
+	}
+	r1, err := decodeG1(v.R1)
+	if err != nil {
+		return err
+	}
+	r2, err := decodeG2(v.R2)
+	if err != nil {
+		return err
+	}
+	bigf, err := hexToG1s(v.Shares)
+	if err != nil {
+		return err
+	}
+	var a0G2 *bls.PointG2
+	var bigfG2 []*bls.PointG2
+	if len(v.KeyG2) > 0 || len(v.SharesG2) > 0 {
+		if a0G2, err = decodeG2(v.KeyG2); err != nil {
+			return err
+		}
+		if bigfG2, err = hexToG2s(v.SharesG2); err != nil {
+			return err
+		}
+	}
+	var proof *SchnorrProof
+	if len(v.Proof) > 0 {
+		if proof, err = BytesToSchnorrProof(v.Proof); err != nil {
+			return err
+		}
+	}
+	pvss.public = &SecretCommitment{
+		commitment: &Commitment{
+			coeff: coeff,
+		},
+	}
+	pvss.r1 = r1
+	pvss.r2 = r2
+	pvss.bigf = bigf
+	pvss.a0G2 = a0G2
+	pvss.bigfG2 = bigfG2
+	pvss.proof = proof
+	return nil
+}
@@ -0,0 +1,78 @@
+package tpke
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"io"
+	"sync"
+
+	bls "github.com/txhsl/tpke/internal/bls"
+)
+
+// DRBG is a deterministic HMAC-DRBG with sha256 (NIST SP 800-90A), only for reproducible test vectors.
+// The output is fully determined by the seed, so it must never be used with a public or reused seed
+type DRBG struct {
+	mu sync.Mutex
+	k  []byte
+	v  []byte
+}
+
+func NewDRBG(seed []byte) *DRBG {
+	d := &DRBG{
+		k: make([]byte, sha256.Size),
+		v: make([]byte, sha256.Size),
+	}
+	for i := range d.v {
+		d.v[i] = 0x01
+	}
+	d.update(seed)
+	return d
+}
+
+func (d *DRBG) Read(p []byte) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	n := 0
+	for n < len(p) {
+		d.v = d.hmac(d.k, d.v)
+		n += copy(p[n:], d.v)
+	}
+	d.update(nil)
+	return n, nil
+}
+
+func (d *DRBG) update(data []byte) {
+	d.k = d.hmac(d.k, d.v, []byte{0x00}, data)
+	d.v = d.hmac(d.k, d.v)
+	if len(data) == 0 {
+		return
+	}
+	d.k = d.hmac(d.k, d.v, []byte{0x01}, data)
+	d.v = d.hmac(d.k, d.v)
+}
+
+func (d *DRBG) hmac(key []byte, inputs ...[]byte) []byte {
+	h := hmac.New(sha256.New, key)
+	for _, in := range inputs {
+		h.Write(in)
+	}
+	return h.Sum(nil)
+}
+
+// Use crypto/rand if no source is given
+func randomReader(random io.Reader) io.Reader {
+	if random == nil {
+		return rand.Reader
+	}
+	return random
+}
+
+// Sample Fr from 64 random bytes, so the result only depends on the source and the bias is negligible
+func randomFr(random io.Reader) (*bls.Fr, error) {
+	b := make([]byte, 64)
+	if _, err := io.ReadFull(randomReader(random), b); err != nil {
+		return nil, err
+	}
+	return bls.NewFr().FromBytes(b), nil
+}
@@ -0,0 +1,93 @@
+package tpke
+
+import (
+	"bytes"
+	"testing"
+
+	bls "github.com/txhsl/tpke/internal/bls"
+)
+
+func TestDRBG(t *testing.T) {
+	a := make([]byte, 100)
+	b := make([]byte, 100)
+	NewDRBG([]byte("seed")).Read(a)
+	NewDRBG([]byte("seed")).Read(b)
+	if !bytes.Equal(a, b) {
+		t.Fatalf("drbg is not deterministic.")
+	}
+	NewDRBG([]byte("another seed")).Read(b)
+	if bytes.Equal(a, b) {
+		t.Fatalf("drbg ignores the seed.")
+	}
+}
+
+func TestDeterministicDKG(t *testing.T) {
+	size := 4
+	threshold := 3
+	run := func(seed []byte) (*PublicKey, *CipherText) {
+		random := NewDRBG(seed)
+		dkg := NewDKGWithReader(size, threshold, random)
+		if err := dkg.Prepare(); err != nil {
+			t.Fatalf(err.Error())
+		}
+		if err := dkg.Verify(); err != nil {
+			t.Fatalf(err.Error())
+		}
+		pubkey := dkg.PublishGlobalPublicKey()
+		ct, err := pubkey.EncryptWithReader(&bls.G1One, random)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		return pubkey, ct
+	}
+	pk1, ct1 := run([]byte("test vector"))
+	pk2, ct2 := run([]byte("test vector"))
+	g1 := bls.NewG1()
+	if !g1.Equal(pk1.pg1, pk2.pg1) || !bytes.Equal(ct1.ToBytes(), ct2.ToBytes()) {
+		t.Fatalf("seeded run is not reproducible.")
+	}
+	pk3, _ := run([]byte("another vector"))
+	if g1.Equal(pk1.pg1, pk3.pg1) {
+		t.Fatalf("seeded run ignores the seed.")
+	}
+}
+
+func TestDeterministicCCAShare(t *testing.T) {
+	sk := &PrivateKey{
+		fr: bls.NewFr().One(),
+	}
+	ct, err := sk.GetPublicKey().EncryptCCAWithReader(&bls.G1One, []byte("block 100"), NewDRBG([]byte("test vector")))
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	share1, err := sk.DecryptShareCCAWithReader(ct, NewDRBG([]byte("test vector")))
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	share2, err := sk.DecryptShareCCAWithReader(ct, NewDRBG([]byte("test vector")))
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if !bytes.Equal(share1.ToBytes(), share2.ToBytes()) {
+		t.Fatalf("seeded share is not reproducible.")
+	}
+}
+
+func TestReaderError(t *testing.T) {
+	// The reader runs out before all ecies keys are generated
+	random := bytes.NewReader(make([]byte, 100))
+	if err := NewDKGWithReader(4, 3, random).Prepare(); err == nil {
+		t.Fatalf("dkg prepared without keys.")
+	}
+	dkg := NewDKG(4, 3)
+	if err := dkg.Prepare(); err != nil {
+		t.Fatalf(err.Error())
+	}
+	if err := dkg.Verify(); err != nil {
+		t.Fatalf(err.Error())
+	}
+	rs := NewReshareWithReader(dkg.PublishGlobalPublicKey(), 3, dkg.GetPrivateKeys(), 5, 3, bytes.NewReader(nil))
+	if err := rs.Prepare(); err == nil {
+		t.Fatalf("reshare prepared without keys.")
+	}
+}
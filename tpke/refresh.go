@@ -0,0 +1,80 @@
+package tpke
+
+import (
+	bls "github.com/txhsl/tpke/internal/bls"
+)
+
+// NewRefresh starts a refresh round with the same participants, where every dealer shares a zero secret.
+// Adding the received shares to the current private keys keeps the global public key, and old shares
+// can not be combined with refreshed ones
+func (dkg *DKG) NewRefresh() *DKG {
+	participants := make([]*Participant, dkg.size)
+	for i := 0; i < dkg.size; i++ {
+		participants[i] = NewParticipantWithReader(dkg.participants[i].ethPrvKey, dkg.random)
+	}
+	return &DKG{
+		size:         dkg.size,
+		threshold:    dkg.threshold,
+		participants: participants,
+		refresh:      true,
+		session:      dkg.session,
+		scheme:       dkg.scheme,
+		suite:        dkg.suite,
+		random:       dkg.random,
+	}
+}
+
+// Compute sk_i'=sk_i+sum(f_j(i)), j in QUAL
+func (dkg *DKG) RefreshPrivateKeys(keys map[int]*PrivateKey) (map[int]*PrivateKey, error) {
+	if !dkg.refresh {
+		return nil, NewDKGRefreshError()
+	}
+	deltas := dkg.GetPrivateKeys()
+	results := make(map[int]*PrivateKey)
+	for index, sk := range keys {
+		delta, ok := deltas[index]
+		if !ok {
+			return nil, NewDKGRefreshError()
+		}
+		results[index] = NewPrivateKey([]*bls.Fr{sk.fr, delta.fr})
+		results[index].scheme = sk.scheme
+		results[index].suite = sk.suite
+		results[index].augKey = sk.augKey
+	}
+	return results, nil
+}
+
+// Global public key stays the same, verification keys are moved by VK_i'=VK_i+sum(F_j(i)), j in QUAL
+func (dkg *DKG) RefreshPublicKey(pk *PublicKey) (*PublicKey, error) {
+	if !dkg.refresh {
+		return nil, NewDKGRefreshError()
+	}
+	g1 := bls.NewG1()
+	g2 := bls.NewG2()
+	result := &PublicKey{
+		pg1:   g1.New().Set(pk.pg1),
+		suite: pk.suite,
+	}
+	if pk.pg2 != nil {
+		result.pg2 = g2.New().Set(pk.pg2)
+	}
+	deltas := dkg.PublishVerificationKeys()
+	vks := make(map[int]*PublicKey)
+	for index, vk := range pk.vks {
+		delta, ok := deltas[index]
+		if !ok {
+			return nil, NewDKGRefreshError()
+		}
+		vks[index] = &PublicKey{
+			pg1: g1.Add(g1.New(), vk, delta.pg1),
+		}
+		if vk2, ok := pk.vks2[index]; ok {
+			if delta.pg2 == nil {
+				return nil, NewDKGRefreshError()
+			}
+			vks[index].pg2 = g2.Add(g2.New(), vk2, delta.pg2)
+		}
+	}
+	result.SetVerificationKeys(vks)
+	return result, nil
+}
@@ -0,0 +1,85 @@
+package tpke
+
+import (
+	"crypto/rand"
+	"testing"
+
+	ecies "github.com/ethereum/go-ethereum/crypto/ecies"
+	bls "github.com/txhsl/tpke/internal/bls"
+)
+
+func TestRefresh(t *testing.T) {
+	size := 7
+	threshold := 5
+	dkg := NewDKG(size, threshold)
+	dkg.Prepare()
+	if err := dkg.Verify(); err != nil {
+		t.Fatalf(err.Error())
+	}
+	pubkey := dkg.PublishGlobalPublicKey()
+	prvkeys := dkg.GetPrivateKeys()
+
+	refresh := dkg.NewRefresh()
+	refresh.Prepare()
+	// Dealer 1 tries to change the global secret
+	refresh.participants[0].GenerateSecret(threshold)
+	ss, _ := refresh.participants[0].GenerateShares(size)
+	for j := 0; j < size; j++ {
+		refresh.messageBox[j][0], _ = ecies.Encrypt(rand.Reader, refresh.participants[j].ethPubKey, ss[j].ToBytes(), nil, nil)
+	}
+	if err := refresh.Verify(); err != nil {
+		t.Fatalf(err.Error())
+	}
+	if qual := refresh.GetQualifiedDealers(); len(qual) != size-1 || qual[0] != 2 {
+		t.Fatalf("unexpected qual %v.", qual)
+	}
+	newPubkey, err := refresh.RefreshPublicKey(pubkey)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	newPrvkeys, err := refresh.RefreshPrivateKeys(prvkeys)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if !bls.NewG1().Equal(pubkey.pg1, newPubkey.pg1) {
+		t.Fatalf("public key changed.")
+	}
+	for i, sk := range newPrvkeys {
+		if sk.fr.Equal(prvkeys[i].fr) {
+			t.Fatalf("share not refreshed.")
+		}
+		if !bls.NewG1().Equal(newPubkey.GetVerificationKey(i).pg1, sk.GetPublicKey().pg1) {
+			t.Fatalf("verification key mismatch.")
+		}
+	}
+
+	// Decrypt with refreshed shares
+	msg := []*bls.PointG1{RandPG1()}
+	cipherTexts := Encrypt(msg, pubkey)
+	shares := decryptShare(cipherTexts, newPrvkeys)
+	results, err := Decrypt(cipherTexts, shares, newPubkey, threshold, 1)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if !bls.NewG1().Equal(msg[0], results[0]) {
+		t.Fatalf("decryption failed.")
+	}
+
+	// Old shares can not be combined with refreshed ones
+	sigMsg := []byte("pizza pizza pizza pizza pizza pizza pizza pizza pizza pizza pizza pizza pizza")
+	indices := []int{1, 2, 3, 4, 5}
+	sigShares := []*SignatureShare{
+		prvkeys[1].SignShare(sigMsg),
+		prvkeys[2].SignShare(sigMsg),
+		newPrvkeys[3].SignShare(sigMsg),
+		newPrvkeys[4].SignShare(sigMsg),
+		newPrvkeys[5].SignShare(sigMsg),
+	}
+	sig, err := aggregateShares(indices, sigShares, 1)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if pubkey.VerifySig(sigMsg, sig) {
+		t.Fatalf("old shares accepted.")
+	}
+}
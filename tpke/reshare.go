@@ -0,0 +1,323 @@
+package tpke
+
+import (
+	"io"
+	"sort"
+
+	crypto "github.com/ethereum/go-ethereum/crypto"
+	ecies "github.com/ethereum/go-ethereum/crypto/ecies"
+	bls "github.com/txhsl/tpke/internal/bls"
+)
+
+// Reshare moves the shared secret of an old committee to a new committee of a different size and threshold.
+// Every old holder deals sub-shares of its share, so that the global public key stays the same
+type Reshare struct {
+	oldPublicKey *PublicKey
+	oldThreshold int
+	size         int // New committee size
+	threshold    int // New committee threshold
+	dealers      []int
+	oldKeys      map[int]*PrivateKey
+	dealerSet    map[int]*Participant
+	participants []*Participant
+	messageBox   [][][]byte // [receiver][dealer], dealers in sorted order
+	qual         []int      // Qualified old dealers
+	coeff        []*bls.Fr  // Lagrange coefficients of qualified old dealers
+	scheme       Scheme     // Scheme of the old public key
+	random       io.Reader
+	err          error // Error of key generation, returned by Prepare
+}
+
+func NewReshare(pub *PublicKey, oldThreshold int, keys map[int]*PrivateKey, size int, threshold int) *Reshare {
+	return NewReshareWithReader(pub, oldThreshold, keys, size, threshold, nil)
+}
+
+// Sub-shares and keys of the new committee are sampled from random, crypto/rand if nil
+func NewReshareWithReader(pub *PublicKey, oldThreshold int, keys map[int]*PrivateKey, size int, threshold int, random io.Reader) *Reshare {
+	random = randomReader(random)
+	dealers := make([]int, 0, len(keys))
+	dealerSet := make(map[int]*Participant)
+	for index := range keys {
+		dealers = append(dealers, index)
+		dealerSet[index] = &Participant{
+			random: random,
+		}
+	}
+	sort.Ints(dealers)
+	rs := &Reshare{
+		oldPublicKey: pub,
+		oldThreshold: oldThreshold,
+		size:         size,
+		threshold:    threshold,
+		dealers:      dealers,
+		oldKeys:      keys,
+		dealerSet:    dealerSet,
+		participants: make([]*Participant, size),
+		scheme:       pub.GetScheme(),
+		random:       random,
+	}
+	for i := 0; i < size; i++ {
+		key, err := ecies.GenerateKey(random, crypto.S256(), nil)
+		if err != nil {
+			rs.err = NewDKGError(err.Error()).withCause(err)
+			break
+		}
+		rs.participants[i] = NewParticipantWithReader(key, random)
+	}
+	return rs
+}
+
+// Reshare the secret of the old committee to a new committee
+func (dkg *DKG) NewReshare(pub *PublicKey, size int, threshold int) *Reshare {
+	return NewReshareWithReader(pub, dkg.threshold, dkg.GetPrivateKeys(), size, threshold, dkg.random)
+}
+
+func (rs *Reshare) Prepare() error {
+	if rs.err != nil {
+		return rs.err
+	}
+	rs.messageBox = make([][][]byte, rs.size)
+	for j := 0; j < rs.size; j++ {
+		rs.messageBox[j] = make([][]byte, len(rs.dealers))
+	}
+	for i, index := range rs.dealers {
+		// Init random polynomial a with a0=sk_i
+		dealer := rs.dealerSet[index]
+		secret, err := newSecret(rs.oldKeys[index].fr, rs.threshold, rs.random)
+		if err != nil {
+			return NewDKGError(err.Error()).withCause(err)
+		}
+		dealer.secret = secret
+		// Compute PVSS for the new committee
+		sharedSecrets, err := dealer.GenerateShares(rs.size)
+		if err != nil {
+			return NewDKGError(err.Error()).withCause(err)
+		}
+		if rs.scheme == MinSig {
+			dealer.pvss.setG2Shares(rs.oldKeys[index].fr, sharedSecrets)
+		}
+		// Send messages
+		for j := 0; j < rs.size; j++ {
+			sharedSecret := sharedSecrets[j].ToBytes()
+			msg, err := ecies.Encrypt(rs.random, rs.participants[j].ethPubKey, sharedSecret[:32], nil, nil)
+			if err != nil {
+				return NewDKGError(err.Error()).withCause(err)
+			}
+			rs.messageBox[j][i] = msg
+		}
+	}
+	return nil
+}
+
+func (rs *Reshare) Verify() error {
+	complaints := rs.Complain()
+	responses := rs.Respond(complaints)
+	return rs.Finalize(complaints, responses)
+}
+
+// New participants verify their sub-shares, and complain about old dealers who sent invalid ones
+func (rs *Reshare) Complain() []*Complaint {
+	complaints := make([]*Complaint, 0)
+	for j := 0; j < rs.size; j++ {
+		rs.participants[j].receivedSecrets = make([]*bls.Fr, len(rs.dealers))
+		for i, index := range rs.dealers {
+			fi := rs.participants[j].ReceiveSecret(j+1, rs.dealerSet[index].pvss, rs.messageBox[j][i])
+			if fi == nil {
+				complaints = append(complaints, &Complaint{
+					Dealer:   index,
+					Receiver: j + 1,
+					Evidence: rs.messageBox[j][i],
+				})
+				continue
+			}
+			rs.participants[j].receivedSecrets[i] = fi
+		}
+	}
+	return complaints
+}
+
+// Old dealers answer complaints by revealing the disputed sub-shares
+func (rs *Reshare) Respond(complaints []*Complaint) []*ComplaintResponse {
+	responses := make([]*ComplaintResponse, 0, len(complaints))
+	for _, c := range complaints {
+		dealer, ok := rs.dealerSet[c.Dealer]
+		if !ok {
+			continue
+		}
+		responses = append(responses, &ComplaintResponse{
+			Dealer:   c.Dealer,
+			Receiver: c.Receiver,
+			Secret:   dealer.RevealSecret(c.Receiver),
+		})
+	}
+	return responses
+}
+
+// Compute QUAL of old dealers, and check both committees against the published commitments
+func (rs *Reshare) Finalize(complaints []*Complaint, responses []*ComplaintResponse) error {
+	if !rs.oldPublicKey.HasVerificationKeys() {
+		return NewDKGReshareError()
+	}
+	g1 := bls.NewG1()
+	pvsss := make(map[int]*PVSS)
+	position := make(map[int]int)
+	for i, index := range rs.dealers {
+		position[index] = i
+		pvss := rs.dealerSet[index].pvss
+		// Old dealer must share its own share, A0=VK_i
+		vk, ok := rs.oldPublicKey.vks[index]
+		if pvss == nil || !ok || len(pvss.bigf) != rs.size || len(pvss.public.commitment.coeff) != rs.threshold ||
+			!g1.Equal(pvss.public.commitment.coeff[0], vk) {
+			pvsss[index] = nil
+			continue
+		}
+		// and A0'=VK_i' in G2 for the min-sig scheme
+		if rs.scheme == MinSig {
+			vk2, ok := rs.oldPublicKey.vks2[index]
+			if !ok || !pvss.HasG2Shares() || !bls.NewG2().Equal(pvss.a0G2, vk2) {
+				pvsss[index] = nil
+				continue
+			}
+		}
+		pvsss[index] = pvss
+	}
+	// Drop complaints with fake evidence
+	accepted := make([]*Complaint, 0, len(complaints))
+	for _, c := range complaints {
+		i, ok := position[c.Dealer]
+		if !ok || c.Receiver <= 0 || c.Receiver > rs.size {
+			continue
+		}
+		if c.Match(rs.messageBox[c.Receiver-1][i]) {
+			accepted = append(accepted, c)
+		}
+	}
+	qual := Qualify(pvsss, accepted, responses)
+	if len(qual) < rs.oldThreshold {
+		inQual := make(map[int]bool)
+		for _, index := range qual {
+			inQual[index] = true
+		}
+		err := NewDKGQualError()
+		for _, index := range rs.dealers {
+			if !inQual[index] {
+				err.withParticipants(index)
+			}
+		}
+		return err
+	}
+	for _, c := range accepted {
+		if r := findResponse(responses, c.Dealer, c.Receiver); r != nil && pvsss[c.Dealer] != nil {
+			rs.participants[c.Receiver-1].receivedSecrets[position[c.Dealer]] = bls.NewFr().Set(r.Secret)
+		}
+	}
+	coeff, err := lagrangeCoefficients(qual)
+	if err != nil {
+		return err
+	}
+	rs.qual = qual
+	rs.coeff = coeff
+
+	// Old committee: sum(l_i*VK_i) must be the global public key
+	pk := rs.PublishGlobalPublicKey()
+	if !g1.Equal(pk.pg1, rs.oldPublicKey.pg1) || (rs.scheme == MinSig && !bls.NewG2().Equal(pk.pg2, rs.oldPublicKey.pg2)) {
+		rs.qual = nil
+		return NewDKGReshareError()
+	}
+	// New committee: verification keys must interpolate to the same global public key
+	indices := make([]int, rs.threshold)
+	points := make([]*bls.PointG1, rs.threshold)
+	for j := 0; j < rs.threshold; j++ {
+		indices[j] = j + 1
+		points[j] = g1.New().Set(pk.vks[j+1])
+	}
+	newCoeff, err := lagrangeCoefficients(indices)
+	if err != nil {
+		return err
+	}
+	interpolated, err := g1.MultiExp(g1.New(), points, newCoeff)
+	if err != nil || !g1.Equal(interpolated, pk.pg1) {
+		rs.qual = nil
+		return NewDKGReshareError()
+	}
+	return nil
+}
+
+func (rs *Reshare) GetQualifiedDealers() []int {
+	return rs.qual
+}
+
+// Global public key is sum(l_i*A0_i) over qualified old dealers, with new verification keys sum(l_i*F_i(j))
+func (rs *Reshare) PublishGlobalPublicKey() *PublicKey {
+	g1 := bls.NewG1()
+	a0 := make([]*bls.PointG1, len(rs.qual))
+	for k, index := range rs.qual {
+		a0[k] = g1.New().Set(rs.dealerSet[index].pvss.public.commitment.coeff[0])
+	}
+	pg1, _ := g1.MultiExp(g1.New(), a0, rs.coeff)
+	pk := &PublicKey{
+		pg1:   pg1,
+		suite: rs.oldPublicKey.suite,
+	}
+	if rs.scheme == MinSig {
+		g2 := bls.NewG2()
+		a0G2 := make([]*bls.PointG2, len(rs.qual))
+		for k, index := range rs.qual {
+			a0G2[k] = g2.New().Set(rs.dealerSet[index].pvss.a0G2)
+		}
+		pk.pg2, _ = g2.MultiExp(g2.New(), a0G2, rs.coeff)
+	}
+	pk.SetVerificationKeys(rs.PublishVerificationKeys())
+	return pk
+}
+
+func (rs *Reshare) PublishVerificationKeys() map[int]*PublicKey {
+	g1 := bls.NewG1()
+	vks := make(map[int]*PublicKey)
+	for j := 0; j < rs.size; j++ {
+		bigf := make([]*bls.PointG1, len(rs.qual))
+		for k, index := range rs.qual {
+			bigf[k] = g1.New().Set(rs.dealerSet[index].pvss.bigf[j])
+		}
+		pg1, _ := g1.MultiExp(g1.New(), bigf, rs.coeff)
+		vks[j+1] = &PublicKey{
+			pg1: pg1,
+		}
+		if rs.scheme == MinSig {
+			g2 := bls.NewG2()
+			bigfG2 := make([]*bls.PointG2, len(rs.qual))
+			for k, index := range rs.qual {
+				bigfG2[k] = g2.New().Set(rs.dealerSet[index].pvss.bigfG2[j])
+			}
+			vks[j+1].pg2, _ = g2.MultiExp(g2.New(), bigfG2, rs.coeff)
+		}
+	}
+	return vks
+}
+
+// New share is sk_j'=sum(l_i*f_i(j)) over qualified old dealers, nil if the reshare did not succeed
+func (rs *Reshare) GetPrivateKeys() map[int]*PrivateKey {
+	if rs.qual == nil {
+		return nil
+	}
+	position := make(map[int]int)
+	for i, index := range rs.dealers {
+		position[index] = i
+	}
+	pks := make(map[int]*PrivateKey)
+	for j := 0; j < rs.size; j++ {
+		fr := bls.NewFr().Zero()
+		minor := bls.NewFr()
+		for k, index := range rs.qual {
+			minor.Mul(rs.coeff[k], rs.participants[j].receivedSecrets[position[index]])
+			fr.Add(fr, minor)
+		}
+		pks[j+1] = &PrivateKey{
+			fr:     fr,
+			scheme: rs.scheme,
+		}
+		// Global public key stays the same
+		pks[j+1].SetCiphersuite(rs.oldPublicKey.suite, rs.oldPublicKey)
+	}
+	return pks
+}
@@ -0,0 +1,75 @@
+package tpke
+
+import (
+	"crypto/rand"
+	"testing"
+
+	bls "github.com/txhsl/tpke/internal/bls"
+)
+
+func TestReshare(t *testing.T) {
+	size := 7
+	threshold := 5
+	dkg := NewDKG(size, threshold)
+	dkg.Prepare()
+	if err := dkg.Verify(); err != nil {
+		t.Fatalf(err.Error())
+	}
+	pubkey := dkg.PublishGlobalPublicKey()
+	prvkeys := dkg.GetPrivateKeys()
+
+	// Encrypt before resharing
+	msg := []*bls.PointG1{RandPG1()}
+	cipherTexts := Encrypt(msg, pubkey)
+
+	// Only 6 old holders take part, and holder 2 deals a wrong share
+	oldKeys := make(map[int]*PrivateKey)
+	for i := 1; i <= 6; i++ {
+		oldKeys[i] = prvkeys[i]
+	}
+	fr, _ := bls.NewFr().Rand(rand.Reader)
+	oldKeys[2] = &PrivateKey{
+		fr: fr,
+	}
+	newSize := 10
+	newThreshold := 7
+	reshare := NewReshare(pubkey, threshold, oldKeys, newSize, newThreshold)
+	reshare.Prepare()
+	if err := reshare.Verify(); err != nil {
+		t.Fatalf(err.Error())
+	}
+	if qual := reshare.GetQualifiedDealers(); len(qual) != 5 || qual[1] != 3 {
+		t.Fatalf("unexpected qual %v.", qual)
+	}
+	newPubkey := reshare.PublishGlobalPublicKey()
+	newPrvkeys := reshare.GetPrivateKeys()
+	if !bls.NewG1().Equal(pubkey.pg1, newPubkey.pg1) {
+		t.Fatalf("public key changed.")
+	}
+	for i, sk := range newPrvkeys {
+		if !bls.NewG1().Equal(newPubkey.GetVerificationKey(i).pg1, sk.GetPublicKey().pg1) {
+			t.Fatalf("verification key mismatch.")
+		}
+	}
+
+	// Pending ciphertexts are decrypted by the new committee
+	shares := decryptShare(cipherTexts, newPrvkeys)
+	results, err := Decrypt(cipherTexts, shares, newPubkey, newThreshold, 1)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if !bls.NewG1().Equal(msg[0], results[0]) {
+		t.Fatalf("decryption failed.")
+	}
+
+	// Not enough old holders
+	delete(oldKeys, 1)
+	reshare = NewReshare(pubkey, threshold, oldKeys, newSize, newThreshold)
+	reshare.Prepare()
+	if err := reshare.Verify(); err == nil {
+		t.Fatalf("reshare should fail.")
+	}
+	if reshare.GetPrivateKeys() != nil {
+		t.Fatalf("private keys of a failed reshare.")
+	}
+}
@@ -0,0 +1,19 @@
+package tpke
+
+// Scheme selects the groups of keys and signatures, it is chosen when the dkg is created.
+// Threshold encryption always uses keys in G1
+type Scheme byte
+
+const (
+	// Keys in G1, 96-byte signatures in G2
+	MinPubKey Scheme = iota
+	// Keys also in G2, 48-byte signatures in G1
+	MinSig
+)
+
+// Domain of min-sig signatures with the default POP ciphersuite
+var DomainMinSig = []byte("BLS_SIG_BLS12381G1_XMD:SHA-256_SSWU_RO_POP_")
+
+func (s Scheme) valid() bool {
+	return s == MinPubKey || s == MinSig
+}
@@ -0,0 +1,177 @@
+package tpke
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	bls "github.com/txhsl/tpke/internal/bls"
+)
+
+func TestMinSig(t *testing.T) {
+	size := 7
+	threshold := 4
+	dkg := NewDKGWithScheme(size, threshold, MinSig, nil)
+	if err := dkg.Prepare(); err != nil {
+		t.Fatalf(err.Error())
+	}
+	if err := dkg.Verify(); err != nil {
+		t.Fatalf(err.Error())
+	}
+	sks := dkg.GetPrivateKeys()
+	pk := dkg.PublishGlobalPublicKey()
+	if pk.GetScheme() != MinSig {
+		t.Fatalf("unexpected scheme.")
+	}
+
+	msg := []byte("pizza pizza pizza pizza pizza pizza pizza pizza pizza pizza pizza pizza pizza")
+	inputs := make(map[int]*SignatureShare, size)
+	for i, sk := range sks {
+		inputs[i] = sk.SignShare(msg)
+		if !pk.VerifySigShare(i, msg, inputs[i]) {
+			t.Fatalf("valid share rejected.")
+		}
+	}
+	// Put a wrong share
+	inputs[1] = sks[2].SignShare(msg)
+	if bad := pk.VerifySigShares(msg, inputs); len(bad) != 1 || bad[0] != 1 {
+		t.Fatalf("unexpected invalid shares %v.", bad)
+	}
+	sig, err := AggregateAndVerifySig(pk, msg, threshold, inputs, 1)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if len(sig.ToBytes()) != 48 || !pk.VerifySig(msg, sig) {
+		t.Fatalf("invalid signature.")
+	}
+	if pk.VerifySig([]byte("pizza"), sig) {
+		t.Fatalf("signature of another message accepted.")
+	}
+	restored, err := BytesToSig(sig.ToBytes())
+	if err != nil || !restored.Equals(sig) {
+		t.Fatalf("signature round trip failed.")
+	}
+
+	// Signatures of the other scheme are rejected
+	other := &PrivateKey{fr: sks[1].fr}
+	if pk.VerifySigShare(1, msg, other.SignShare(msg)) {
+		t.Fatalf("share of another scheme accepted.")
+	}
+
+	// Keys keep the scheme through encodings
+	pkBytes, err := BytesToPublicKey(pk.ToBytes())
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	skBytes, err := BytesToPrivateKey(sks[3].ToBytes())
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if !pkBytes.VerifySigShare(3, msg, skBytes.SignShare(msg)) {
+		t.Fatalf("binary round trip failed.")
+	}
+	b, _ := json.Marshal(pk)
+	pkJSON := &PublicKey{}
+	if err := json.Unmarshal(b, pkJSON); err != nil {
+		t.Fatalf(err.Error())
+	}
+	b, _ = json.Marshal(sks[3])
+	skJSON := &PrivateKey{}
+	if err := json.Unmarshal(b, skJSON); err != nil {
+		t.Fatalf(err.Error())
+	}
+	if !pkJSON.VerifySig(msg, sig) || !pkJSON.VerifySigShare(3, msg, skJSON.SignShare(msg)) {
+		t.Fatalf("json round trip failed.")
+	}
+
+	// Keys in G2 are dropped if any is missing, and the key still round trips
+	vks := make(map[int]*PublicKey, size)
+	for i, sk := range sks {
+		vks[i] = sk.GetPublicKey()
+	}
+	vks[2] = &PublicKey{pg1: vks[2].pg1}
+	partial := &PublicKey{pg1: pk.pg1, pg2: pk.pg2}
+	partial.SetVerificationKeys(vks)
+	pkBytes, err = BytesToPublicKey(partial.ToBytes())
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if !bytes.Equal(pkBytes.ToBytes(), partial.ToBytes()) || !pkBytes.VerifySig(msg, sig) || pkBytes.GetVerificationKey(3) == nil {
+		t.Fatalf("binary round trip failed.")
+	}
+	b, _ = json.Marshal(partial)
+	pkJSON = &PublicKey{}
+	if err := json.Unmarshal(b, pkJSON); err != nil {
+		t.Fatalf(err.Error())
+	}
+	if !bytes.Equal(pkJSON.ToBytes(), partial.ToBytes()) {
+		t.Fatalf("json round trip failed.")
+	}
+
+	pvss, err := BytesToPVSS(dkg.participants[0].pvss.ToBytes())
+	if err != nil || !pvss.HasG2Shares() || !pvss.Verify() {
+		t.Fatalf("pvss round trip failed.")
+	}
+}
+
+func TestMinSigRefreshAndReshare(t *testing.T) {
+	size := 5
+	threshold := 3
+	dkg := NewDKGWithScheme(size, threshold, MinSig, nil)
+	if err := dkg.Prepare(); err != nil {
+		t.Fatalf(err.Error())
+	}
+	if err := dkg.Verify(); err != nil {
+		t.Fatalf(err.Error())
+	}
+	sks := dkg.GetPrivateKeys()
+	pk := dkg.PublishGlobalPublicKey()
+	msg := []byte("pizza pizza pizza pizza pizza pizza pizza pizza pizza pizza pizza pizza pizza")
+
+	refresh := dkg.NewRefresh()
+	if err := refresh.Prepare(); err != nil {
+		t.Fatalf(err.Error())
+	}
+	if err := refresh.Verify(); err != nil {
+		t.Fatalf(err.Error())
+	}
+	refreshed, err := refresh.RefreshPrivateKeys(sks)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	refreshedPK, err := refresh.RefreshPublicKey(pk)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if !bls.NewG2().Equal(refreshedPK.pg2, pk.pg2) {
+		t.Fatalf("public key changed.")
+	}
+	for i, sk := range refreshed {
+		if !refreshedPK.VerifySigShare(i, msg, sk.SignShare(msg)) {
+			t.Fatalf("refreshed share rejected.")
+		}
+	}
+
+	rs := NewReshare(refreshedPK, threshold, refreshed, size+2, threshold+1)
+	if err := rs.Prepare(); err != nil {
+		t.Fatalf(err.Error())
+	}
+	if err := rs.Verify(); err != nil {
+		t.Fatalf(err.Error())
+	}
+	newPK := rs.PublishGlobalPublicKey()
+	inputs := make(map[int]*SignatureShare)
+	for i, sk := range rs.GetPrivateKeys() {
+		inputs[i] = sk.SignShare(msg)
+		if !newPK.VerifySigShare(i, msg, inputs[i]) {
+			t.Fatalf("reshared share rejected.")
+		}
+	}
+	sig, err := AggregateAndVerifySig(newPK, msg, threshold+1, inputs, 1)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if !pk.VerifySig(msg, sig) {
+		t.Fatalf("signature rejected by the original key.")
+	}
+}
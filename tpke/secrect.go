@@ -1,16 +1,50 @@
 package tpke
 
-import bls "github.com/kilic/bls12-381"
+import (
+	"encoding/json"
+	"io"
+
+	bls "github.com/txhsl/tpke/internal/bls"
+)
 
 type Secret struct {
 	poly Poly
 }
 
 func RandomSecret(threshold int) *Secret {
-	randomPoly := randomPoly(threshold)
+	secret, _ := RandomSecretWithReader(threshold, nil)
+	return secret
+}
+
+// Secret with coefficients sampled from random, crypto/rand if nil
+func RandomSecretWithReader(threshold int, random io.Reader) (*Secret, error) {
+	randomPoly, err := randomPoly(threshold, random)
+	if err != nil {
+		return nil, err
+	}
 	return &Secret{
 		poly: *randomPoly,
+	}, nil
+}
+
+// Secret with a given a0, used to reshare an existing share
+func NewSecret(a0 *bls.Fr, threshold int) *Secret {
+	secret, _ := newSecret(a0, threshold, nil)
+	return secret
+}
+
+func newSecret(a0 *bls.Fr, threshold int, random io.Reader) (*Secret, error) {
+	secret, err := RandomSecretWithReader(threshold, random)
+	if err != nil {
+		return nil, err
 	}
+	secret.poly.coeff[0].Set(a0)
+	return secret, nil
+}
+
+// Secret with a0=0, used to refresh shares without changing the global secret
+func ZeroSecret(threshold int) *Secret {
+	return NewSecret(bls.NewFr().Zero(), threshold)
 }
 
 func (s *Secret) Commitment() *SecretCommitment {
@@ -57,3 +91,59 @@ func (sc *SecretCommitment) Equals(other *SecretCommitment) bool {
 	}
 	return true
 }
+
+func (sc *SecretCommitment) ToBytes() []byte {
+	return appendG1s(encodingHeader(encodingSecretCommitment), sc.commitment.coeff)
+}
+
+func BytesToSecretCommitment(b []byte) (*SecretCommitment, error) {
+	b, err := readEncodingHeader(b, encodingSecretCommitment)
+	if err != nil {
+		return nil, err
+	}
+	coeff, rest, err := readG1s(b)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) != 0 || len(coeff) == 0 {
+		return nil, NewEncodingLengthError()
+	}
+	return &SecretCommitment{
+		commitment: &Commitment{
+			coeff: coeff,
+		},
+	}, nil
+}
+
+type secretCommitmentJSON struct {
+	Version    byte       `json:"version"`
+	Commitment []hexBytes `json:"commitment"`
+}
+
+func (sc *SecretCommitment) MarshalJSON() ([]byte, error) {
+	return json.Marshal(&secretCommitmentJSON{
+		Version:    EncodingVersion,
+		Commitment: g1sToHex(sc.commitment.coeff),
+	})
+}
+
+func (sc *SecretCommitment) UnmarshalJSON(b []byte) error {
+	var v secretCommitmentJSON
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	if err := checkEncodingVersion(v.Version); err != nil {
+		return err
+	}
+	coeff, err := hexToG1s(v.Commitment)
+	if err != nil {
+		return err
+	}
+	if len(coeff) == 0 {
+		return NewEncodingLengthError()
+	}
+	sc.commitment = &Commitment{
+		coeff: coeff,
+	}
+	return nil
+}
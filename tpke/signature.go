@@ -1,17 +1,19 @@
 package tpke
 
 import (
-	"fmt"
-	"math"
-	"math/big"
+	"sort"
 
-	bls "github.com/kilic/bls12-381"
+	bls "github.com/txhsl/tpke/internal/bls"
 )
 
+// Domain of signatures with the default POP ciphersuite, see Ciphersuite for the others
 var Domain = []byte("BLS_SIG_BLS12381G2_XMD:SHA-256_SSWU_RO_POP_")
 
+// Signature is in G2, or in G1 for the min-sig scheme
 type Signature struct {
-	pg2 *bls.PointG2
+	pg2   *bls.PointG2
+	pg1   *bls.PointG1
+	keyID *KeyID // Optional, key generation of the signature
 }
 
 func NewSignature(pg2 *bls.PointG2) *Signature {
@@ -20,34 +22,113 @@ func NewSignature(pg2 *bls.PointG2) *Signature {
 	}
 }
 
+func NewMinSigSignature(pg1 *bls.PointG1) *Signature {
+	return &Signature{
+		pg1: pg1,
+	}
+}
+
+func (s *Signature) GetScheme() Scheme {
+	if s.pg1 != nil {
+		return MinSig
+	}
+	return MinPubKey
+}
+
 func (s *Signature) Equals(sig *Signature) bool {
+	if s.GetScheme() != sig.GetScheme() {
+		return false
+	}
+	if s.GetScheme() == MinSig {
+		return bls.NewG1().Equal(s.pg1, sig.pg1)
+	}
 	g2 := bls.NewG2()
 	return g2.Equal(s.pg2, sig.pg2)
 }
 
+// SetKeyID binds the signature to a key generation, see Keyring
+func (s *Signature) SetKeyID(id KeyID) {
+	s.keyID = &id
+}
+
+func (s *Signature) GetKeyID() *KeyID {
+	return s.keyID
+}
+
+// 96 bytes, or 48 bytes for the min-sig scheme, followed by 40 bytes of the key id if set
 func (s *Signature) ToBytes() []byte {
-	return bls.NewG2().ToCompressed(s.pg2)
+	var out []byte
+	if s.GetScheme() == MinSig {
+		out = bls.NewG1().ToCompressed(s.pg1)
+	} else {
+		out = bls.NewG2().ToCompressed(s.pg2)
+	}
+	if s.keyID != nil {
+		out = append(out, s.keyID.ToBytes()...)
+	}
+	return out
 }
 
+// Scheme and key id are told by the length
 func BytesToSig(b []byte) (*Signature, error) {
-	pg2, err := bls.NewG2().FromCompressed(b)
-	if err != nil {
-		return nil, err
+	var sig *Signature
+	switch len(b) {
+	case fpByteSize, fpByteSize + keyIDSize:
+		pg1, err := bls.NewG1().FromCompressed(b[:fpByteSize])
+		if err != nil {
+			return nil, err
+		}
+		sig = NewMinSigSignature(pg1)
+		b = b[fpByteSize:]
+	case 2 * fpByteSize, 2*fpByteSize + keyIDSize:
+		pg2, err := bls.NewG2().FromCompressed(b[:2*fpByteSize])
+		if err != nil {
+			return nil, err
+		}
+		sig = NewSignature(pg2)
+		b = b[2*fpByteSize:]
+	default:
+		return nil, NewEncodingLengthError()
 	}
-	return &Signature{
-		pg2: pg2,
-	}, nil
+	if len(b) > 0 {
+		id, err := BytesToKeyID(b)
+		if err != nil {
+			return nil, err
+		}
+		sig.keyID = &id
+	}
+	return sig, nil
 }
 
 type SignatureShare struct {
 	pg2 *bls.PointG2
+	pg1 *bls.PointG1
+}
+
+func (s *SignatureShare) GetScheme() Scheme {
+	if s.pg1 != nil {
+		return MinSig
+	}
+	return MinPubKey
 }
 
 func (s *SignatureShare) ToBytes() []byte {
+	if s.GetScheme() == MinSig {
+		return bls.NewG1().ToCompressed(s.pg1)
+	}
 	return bls.NewG2().ToCompressed(s.pg2)
 }
 
 func BytesToSigShare(b []byte) (*SignatureShare, error) {
+	if len(b) == fpByteSize {
+		pg1, err := bls.NewG1().FromCompressed(b)
+		if err != nil {
+			return nil, err
+		}
+		return &SignatureShare{
+			pg1: pg1,
+		}, nil
+	}
 	pg2, err := bls.NewG2().FromCompressed(b)
 	if err != nil {
 		return nil, err
@@ -57,39 +138,75 @@ func BytesToSigShare(b []byte) (*SignatureShare, error) {
 	}, nil
 }
 
+// Shares are checked against verification keys only if the first threshold shares fail,
+// otherwise method returns error if all combinations of shares fail
 func AggregateAndVerifySig(pk *PublicKey, msg []byte, threshold int, inputs map[int]*SignatureShare, scaler int) (*Signature, error) {
-	if len(inputs) < threshold {
+	if threshold < 1 || len(inputs) < threshold {
 		return nil, NewSigNotEnoughShareError()
 	}
 
-	matrix := make([][]int, len(inputs))           // size=len(inputs)*threshold, including all rows
+	indices := make([]int, 0, len(inputs))         // size=len(inputs), including all indices
 	shares := make([]*SignatureShare, len(inputs)) // size=len(inputs), including all shares
 
 	// Be aware of a random order of sig shares
-	i := 0
-	for index, v := range inputs {
-		row := make([]int, threshold)
-		for j := 0; j < threshold; j++ {
-			row[j] = int(math.Pow(float64(index), float64(j)))
+	for index := range inputs {
+		indices = append(indices, index)
+	}
+	sort.Ints(indices)
+	for i, index := range indices {
+		shares[i] = inputs[index]
+	}
+
+	// Try the first threshold shares
+	sig, err := aggregateShares(indices[:threshold], shares[:threshold], scaler)
+	if err == nil && pk.VerifySig(msg, sig) {
+		return sig, nil
+	}
+
+	// Drop invalid shares found by batch verification
+	if pk.HasVerificationKeys() {
+		invalid := pk.VerifySigShares(msg, inputs)
+		bad := make(map[int]bool)
+		for _, index := range invalid {
+			bad[index] = true
 		}
-		matrix[i] = row
-		shares[i] = v
-		i++
+		idx := make([]int, 0, threshold)
+		s := make([]*SignatureShare, 0, threshold)
+		for i := 0; i < len(shares) && len(idx) < threshold; i++ {
+			if !bad[indices[i]] {
+				idx = append(idx, indices[i])
+				s = append(s, shares[i])
+			}
+		}
+		if len(idx) < threshold {
+			return nil, NewSigNotEnoughShareError().withParticipants(invalid...)
+		}
+		// Valid shares of keys without threshold t still aggregate to an invalid signature
+		sig, err := aggregateShares(idx, s, scaler)
+		if err != nil {
+			return nil, err
+		}
+		if !pk.VerifySig(msg, sig) {
+			return nil, NewSigAggregationError()
+		}
+		return sig, nil
 	}
 
 	// Use different combinations to verify
 	combs := getCombs(len(inputs), threshold)
 	for _, v := range combs {
-		m := make([][]int, threshold)           // size=threshold*threshold, only seleted rows
+		idx := make([]int, threshold)           // size=threshold, only seleted indices
 		s := make([]*SignatureShare, threshold) // size=threshold, only seleted shares
 		for i := 0; i < len(v); i++ {
-			m[i] = matrix[v[i]]
+			idx[i] = indices[v[i]]
 			s[i] = shares[v[i]]
 		}
-		fmt.Printf("m: %v\n", m)
-		fmt.Printf("s: %v\n", s)
 
-		sig := aggregateShares(m, s, scaler)
+		// Skip combinations with malformed shares
+		sig, err := aggregateShares(idx, s, scaler)
+		if err != nil {
+			continue
+		}
 		if pk.VerifySig(msg, sig) {
 			return sig, nil
 		}
@@ -98,79 +215,99 @@ func AggregateAndVerifySig(pk *PublicKey, msg []byte, threshold int, inputs map[
 	return nil, NewSigAggregationError()
 }
 
-func Aggregate(pk *PublicKey, msg []byte, threshold int, inputs map[int]*SignatureShare, scaler int) (*Signature, [][]int, []*SignatureShare, error) {
+func Aggregate(pk *PublicKey, msg []byte, threshold int, inputs map[int]*SignatureShare, scaler int) (*Signature, []int, []*SignatureShare, error) {
 
-	if len(inputs) < threshold {
+	if threshold < 1 || len(inputs) < threshold {
 		return nil, nil, nil, NewSigNotEnoughShareError()
 	}
 
-	matrix := make([][]int, len(inputs))           // size=len(inputs)*threshold, including all rows
+	indices := make([]int, len(inputs))            // size=len(inputs), including all indices
 	shares := make([]*SignatureShare, len(inputs)) // size=len(inputs), including all shares
 
 	// Be aware of a random order of sig shares
 	i := 0
 	for index, v := range inputs {
-		row := make([]int, threshold)
-		for j := 0; j < threshold; j++ {
-			row[j] = int(math.Pow(float64(index), float64(j)))
-		}
-		matrix[i] = row
+		indices[i] = index
 		shares[i] = v
 		i++
 	}
 
-	m := make([][]int, threshold)           // size=threshold*threshold, only seleted rows
-	s := make([]*SignatureShare, threshold) // size=threshold, only seleted shares
-	for i := 0; i < threshold; i++ {
-		m[i] = matrix[i]
-		s[i] = shares[i]
+	sig, err := aggregateShares(indices[:threshold], shares[:threshold], scaler)
+	if err != nil {
+		return nil, nil, nil, err
 	}
-	sig := aggregateShares(m, s, scaler)
 	if pk.VerifySig(msg, sig) {
-		return sig, matrix, shares, nil
+		return sig, indices, shares, nil
 	}
 
 	return nil, nil, nil, NewSigAggregationError()
 }
 
-func Verify(pk *PublicKey, msg []byte, threshold int, inputs map[int]*SignatureShare, scaler int, matrix [][]int, shares []*SignatureShare) (bool, []*Signature) {
+func Verify(pk *PublicKey, msg []byte, threshold int, inputs map[int]*SignatureShare, scaler int, indices []int, shares []*SignatureShare) (bool, []*Signature) {
 	combs := getCombs(len(inputs), threshold)
 	sigs := make([]*Signature, 0)
 	for _, v := range combs {
-		m := make([][]int, threshold)           // size=threshold*threshold, only seleted rows
+		idx := make([]int, threshold)           // size=threshold, only seleted indices
 		s := make([]*SignatureShare, threshold) // size=threshold, only seleted shares
 		for i := 0; i < len(v); i++ {
-			m[i] = matrix[v[i]]
+			idx[i] = indices[v[i]]
 			s[i] = shares[v[i]]
 		}
-		sig := aggregateShares(m, s, scaler)
-		sigs = append(sigs, sig)
-		if !pk.VerifySig(msg, sig) {
+		sig, err := aggregateShares(idx, s, scaler)
+		if err != nil || !pk.VerifySig(msg, sig) {
 			return false, nil
 		}
+		sigs = append(sigs, sig)
 	}
 	return true, sigs
 }
 
-func aggregateShares(matrix [][]int, shares []*SignatureShare, scaler int) *Signature {
-	// Be aware of the integer overflow when the size and threshold grow big
-	d, coeff := feldman(matrix)
-	d = scaler / d
-	// Compute d1
-	denominator := bls.NewFr().FromBytes(big.NewInt(int64(abs(d))).Bytes())
-	g2 := bls.NewG2()
-	pg2 := g2.Zero()
-	// Add up shares with some factors as d1
+func aggregateShares(indices []int, shares []*SignatureShare, scaler int) (*Signature, error) {
+	// Compute lagrange coefficients in Fr, no integer overflow for big size and threshold
+	coeff, err := lagrangeCoefficients(indices)
+	if err != nil {
+		return nil, err
+	}
+	if scaler > 1 {
+		fr := frFromInt(scaler)
+		for i := range coeff {
+			coeff[i].Mul(coeff[i], fr)
+		}
+	}
 	for i := 0; i < len(shares); i++ {
-		minor := g2.New()
-		g2.MulScalar(minor, shares[i].pg2, bls.NewFr().FromBytes(big.NewInt(int64(abs(coeff[i]))).Bytes()))
-		if coeff[i] < 0 {
-			g2.Neg(minor, minor)
+		if shares[i] == nil || shares[i].GetScheme() != shares[0].GetScheme() {
+			return nil, NewSigShareError().withParticipants(indices[i])
 		}
-		g2.Add(pg2, pg2, minor)
 	}
-	// Divide d1 by d
-	g2.MulScalar(pg2, pg2, denominator)
-	// Verify
-	return NewSignature(pg2)
+	if shares[0].GetScheme() == MinSig {
+		return aggregateMinSigShares(shares, coeff)
+	}
+	return aggregateG2Shares(shares, coeff)
+}
+
+func aggregateG2Shares(shares []*SignatureShare, coeff []*bls.Fr) (*Signature, error) {
+	g2 := bls.NewG2()
+	points := make([]*bls.PointG2, len(shares))
+	for i := 0; i < len(shares); i++ {
+		points[i] = g2.New().Set(shares[i].pg2)
+	}
+	// Add up shares with lagrange coefficients, S=sum(l_i*S_i)
+	pg2, err := g2.MultiExp(g2.New(), points, coeff)
+	if err != nil {
+		return nil, NewSigAggregationError()
+	}
+	return NewSignature(pg2), nil
+}
+
+func aggregateMinSigShares(shares []*SignatureShare, coeff []*bls.Fr) (*Signature, error) {
+	g1 := bls.NewG1()
+	points := make([]*bls.PointG1, len(shares))
+	for i := 0; i < len(shares); i++ {
+		points[i] = g1.New().Set(shares[i].pg1)
+	}
+	pg1, err := g1.MultiExp(g1.New(), points, coeff)
+	if err != nil {
+		return nil, NewSigAggregationError()
+	}
+	return NewMinSigSignature(pg1), nil
 }
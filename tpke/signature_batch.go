@@ -0,0 +1,300 @@
+package tpke
+
+import (
+	"sort"
+
+	bls "github.com/txhsl/tpke/internal/bls"
+)
+
+// Hashes of a batch of messages in the signature group, computed once and shared by signing,
+// aggregation and verification
+type batchHashes struct {
+	g2 []*bls.PointG2 // Default scheme
+	g1 []*bls.PointG1 // Min-sig scheme
+}
+
+func hashBatch(scheme Scheme, suite Ciphersuite, key []byte, msgs [][]byte) *batchHashes {
+	h := &batchHashes{}
+	for _, msg := range msgs {
+		input, domain := suite.input(scheme, key, msg)
+		if scheme == MinSig {
+			g1Hash, _ := bls.NewG1().HashToCurve(input, domain)
+			h.g1 = append(h.g1, g1Hash)
+			continue
+		}
+		g2Hash, _ := bls.NewG2().HashToCurve(input, domain)
+		h.g2 = append(h.g2, g2Hash)
+	}
+	return h
+}
+
+// SignShares signs every message, S_m=H(m)*sk
+func (sk *PrivateKey) SignShares(msgs [][]byte) []*SignatureShare {
+	hashes := hashBatch(sk.scheme, sk.suite, sk.signKey(), msgs)
+	shares := make([]*SignatureShare, len(msgs))
+	for m := range msgs {
+		if sk.scheme == MinSig {
+			g1 := bls.NewG1()
+			shares[m] = &SignatureShare{
+				pg1: g1.MulScalar(g1.New(), hashes.g1[m], sk.fr),
+			}
+			continue
+		}
+		g2 := bls.NewG2()
+		shares[m] = &SignatureShare{
+			pg2: g2.MulScalar(g2.New(), hashes.g2[m], sk.fr),
+		}
+	}
+	return shares
+}
+
+// VerifySigBatch checks all signatures at once, e(G1,sum(p_m*S_m))==e(PK,sum(p_m*H(m))),
+// or e(sum(p_m*S_m),G2)==e(sum(p_m*H(m)),PK) for the min-sig scheme
+func (pk *PublicKey) VerifySigBatch(msgs [][]byte, sigs []*Signature) bool {
+	if len(msgs) != len(sigs) {
+		return false
+	}
+	return pk.verifySigBatch(hashBatch(pk.GetScheme(), pk.suite, pk.schemeKey(), msgs), sigs)
+}
+
+func (pk *PublicKey) verifySigBatch(hashes *batchHashes, sigs []*Signature) bool {
+	weights := batchWeights(len(sigs))
+	if weights == nil {
+		return false
+	}
+	g1 := bls.NewG1()
+	g2 := bls.NewG2()
+	pairing := bls.NewEngine()
+	if pk.GetScheme() == MinSig {
+		points := make([]*bls.PointG1, len(sigs))
+		for m, sig := range sigs {
+			if sig == nil || sig.pg1 == nil {
+				return false
+			}
+			points[m] = g1.New().Set(sig.pg1)
+		}
+		sumSig, err := g1.MultiExp(g1.New(), points, weights)
+		if err != nil {
+			return false
+		}
+		sumHash, err := g1.MultiExp(g1.New(), copyG1s(hashes.g1), weights)
+		if err != nil {
+			return false
+		}
+		return pairing.AddPair(sumSig, &bls.G2One).AddPairInv(sumHash, pk.pg2).Check()
+	}
+	points := make([]*bls.PointG2, len(sigs))
+	for m, sig := range sigs {
+		if sig == nil || sig.pg2 == nil {
+			return false
+		}
+		points[m] = g2.New().Set(sig.pg2)
+	}
+	sumSig, err := g2.MultiExp(g2.New(), points, weights)
+	if err != nil {
+		return false
+	}
+	sumHash, err := g2.MultiExp(g2.New(), copyG2s(hashes.g2), weights)
+	if err != nil {
+		return false
+	}
+	return pairing.AddPair(g1.New().Set(pk.pg1), sumHash).AddPairInv(&bls.G1One, sumSig).Check()
+}
+
+// AggregateBatch combines share vectors of participants into one signature per message. Hashes and
+// lagrange coefficients are computed once for the batch. Share vectors are checked against verification
+// keys only if the first threshold fail, otherwise combinations are tried as AggregateAndVerifySig does
+func AggregateBatch(pk *PublicKey, msgs [][]byte, threshold int, inputs map[int][]*SignatureShare, scaler int) ([]*Signature, error) {
+	if len(inputs) < threshold {
+		return nil, NewSigNotEnoughShareError()
+	}
+	indices := make([]int, 0, len(inputs))
+	for index := range inputs {
+		indices = append(indices, index)
+	}
+	sort.Ints(indices)
+	hashes := hashBatch(pk.GetScheme(), pk.suite, pk.schemeKey(), msgs)
+
+	// Try the first threshold participants
+	sigs, err := aggregateBatch(indices[:threshold], inputs, len(msgs), scaler)
+	if err == nil && pk.verifySigBatch(hashes, sigs) {
+		return sigs, nil
+	}
+
+	// Drop participants with any invalid share
+	if pk.HasVerificationKeys() {
+		invalid := pk.verifyShareVectors(hashes, indices, inputs)
+		bad := make(map[int]bool)
+		for _, index := range invalid {
+			bad[index] = true
+		}
+		idx := make([]int, 0, threshold)
+		for i := 0; i < len(indices) && len(idx) < threshold; i++ {
+			if !bad[indices[i]] {
+				idx = append(idx, indices[i])
+			}
+		}
+		if len(idx) < threshold {
+			return nil, NewSigNotEnoughShareError().withParticipants(invalid...)
+		}
+		sigs, err := aggregateBatch(idx, inputs, len(msgs), scaler)
+		if err != nil {
+			return nil, err
+		}
+		if !pk.verifySigBatch(hashes, sigs) {
+			return nil, NewSigAggregationError()
+		}
+		return sigs, nil
+	}
+
+	// Use different combinations to verify
+	for _, v := range getCombs(len(indices), threshold) {
+		idx := make([]int, threshold)
+		for i := range v {
+			idx[i] = indices[v[i]]
+		}
+		sigs, err := aggregateBatch(idx, inputs, len(msgs), scaler)
+		if err == nil && pk.verifySigBatch(hashes, sigs) {
+			return sigs, nil
+		}
+	}
+	return nil, NewSigAggregationError()
+}
+
+// Lagrange coefficients of the participants are computed once, then S_m=sum(l_i*S_im) for every message
+func aggregateBatch(indices []int, inputs map[int][]*SignatureShare, n int, scaler int) ([]*Signature, error) {
+	coeff, err := lagrangeCoefficients(indices)
+	if err != nil {
+		return nil, err
+	}
+	if scaler > 1 {
+		fr := frFromInt(scaler)
+		for i := range coeff {
+			coeff[i].Mul(coeff[i], fr)
+		}
+	}
+	for _, index := range indices {
+		if len(inputs[index]) != n {
+			return nil, NewSigShareError().withParticipants(index)
+		}
+	}
+	sigs := make([]*Signature, n)
+	for m := 0; m < n; m++ {
+		shares := make([]*SignatureShare, len(indices))
+		for i, index := range indices {
+			shares[i] = inputs[index][m]
+			if shares[i] == nil || shares[i].GetScheme() != inputs[indices[0]][m].GetScheme() {
+				return nil, NewSigShareError().withParticipants(index)
+			}
+		}
+		if shares[0].GetScheme() == MinSig {
+			sigs[m], err = aggregateMinSigShares(shares, coeff)
+		} else {
+			sigs[m], err = aggregateG2Shares(shares, coeff)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return sigs, nil
+}
+
+// Returns indices of participants with any invalid share, items are grouped by participant,
+// e(G1,sum(p_im*S_im))==prod(e(VK_i,sum(p_im*H(m)))), so a batch costs at most size+1 pairings
+func (pk *PublicKey) verifyShareVectors(hashes *batchHashes, indices []int, inputs map[int][]*SignatureShare) []int {
+	minSig := pk.GetScheme() == MinSig
+	n := len(hashes.g1) + len(hashes.g2)
+	invalid := make([]int, 0)
+	positions := make([]int, 0, len(indices))
+	for i, index := range indices {
+		ok := len(inputs[index]) == n
+		if minSig {
+			_, has := pk.vks2[index]
+			ok = ok && has
+		} else {
+			_, has := pk.vks[index]
+			ok = ok && has
+		}
+		for m := 0; ok && m < n; m++ {
+			share := inputs[index][m]
+			ok = share != nil && ((minSig && share.pg1 != nil) || (!minSig && share.pg2 != nil))
+		}
+		if !ok {
+			invalid = append(invalid, index)
+			continue
+		}
+		positions = append(positions, i)
+	}
+	weights := batchWeights(len(indices) * n)
+	if weights == nil {
+		return indices
+	}
+	check := func(positions []int) bool {
+		g1 := bls.NewG1()
+		g2 := bls.NewG2()
+		pairing := bls.NewEngine()
+		if minSig {
+			// e(sum(p_im*S_im),G2)==prod(e(sum(p_im*H(m)),VK_i))
+			shares := make([]*bls.PointG1, 0, len(positions)*n)
+			w := make([]*bls.Fr, 0, len(positions)*n)
+			for _, i := range positions {
+				for m := 0; m < n; m++ {
+					shares = append(shares, g1.New().Set(inputs[indices[i]][m].pg1))
+					w = append(w, weights[i*n+m])
+				}
+				sumHash, err := g1.MultiExp(g1.New(), copyG1s(hashes.g1), weights[i*n:(i+1)*n])
+				if err != nil {
+					return false
+				}
+				pairing.AddPairInv(sumHash, pk.vks2[indices[i]])
+			}
+			sumShare, err := g1.MultiExp(g1.New(), shares, w)
+			if err != nil {
+				return false
+			}
+			return pairing.AddPair(sumShare, &bls.G2One).Check()
+		}
+		shares := make([]*bls.PointG2, 0, len(positions)*n)
+		w := make([]*bls.Fr, 0, len(positions)*n)
+		for _, i := range positions {
+			for m := 0; m < n; m++ {
+				shares = append(shares, g2.New().Set(inputs[indices[i]][m].pg2))
+				w = append(w, weights[i*n+m])
+			}
+			sumHash, err := g2.MultiExp(g2.New(), copyG2s(hashes.g2), weights[i*n:(i+1)*n])
+			if err != nil {
+				return false
+			}
+			pairing.AddPair(g1.New().Set(pk.vks[indices[i]]), sumHash)
+		}
+		sumShare, err := g2.MultiExp(g2.New(), shares, w)
+		if err != nil {
+			return false
+		}
+		return pairing.AddPairInv(&bls.G1One, sumShare).Check()
+	}
+	for _, i := range bisect(positions, check) {
+		invalid = append(invalid, indices[i])
+	}
+	sort.Ints(invalid)
+	return invalid
+}
+
+// MultiExp changes its points, so hashes are copied before every use
+func copyG1s(points []*bls.PointG1) []*bls.PointG1 {
+	g1 := bls.NewG1()
+	out := make([]*bls.PointG1, len(points))
+	for i, p := range points {
+		out[i] = g1.New().Set(p)
+	}
+	return out
+}
+
+func copyG2s(points []*bls.PointG2) []*bls.PointG2 {
+	g2 := bls.NewG2()
+	out := make([]*bls.PointG2, len(points))
+	for i, p := range points {
+		out[i] = g2.New().Set(p)
+	}
+	return out
+}
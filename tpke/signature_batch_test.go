@@ -0,0 +1,79 @@
+package tpke
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+)
+
+func TestSignatureBatch(t *testing.T) {
+	for _, scheme := range []Scheme{MinPubKey, MinSig} {
+		size := 7
+		threshold := 4
+		dkg := NewDKGWithScheme(size, threshold, scheme, nil)
+		if err := dkg.Prepare(); err != nil {
+			t.Fatalf(err.Error())
+		}
+		if err := dkg.Verify(); err != nil {
+			t.Fatalf(err.Error())
+		}
+		sks := dkg.GetPrivateKeys()
+		pk := dkg.PublishGlobalPublicKey()
+
+		msgs := make([][]byte, 5)
+		for m := range msgs {
+			msgs[m] = []byte("pizza " + strconv.Itoa(m))
+		}
+		inputs := make(map[int][]*SignatureShare, size)
+		for i, sk := range sks {
+			inputs[i] = sk.SignShares(msgs)
+			for m, share := range inputs[i] {
+				if !pk.VerifySigShare(i, msgs[m], share) {
+					t.Fatalf("valid share rejected.")
+				}
+			}
+		}
+
+		// Put a wrong share into the vectors of the first participants
+		inputs[2][3] = sks[2].SignShare([]byte("pizza"))
+		sigs, err := AggregateBatch(pk, msgs, threshold, inputs, 1)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		if len(sigs) != len(msgs) || !pk.VerifySigBatch(msgs, sigs) {
+			t.Fatalf("invalid signatures.")
+		}
+		for m, sig := range sigs {
+			if !pk.VerifySig(msgs[m], sig) {
+				t.Fatalf("invalid signature.")
+			}
+		}
+		if pk.VerifySigBatch(msgs[1:], sigs[:len(sigs)-1]) {
+			t.Fatalf("signatures of other messages accepted.")
+		}
+
+		// Not enough valid vectors
+		inputs[4] = inputs[4][:2]
+		inputs[5][0] = nil
+		inputs[6] = inputs[7]
+		_, err = AggregateBatch(pk, msgs, threshold, inputs, 1)
+		if !errors.Is(err, ErrNotEnoughShares) {
+			t.Fatalf("aggregation should fail.")
+		}
+		var custom *CustomError
+		if !errors.As(err, &custom) || len(custom.Participants) != 4 || custom.Participants[0] != 2 {
+			t.Fatalf("unexpected blamed participants %v.", custom.Participants)
+		}
+	}
+
+	// Keys of threshold 5 used with threshold 3, all shares are valid but no 3 of them combine
+	_, sks, pk, _ := dkg(5, 5)
+	msgs := [][]byte{[]byte("pizza"), []byte("pizza pizza")}
+	inputs := make(map[int][]*SignatureShare)
+	for i, sk := range sks {
+		inputs[i] = sk.SignShares(msgs)
+	}
+	if _, err := AggregateBatch(pk, msgs, 3, inputs, 1); !errors.Is(err, ErrAggregation) {
+		t.Fatalf("aggregation should fail.")
+	}
+}
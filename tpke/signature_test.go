@@ -2,6 +2,7 @@ package tpke
 
 import (
 	cryptoRand "crypto/rand"
+	"errors"
 	"math"
 	"math/rand"
 	"os"
@@ -10,7 +11,7 @@ import (
 	"testing"
 	"time"
 
-	bls "github.com/kilic/bls12-381"
+	bls "github.com/txhsl/tpke/internal/bls"
 )
 
 func TestSingleSignature(t *testing.T) {
@@ -24,14 +25,83 @@ func TestSingleSignature(t *testing.T) {
 	pk := &PublicKey{
 		pg1: g1.MulScalar(g1.New(), &bls.G1One, fr),
 	}
+	pk.SetVerificationKeys(map[int]*PublicKey{1: sk.GetPublicKey()})
 
 	msg := []byte("pizza pizza pizza pizza pizza pizza pizza pizza pizza pizza pizza pizza pizza")
 	share := sk.SignShare(msg)
-	if !pk.VerifySigShare(msg, share) {
+	if !pk.VerifySigShare(1, msg, share) {
 		t.Fatalf("invalid signature")
 	}
 }
 
+func TestThresholdSignatureLargeCommittee(t *testing.T) {
+	size := 100
+	threshold := 67
+	poly, _ := randomPoly(threshold, nil)
+	g1 := bls.NewG1()
+	pk := &PublicKey{
+		pg1: g1.MulScalar(g1.New(), &bls.G1One, poly.coeff[0]),
+	}
+
+	msg := []byte("pizza pizza pizza pizza pizza pizza pizza pizza pizza pizza pizza pizza pizza")
+	inputs := make(map[int]*SignatureShare, threshold)
+	for i := size - threshold + 1; i <= size; i++ {
+		sk := &PrivateKey{
+			fr: poly.evaluate(*frFromInt(i)),
+		}
+		inputs[i] = sk.SignShare(msg)
+	}
+	sig, err := AggregateAndVerifySig(pk, msg, threshold, inputs, 1)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if !pk.VerifySig(msg, sig) {
+		t.Fatalf("invalid signature")
+	}
+}
+
+func TestThresholdSignatureInvalidShares(t *testing.T) {
+	size := 7
+	threshold := 4
+	_, sks, pk, scaler := dkg(size, threshold)
+
+	msg := []byte("pizza pizza pizza pizza pizza pizza pizza pizza pizza pizza pizza pizza pizza")
+	inputs := make(map[int]*SignatureShare, size)
+	for i, sk := range sks {
+		inputs[i] = sk.SignShare(msg)
+	}
+
+	// Put wrong shares
+	inputs[1] = sks[2].SignShare(msg)
+	inputs[3] = sks[3].SignShare([]byte("pizza"))
+	if pk.VerifySigShare(1, msg, inputs[1]) || pk.VerifySigShare(3, msg, inputs[3]) {
+		t.Fatalf("invalid share accepted")
+	}
+	if !pk.VerifySigShare(2, msg, inputs[2]) {
+		t.Fatalf("valid share rejected")
+	}
+
+	sig, err := AggregateAndVerifySig(pk, msg, threshold, inputs, scaler)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if !pk.VerifySig(msg, sig) {
+		t.Fatalf("invalid signature")
+	}
+
+	// No threshold
+	if _, err := AggregateAndVerifySig(pk, msg, 0, inputs, scaler); !errors.Is(err, ErrNotEnoughShares) {
+		t.Fatalf("aggregation should fail")
+	}
+
+	// Not enough valid shares
+	inputs[5] = inputs[1]
+	inputs[7] = inputs[1]
+	if _, err := AggregateAndVerifySig(pk, msg, threshold, inputs, scaler); err == nil {
+		t.Fatalf("aggregation should fail")
+	}
+}
+
 func TestThresholdSignature(t *testing.T) {
 	nEnv := os.Getenv("N")
 
@@ -59,7 +129,7 @@ func TestThresholdSignature(t *testing.T) {
 	// 	totalSignTime += signElapsed
 	// 	totalAggregateTime += aggregateElapsed
 
-	// 	isValid, sigs := Verify(pk, msg, threshold, inputs, scaler, matrix, shares)
+	// 	isValid, sigs := Verify(pk, msg, threshold, inputs, scaler, indices, shares)
 
 	// 	if !isValid || sigs == nil {
 	// 		t.Fatalf("invalid signature")
@@ -122,7 +192,7 @@ func generateRandomMsg(length int) ([]byte, error) {
 	return randomBytes, nil
 }
 
-func signAndAggregate(sks map[int]*PrivateKey, pk *PublicKey, threshold int, scalar int) (time.Duration, time.Duration, *Signature, [][]int, []*SignatureShare, map[int]*SignatureShare, []byte, error) {
+func signAndAggregate(sks map[int]*PrivateKey, pk *PublicKey, threshold int, scalar int) (time.Duration, time.Duration, *Signature, []int, []*SignatureShare, map[int]*SignatureShare, []byte, error) {
 	// sign
 	signStart := time.Now()
 	// msg := []byte("pizza pizza pizza pizza pizza pizza pizza pizza pizza pizza pizza pizza pizza")
@@ -151,12 +221,12 @@ func signAndAggregate(sks map[int]*PrivateKey, pk *PublicKey, threshold int, sca
 
 	// aggregate
 	aggregateStart := time.Now()
-	sig, matrix, shares, err := Aggregate(pk, msg, threshold, inputs, scalar)
+	sig, indices, shares, err := Aggregate(pk, msg, threshold, inputs, scalar)
 	if err != nil {
 		return 0, 0, nil, nil, nil, inputs, msg, err
 	}
 
 	aggregateElapsed := time.Since(aggregateStart)
 
-	return signElapsed, aggregateElapsed, sig, matrix, shares, inputs, msg, nil
+	return signElapsed, aggregateElapsed, sig, indices, shares, inputs, msg, nil
 }
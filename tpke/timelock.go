@@ -0,0 +1,185 @@
+package tpke
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"encoding/binary"
+	"io"
+
+	bls "github.com/txhsl/tpke/internal/bls"
+	"golang.org/x/crypto/hkdf"
+)
+
+const TimelockVersion byte = 1
+
+var timelockKeyInfo = []byte("TPKE_TIMELOCK_AES256GCM_V1")
+
+// Identities of block heights are prefixed, so they never collide with beacon inputs
+var blockIdentityPrefix = []byte("TPKE_BLOCK_")
+
+// TimelockCipherText is an identity-based encryption to the committee in the style of Boneh-Franklin.
+// The decryption key of an identity is the threshold signature on it, so a single signature releases
+// every ciphertext bound to the same identity, e.g. all transactions of a block
+type TimelockCipherText struct {
+	version  byte
+	identity []byte
+	bigU     *bls.PointG1 // U=rG1, nil for the min-sig scheme
+	bigUG2   *bls.PointG2 // U=rG2 for the min-sig scheme
+	nonce    []byte
+	sealed   []byte
+}
+
+// BlockIdentity is the identity of a block height, 8 bytes big-endian after a fixed prefix
+func BlockIdentity(height uint64) []byte {
+	out := make([]byte, len(blockIdentityPrefix)+8)
+	copy(out, blockIdentityPrefix)
+	binary.BigEndian.PutUint64(out[len(blockIdentityPrefix):], height)
+	return out
+}
+
+func (pk *PublicKey) EncryptTimelock(identity []byte, plaintext []byte) (*TimelockCipherText, error) {
+	return pk.EncryptTimelockWithReader(identity, plaintext, nil)
+}
+
+// K=e(r*PK,H(id)) with the hash of signatures, so that K=e(U,S) for the signature S on the identity.
+// The kem randomness and the nonce are sampled from random, crypto/rand if nil
+func (pk *PublicKey) EncryptTimelockWithReader(identity []byte, plaintext []byte, random io.Reader) (*TimelockCipherText, error) {
+	random = randomReader(random)
+	r, err := randomFr(random)
+	if err != nil {
+		return nil, err
+	}
+	ct := &TimelockCipherText{
+		version:  TimelockVersion,
+		identity: append([]byte{}, identity...),
+	}
+	input, domain := pk.suite.input(pk.GetScheme(), pk.schemeKey(), identity)
+	pairing := bls.NewEngine()
+	if pk.GetScheme() == MinSig {
+		// K=e(H(id),r*PK) with the key in G2
+		g2 := bls.NewG2()
+		g1Hash, _ := bls.NewG1().HashToCurve(input, domain)
+		ct.bigUG2 = g2.MulScalar(g2.New(), &bls.G2One, r)
+		pairing.AddPair(g1Hash, g2.MulScalar(g2.New(), pk.pg2, r))
+	} else {
+		g1 := bls.NewG1()
+		g2Hash, _ := bls.NewG2().HashToCurve(input, domain)
+		ct.bigU = g1.MulScalar(g1.New(), &bls.G1One, r)
+		pairing.AddPair(g1.MulScalar(g1.New(), pk.pg1, r), g2Hash)
+	}
+	aead, err := newTimelockAEAD(pairing.Result())
+	if err != nil {
+		return nil, err
+	}
+	ct.nonce = make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(random, ct.nonce); err != nil {
+		return nil, err
+	}
+	ct.sealed = aead.Seal(nil, ct.nonce, plaintext, ct.header())
+	return ct, nil
+}
+
+// DecryptTimelock opens the ciphertext with the signature on its identity, K=e(U,S).
+// The signature should be checked with VerifySig first, a wrong one fails in aes-gcm
+func DecryptTimelock(ct *TimelockCipherText, sig *Signature) ([]byte, error) {
+	if ct.version != TimelockVersion {
+		return nil, NewAESVersionError()
+	}
+	if sig == nil || (sig.pg1 == nil && sig.pg2 == nil) || (sig.GetScheme() == MinSig) != (ct.bigUG2 != nil) {
+		return nil, NewAESDecryptionError()
+	}
+	pairing := bls.NewEngine()
+	if sig.GetScheme() == MinSig {
+		pairing.AddPair(sig.pg1, ct.bigUG2)
+	} else {
+		pairing.AddPair(ct.bigU, sig.pg2)
+	}
+	aead, err := newTimelockAEAD(pairing.Result())
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := aead.Open(nil, ct.nonce, ct.sealed, ct.header())
+	if err != nil {
+		return nil, NewAESDecryptionError()
+	}
+	return plaintext, nil
+}
+
+func (ct *TimelockCipherText) GetIdentity() []byte {
+	return ct.identity
+}
+
+func (ct *TimelockCipherText) GetScheme() Scheme {
+	if ct.bigUG2 != nil {
+		return MinSig
+	}
+	return MinPubKey
+}
+
+// Version, identity and U are authenticated as additional data
+func (ct *TimelockCipherText) header() []byte {
+	out := appendWithLength([]byte{ct.version}, ct.identity)
+	if ct.bigUG2 != nil {
+		return appendWithLength(out, bls.NewG2().ToCompressed(ct.bigUG2))
+	}
+	return appendWithLength(out, bls.NewG1().ToCompressed(ct.bigU))
+}
+
+func (ct *TimelockCipherText) ToBytes() []byte {
+	out := ct.header()
+	out = append(out, ct.nonce...)
+	return append(out, ct.sealed...)
+}
+
+// Scheme is told by the length of U
+func BytesToTimelockCipherText(b []byte) (*TimelockCipherText, error) {
+	if len(b) < 1 {
+		return nil, NewEncodingLengthError()
+	}
+	if b[0] != TimelockVersion {
+		return nil, NewAESVersionError()
+	}
+	identity, rest, ok := readWithLength(b[1:])
+	if !ok {
+		return nil, NewEncodingLengthError()
+	}
+	u, rest, ok := readWithLength(rest)
+	if !ok {
+		return nil, NewEncodingLengthError()
+	}
+	ct := &TimelockCipherText{
+		version:  b[0],
+		identity: append([]byte{}, identity...),
+	}
+	var err error
+	if len(u) == 2*fpByteSize {
+		ct.bigUG2, err = decodeG2(u)
+	} else {
+		ct.bigU, err = decodeG1(u)
+	}
+	if err != nil {
+		return nil, err
+	}
+	// 12-byte nonce and 16-byte tag of gcm
+	if len(rest) < 12+16 {
+		return nil, NewEncodingLengthError()
+	}
+	ct.nonce = append([]byte{}, rest[:12]...)
+	ct.sealed = append([]byte{}, rest[12:]...)
+	return ct, nil
+}
+
+// Derive an aes-256 key from K with hkdf-sha256
+func newTimelockAEAD(k *bls.E) (cipher.AEAD, error) {
+	key := make([]byte, 32)
+	kdf := hkdf.New(sha256.New, bls.NewGT().ToBytes(k), nil, timelockKeyInfo)
+	if _, err := io.ReadFull(kdf, key); err != nil {
+		return nil, NewAESError(err.Error()).withCause(err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, NewAESEncryptionError()
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,86 @@
+package tpke
+
+import (
+	"bytes"
+	"errors"
+	"strconv"
+	"testing"
+)
+
+func TestTimelock(t *testing.T) {
+	for _, scheme := range []Scheme{MinPubKey, MinSig} {
+		size := 7
+		threshold := 5
+		dkg := NewDKGWithScheme(size, threshold, scheme, nil)
+		if err := dkg.Prepare(); err != nil {
+			t.Fatalf(err.Error())
+		}
+		if err := dkg.Verify(); err != nil {
+			t.Fatalf(err.Error())
+		}
+		pk := dkg.PublishGlobalPublicKey()
+		sks := dkg.GetPrivateKeys()
+
+		// Transactions of a block are encrypted to its height
+		height := uint64(100)
+		txs := make([]*TimelockCipherText, 10)
+		for i := range txs {
+			ct, err := pk.EncryptTimelock(BlockIdentity(height), []byte("tx "+strconv.Itoa(i)))
+			if err != nil {
+				t.Fatalf(err.Error())
+			}
+			txs[i], err = BytesToTimelockCipherText(ct.ToBytes())
+			if err != nil {
+				t.Fatalf(err.Error())
+			}
+			if txs[i].GetScheme() != scheme {
+				t.Fatalf("unexpected scheme.")
+			}
+		}
+
+		// The committee releases the block by signing its height
+		sign := func(identity []byte) *Signature {
+			inputs := make(map[int]*SignatureShare)
+			for i := 1; i <= threshold; i++ {
+				inputs[i] = sks[i].SignShare(identity)
+			}
+			sig, err := AggregateAndVerifySig(pk, identity, threshold, inputs, 1)
+			if err != nil {
+				t.Fatalf(err.Error())
+			}
+			return sig
+		}
+		sig := sign(BlockIdentity(height))
+		for i, ct := range txs {
+			if !bytes.Equal(ct.GetIdentity(), BlockIdentity(height)) {
+				t.Fatalf("unexpected identity.")
+			}
+			plaintext, err := DecryptTimelock(ct, sig)
+			if err != nil {
+				t.Fatalf(err.Error())
+			}
+			if string(plaintext) != "tx "+strconv.Itoa(i) {
+				t.Fatalf("unexpected plaintext.")
+			}
+		}
+
+		// Signatures of other heights do not decrypt
+		if _, err := DecryptTimelock(txs[0], sign(BlockIdentity(height+1))); err == nil {
+			t.Fatalf("decrypted with the signature of another height.")
+		}
+		// Identity is authenticated, the last byte of height follows the version and the length
+		b := txs[0].ToBytes()
+		b[1+4+len(BlockIdentity(height))-1]++
+		ct, err := BytesToTimelockCipherText(b)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		if _, err := DecryptTimelock(ct, sig); err == nil {
+			t.Fatalf("decrypted with a modified identity.")
+		}
+		// Truncated ciphertext
+		if _, err := BytesToTimelockCipherText(b[:len(b)-len(ct.sealed)]); !errors.Is(err, ErrEncoding) {
+			t.Fatalf("truncated ciphertext accepted.")
+		}
+	}
+}
@@ -1,10 +1,10 @@
 package tpke
 
 import (
-	"math"
-	"math/big"
+	"io"
+	"sort"
 
-	bls "github.com/kilic/bls12-381"
+	bls "github.com/txhsl/tpke/internal/bls"
 )
 
 var fpByteSize = 48
@@ -13,8 +13,19 @@ type CipherText struct {
 	cMsg       *bls.PointG1
 	bigR       *bls.PointG1
 	commitment *bls.PointG2
+	keyID      *KeyID // Optional, key generation of the ciphertext
 }
 
+// SetKeyID binds the ciphertext to a key generation, see Keyring
+func (ct *CipherText) SetKeyID(id KeyID) {
+	ct.keyID = &id
+}
+
+func (ct *CipherText) GetKeyID() *KeyID {
+	return ct.keyID
+}
+
+// 192 bytes, followed by 40 bytes of the key id if set
 func (ct *CipherText) ToBytes() []byte {
 	out := make([]byte, 4*fpByteSize)
 	g1 := bls.NewG1()
@@ -22,10 +33,16 @@ func (ct *CipherText) ToBytes() []byte {
 	copy(out[:fpByteSize], g1.ToCompressed(ct.cMsg))
 	copy(out[fpByteSize:2*fpByteSize], g1.ToCompressed(ct.bigR))
 	copy(out[2*fpByteSize:4*fpByteSize], g2.ToCompressed(ct.commitment))
+	if ct.keyID != nil {
+		out = append(out, ct.keyID.ToBytes()...)
+	}
 	return out
 }
 
 func BytesToCipherText(b []byte) (*CipherText, error) {
+	if len(b) != 4*fpByteSize && len(b) != 4*fpByteSize+keyIDSize {
+		return nil, NewEncodingLengthError()
+	}
 	g1 := bls.NewG1()
 	g2 := bls.NewG2()
 	cMsg, err := g1.FromCompressed(b[:fpByteSize])
@@ -40,11 +57,19 @@ func BytesToCipherText(b []byte) (*CipherText, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &CipherText{
+	ct := &CipherText{
 		cMsg:       cMsg,
 		bigR:       bigR,
 		commitment: commitment,
-	}, nil
+	}
+	if len(b) > 4*fpByteSize {
+		id, err := BytesToKeyID(b[4*fpByteSize:])
+		if err != nil {
+			return nil, err
+		}
+		ct.keyID = &id
+	}
+	return ct, nil
 }
 
 func (ct *CipherText) Verify() error {
@@ -59,29 +84,54 @@ func (ct *CipherText) Verify() error {
 }
 
 func Encrypt(msgs []*bls.PointG1, pub *PublicKey) []*CipherText {
+	results, _ := EncryptWithReader(msgs, pub, nil)
+	return results
+}
+
+func EncryptWithReader(msgs []*bls.PointG1, pub *PublicKey, random io.Reader) ([]*CipherText, error) {
 	results := make([]*CipherText, len(msgs))
 	for i := 0; i < len(msgs); i++ {
-		results[i] = pub.Encrypt(msgs[i])
+		ct, err := pub.EncryptWithReader(msgs[i], random)
+		if err != nil {
+			return nil, err
+		}
+		results[i] = ct
 	}
-	return results
+	return results, nil
 }
 
 type DecryptionShare struct {
-	pg1 *bls.PointG1
+	pg1   *bls.PointG1
+	proof *DLEQProof // Only for CCA ciphertexts
 }
 
 func (s *DecryptionShare) ToBytes() []byte {
-	return bls.NewG1().ToCompressed(s.pg1)
+	out := bls.NewG1().ToCompressed(s.pg1)
+	if s.proof != nil {
+		out = append(out, s.proof.ToBytes()...)
+	}
+	return out
 }
 
 func BytesToDecryptionShare(b []byte) (*DecryptionShare, error) {
-	pg1, err := bls.NewG1().FromCompressed(b)
+	if len(b) != fpByteSize && len(b) != fpByteSize+64 {
+		return nil, NewTPKEDecryptionShareError()
+	}
+	pg1, err := bls.NewG1().FromCompressed(b[:fpByteSize])
 	if err != nil {
 		return nil, err
 	}
-	return &DecryptionShare{
+	share := &DecryptionShare{
 		pg1: pg1,
-	}, nil
+	}
+	if len(b) > fpByteSize {
+		proof, err := BytesToDLEQProof(b[fpByteSize:])
+		if err != nil {
+			return nil, err
+		}
+		share.proof = proof
+	}
+	return share, nil
 }
 
 type decryptMessage struct {
@@ -90,8 +140,9 @@ type decryptMessage struct {
 }
 
 type verifyMessage struct {
-	index int
-	err   error
+	index  int
+	result *bls.PointG1
+	err    error
 }
 
 func decryptShare(cts []*CipherText, prvs map[int]*PrivateKey) map[int]([]*DecryptionShare) {
@@ -120,37 +171,46 @@ func parallelDecryptShare(index int, key *PrivateKey, cts []*CipherText, ch chan
 	}
 }
 
-// PublicKey is used for immediate verification, method returns error if all combinations of shares fail
+// PublicKey is used for immediate verification. Shares are checked against verification keys only if the
+// first threshold shares fail, otherwise method returns error if all combinations of shares fail
 func Decrypt(cts []*CipherText, inputs map[int]([]*DecryptionShare), pub *PublicKey, threshold int, scaler int) ([]*bls.PointG1, error) {
 	if len(inputs) < threshold {
 		return nil, NewTPKENotEnoughShareError()
 	}
 
-	matrix := make([][]int, len(inputs))              // size=len(inputs)*threshold, including all rows
+	indices := make([]int, 0, len(inputs))            // size=len(inputs), including all indices
 	shares := make([][]*DecryptionShare, len(inputs)) // size=len(inputs)*len(cts), including all shares
 
 	// Be aware of a random order of decryption shares
-	i := 0
-	for index, v := range inputs {
-		row := make([]int, threshold)
-		for j := 0; j < threshold; j++ {
-			row[j] = int(math.Pow(float64(index), float64(j)))
-		}
-		matrix[i] = row
-		shares[i] = v
-		i++
+	for index := range inputs {
+		indices = append(indices, index)
+	}
+	sort.Ints(indices)
+	for i, index := range indices {
+		shares[i] = inputs[index]
+	}
+
+	// Try the first threshold shares
+	results, err := tryDecrypt(cts, indices[:threshold], shares[:threshold], pub, scaler)
+	if err == nil {
+		return results, nil
+	}
+
+	// Drop invalid shares one by one
+	if pub.HasVerificationKeys() {
+		return decryptVerifiedShares(cts, indices, shares, pub, threshold, scaler)
 	}
 
 	// Use different combinations to decrypt
 	combs := getCombs(len(inputs), threshold)
 	for _, v := range combs {
-		m := make([][]int, threshold)              // size=threshold*threshold, only seleted rows
+		idx := make([]int, threshold)              // size=threshold, only seleted indices
 		s := make([][]*DecryptionShare, threshold) // size=threshold*len(cts), only seleted shares
 		for i := 0; i < len(v); i++ {
-			m[i] = matrix[v[i]]
+			idx[i] = indices[v[i]]
 			s[i] = shares[v[i]]
 		}
-		results, err := tryDecrypt(cts, m, s, pub, scaler)
+		results, err := tryDecrypt(cts, idx, s, pub, scaler)
 		if err == nil {
 			return results, nil
 		}
@@ -158,64 +218,124 @@ func Decrypt(cts []*CipherText, inputs map[int]([]*DecryptionShare), pub *Public
 	return nil, NewTPKEDecryptionError()
 }
 
-func tryDecrypt(cts []*CipherText, matrix [][]int, shares [][]*DecryptionShare, pub *PublicKey, scaler int) ([]*bls.PointG1, error) {
-	// Be aware of the integer overflow when the size and threshold of tpke grow big
-	d, coeff := feldman(matrix)
-	d = scaler / d
-	results := make([]*bls.PointG1, len(cts))
-	// Compute M=C-d1/d
-	denominator := bls.NewFr().FromBytes(big.NewInt(int64(abs(d))).Bytes())
-	if d < 0 {
-		denominator.Neg(denominator)
+func tryDecrypt(cts []*CipherText, indices []int, shares [][]*DecryptionShare, pub *PublicKey, scaler int) ([]*bls.PointG1, error) {
+	coeff, err := decryptionCoefficients(indices, scaler)
+	if err != nil {
+		return nil, err
 	}
-	ch := make(chan verifyMessage, len(cts))
+	results := make([]*bls.PointG1, len(cts))
+	rpks := make([]*bls.PointG1, len(cts))
 	g1 := bls.NewG1()
 	for i := 0; i < len(cts); i++ {
-		rpk := g1.Zero()
-		// Add up shares with some factors as d1, and plus -1
+		s := make([]*DecryptionShare, len(shares))
 		for j := 0; j < len(shares); j++ {
-			minor := g1.New()
-			g1.MulScalar(minor, shares[j][i].pg1, bls.NewFr().FromBytes(big.NewInt(int64(abs(coeff[j]))).Bytes()))
-			if coeff[j] > 0 {
-				g1.Neg(minor, minor)
+			if i >= len(shares[j]) || shares[j][i] == nil {
+				return nil, NewTPKEDecryptionShareError().withParticipants(indices[j]).withCipherText(i)
 			}
-			g1.Add(rpk, rpk, minor)
+			s[j] = shares[j][i]
+		}
+		rpk, err := combineDecryptionShares(coeff, s)
+		if err != nil {
+			return nil, err
 		}
-		// Divide -d1 by d
-		g1.MulScalar(rpk, rpk, denominator)
 		// Decrypt
 		results[i] = g1.Add(g1.Zero(), cts[i].cMsg, rpk)
-		// Verify the decryption
-		go parallelVerify(i, cts[i], pub.pg1, rpk, ch)
+		rpks[i] = rpk
 	}
-	for i := 0; i < len(cts); i++ {
-		msg := <-ch
-		if msg.err != nil {
-			return nil, msg.err
+	// Verify all decryptions in one batch
+	if !verifyDecryptions(pub.pg1, cts, rpks) {
+		return nil, NewTPKEDecryptionError()
+	}
+	return results, nil
+}
+
+// Invalid shares are found by batch verification, then the first threshold valid shares are combined
+func decryptVerifiedShares(cts []*CipherText, indices []int, shares [][]*DecryptionShare, pub *PublicKey, threshold int, scaler int) ([]*bls.PointG1, error) {
+	inputs := make(map[int]([]*DecryptionShare))
+	for j, index := range indices {
+		inputs[index] = shares[j]
+	}
+	invalid := pub.VerifyDecryptionShares(cts, inputs)
+	bad := make(map[int]map[int]bool)
+	for index, positions := range invalid {
+		bad[index] = make(map[int]bool)
+		for _, i := range positions {
+			bad[index][i] = true
 		}
 	}
+	// Participants with an invalid share of ciphertext i
+	blame := func(i int) []int {
+		blamed := make([]int, 0)
+		for _, index := range indices {
+			if bad[index][i] {
+				blamed = append(blamed, index)
+			}
+		}
+		return blamed
+	}
 
+	results := make([]*bls.PointG1, len(cts))
+	rpks := make([]*bls.PointG1, len(cts))
+	g1 := bls.NewG1()
+	for i := 0; i < len(cts); i++ {
+		idx := make([]int, 0, threshold)
+		s := make([]*DecryptionShare, 0, threshold)
+		for j := 0; j < len(indices) && len(idx) < threshold; j++ {
+			if !bad[indices[j]][i] {
+				idx = append(idx, indices[j])
+				s = append(s, shares[j][i])
+			}
+		}
+		if len(idx) < threshold {
+			return nil, NewTPKENotEnoughShareError().withParticipants(blame(i)...).withCipherText(i)
+		}
+		coeff, err := decryptionCoefficients(idx, scaler)
+		if err != nil {
+			return nil, err
+		}
+		rpk, err := combineDecryptionShares(coeff, s)
+		if err != nil {
+			return nil, err
+		}
+		results[i] = g1.Add(g1.New(), cts[i].cMsg, rpk)
+		rpks[i] = rpk
+	}
+	// Valid shares of keys without threshold t still combine to a wrong plaintext
+	if !verifyDecryptions(pub.pg1, cts, rpks) {
+		return nil, NewTPKEDecryptionError()
+	}
 	return results, nil
 }
 
-func parallelVerify(index int, ct *CipherText, pk *bls.PointG1, rpk *bls.PointG1, ch chan<- verifyMessage) {
-	// User sends an invalid commitment for his random r
-	g2 := bls.NewG2()
-	pairing := bls.NewEngine()
-	cmt := g2.New()
-	g2.Neg(cmt, ct.commitment)
-	// Decrypted rpk is not correct, e(pk,rG2)!=e(rpk,G2), decryption fails
-	e1 := pairing.AddPair(pk, cmt).Result()
-	e2 := pairing.AddPair(rpk, &bls.G2One).Result()
-	if !e1.Equal(e2) {
-		ch <- verifyMessage{
-			index: index,
-			err:   NewTPKEDecryptionError(),
+func decryptionCoefficients(indices []int, scaler int) ([]*bls.Fr, error) {
+	// Compute lagrange coefficients in Fr, no integer overflow for big size and threshold
+	coeff, err := lagrangeCoefficients(indices)
+	if err != nil {
+		return nil, err
+	}
+	if scaler > 1 {
+		fr := frFromInt(scaler)
+		for j := range coeff {
+			coeff[j].Mul(coeff[j], fr)
 		}
-		return
 	}
-	ch <- verifyMessage{
-		index: index,
-		err:   nil,
+	// Negate coefficients to compute -rpk directly
+	for j := range coeff {
+		coeff[j].Neg(coeff[j])
+	}
+	return coeff, nil
+}
+
+// Compute -rpk=-sum(l_j*S_j), so that M=C-rpk
+func combineDecryptionShares(coeff []*bls.Fr, shares []*DecryptionShare) (*bls.PointG1, error) {
+	g1 := bls.NewG1()
+	points := make([]*bls.PointG1, len(shares))
+	for j := 0; j < len(shares); j++ {
+		points[j] = g1.New().Set(shares[j].pg1)
+	}
+	rpk, err := g1.MultiExp(g1.New(), points, coeff)
+	if err != nil {
+		return nil, NewTPKEDecryptionError()
 	}
+	return rpk, nil
 }
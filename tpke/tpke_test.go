@@ -3,7 +3,7 @@ package tpke
 import (
 	"testing"
 
-	bls "github.com/kilic/bls12-381"
+	bls "github.com/txhsl/tpke/internal/bls"
 )
 
 func TestTPKE(t *testing.T) {
@@ -43,6 +43,106 @@ func TestTPKE(t *testing.T) {
 	}
 }
 
+func TestVerificationKeys(t *testing.T) {
+	size := 7
+	threshold := 5
+	dkg := NewDKG(size, threshold)
+	dkg.Prepare()
+	if err := dkg.Verify(); err != nil {
+		t.Fatalf(err.Error())
+	}
+	pubkey := dkg.PublishGlobalPublicKey()
+	prvkeys := dkg.GetPrivateKeys()
+	for i, sk := range prvkeys {
+		if !bls.NewG1().Equal(pubkey.GetVerificationKey(i).pg1, sk.GetPublicKey().pg1) {
+			t.Fatalf("verification key mismatch.")
+		}
+	}
+
+	msg := make([]*bls.PointG1, 2)
+	msg[0] = RandPG1()
+	msg[1] = RandPG1()
+	cipherTexts := Encrypt(msg, pubkey)
+	shares := decryptShare(cipherTexts, prvkeys)
+	if !pubkey.VerifyDecryptionShare(1, cipherTexts[0], shares[1][0]) {
+		t.Fatalf("valid share rejected.")
+	}
+	if pubkey.VerifyDecryptionShare(2, cipherTexts[0], shares[1][0]) {
+		t.Fatalf("invalid share accepted.")
+	}
+	if pubkey.VerifyDecryptionShare(1, cipherTexts[0], nil) || pubkey.VerifyDecryptionShare(1, cipherTexts[0], &DecryptionShare{}) {
+		t.Fatalf("empty share accepted.")
+	}
+	if pubkey.VerifyDecryptionShare(1, nil, shares[1][0]) {
+		t.Fatalf("share accepted without ciphertext.")
+	}
+
+	// Put wrong shares for different ciphertexts
+	shares[1][0].pg1 = RandPG1()
+	shares[2][1].pg1 = RandPG1()
+	shares[3] = shares[3][:1]
+	results, err := Decrypt(cipherTexts, shares, pubkey, threshold, dkg.GetScaler())
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	for i := range msg {
+		if !bls.NewG1().Equal(msg[i], results[i]) {
+			t.Fatalf("decryption failed.")
+		}
+	}
+
+	// Not enough valid shares
+	shares[4][1].pg1 = RandPG1()
+	if _, err := Decrypt(cipherTexts, shares, pubkey, threshold, dkg.GetScaler()); err == nil {
+		t.Fatalf("decryption should fail.")
+	}
+}
+
+func TestThresholdMismatch(t *testing.T) {
+	// Keys of threshold 5 are used with threshold 3, every share is valid but no 3 of them combine
+	size := 5
+	threshold := 3
+	_, sks, pk, _ := dkg(size, size)
+
+	msg := []byte("pizza pizza pizza pizza pizza pizza pizza pizza pizza pizza pizza pizza pizza")
+	inputs := make(map[int]*SignatureShare, size)
+	for i, sk := range sks {
+		inputs[i] = sk.SignShare(msg)
+	}
+	if _, err := AggregateAndVerifySig(pk, msg, threshold, inputs, 1); err == nil {
+		t.Fatalf("aggregation should fail.")
+	}
+
+	cipherTexts := Encrypt([]*bls.PointG1{RandPG1()}, pk)
+	shares := decryptShare(cipherTexts, sks)
+	if _, err := Decrypt(cipherTexts, shares, pk, threshold, 1); err == nil {
+		t.Fatalf("decryption should fail.")
+	}
+
+	// Nor do they in an aggregator
+	sigAggregator := NewSigAggregator(pk, msg, threshold, 1)
+	decAggregator, err := NewDecryptionAggregator(pk, cipherTexts, threshold, 1)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	for i := 1; i <= threshold; i++ {
+		if _, err = sigAggregator.AddSigShare(i, inputs[i]); err != nil {
+			break
+		}
+	}
+	if err == nil || sigAggregator.Done() {
+		t.Fatalf("aggregation should fail.")
+	}
+	for i := 1; i <= threshold; i++ {
+		if _, err = decAggregator.AddDecryptionShares(i, shares[i]); err != nil {
+			break
+		}
+	}
+	if err == nil || decAggregator.Done() {
+		t.Fatalf("decryption should fail.")
+	}
+}
+
 func TestBytesEncoding(t *testing.T) {
 	ct := &CipherText{
 		cMsg:       &bls.G1One,
@@ -2,75 +2,51 @@ package tpke
 
 import (
 	"bytes"
+	"encoding/binary"
 	"errors"
-	"math"
+	"math/big"
+
+	bls "github.com/txhsl/tpke/internal/bls"
 )
 
-func feldman(matrix [][]int) (int, []int) {
-	// Compute D, D1
-	d, coeff := determinant(matrix, len(matrix))
-	g := d
-	for i := 0; i < len(coeff); i++ {
-		g = gcd(g, coeff[i])
-	}
-	d = d / g
-	for i := 0; i < len(coeff); i++ {
-		coeff[i] = coeff[i] / g
-	}
-	if d < 0 {
-		d = -d
-		for i := 0; i < len(coeff); i++ {
-			coeff[i] = -coeff[i]
-		}
-	}
-	return d, coeff
+// Convert a participant index into a field element
+func frFromInt(i int) *bls.Fr {
+	return bls.NewFr().FromBytes(big.NewInt(int64(i)).Bytes())
 }
 
-func determinant(matrix [][]int, order int) (int, []int) {
-	value := 0
-	coeff := make([]int, order)
-	sign := 1
-	if order == 1 {
-		value = matrix[0][0]
-		coeff[0] = 1
-	} else {
-		for i := 0; i < order; i++ {
-			cofactor := laplace(matrix, i, 0, order)
-			value += sign * matrix[i][0] * cofactor
-			coeff[i] = sign * cofactor
-			sign *= -1
+// Compute lagrange coefficients at x=0 in Fr, indices start from 1
+func lagrangeCoefficients(indices []int) ([]*bls.Fr, error) {
+	xs := make([]*bls.Fr, len(indices))
+	for i, index := range indices {
+		if index <= 0 {
+			return nil, NewInterpolationIndexError().withParticipants(index)
 		}
-	}
-	return value, coeff
-}
-
-func laplace(matrix [][]int, r int, c int, order int) int {
-	result := 0
-	cofactor := make([][]int, order)
-	for i := 0; i < order; i++ {
-		cofactor[i] = make([]int, order)
-	}
-	for i := 0; i < order; i++ {
-		for j := 0; j < order; j++ {
-			tmpi := i
-			tmpj := j
-			if i != r && j != c {
-				if i > r {
-					i--
-				}
-				if j > c {
-					j--
-				}
-				cofactor[i][j] = matrix[tmpi][tmpj]
-				i = tmpi
-				j = tmpj
+		xs[i] = frFromInt(index)
+	}
+	coeff := make([]*bls.Fr, len(xs))
+	numerator := bls.NewFr()
+	denominator := bls.NewFr()
+	diff := bls.NewFr()
+	for i := range xs {
+		numerator.One()
+		denominator.One()
+		// l_i(0)=prod(x_j/(x_j-x_i)), j!=i
+		for j := range xs {
+			if i == j {
+				continue
 			}
+			diff.Sub(xs[j], xs[i])
+			if diff.IsZero() {
+				return nil, NewInterpolationDuplicateError().withParticipants(indices[i])
+			}
+			numerator.Mul(numerator, xs[j])
+			denominator.Mul(denominator, diff)
 		}
+		denominator.Inverse(denominator)
+		coeff[i] = bls.NewFr()
+		coeff[i].Mul(numerator, denominator)
 	}
-	if order >= 2 {
-		result, _ = determinant(cofactor, order-1)
-	}
-	return result
+	return coeff, nil
 }
 
 func pkcs7Padding(data []byte, blockSize int) []byte {
@@ -88,58 +64,16 @@ func pkcs7UnPadding(data []byte) ([]byte, error) {
 		return nil, errors.New("empty array")
 	}
 	unPadding := int(data[length-1])
-	if length-unPadding < 0 {
+	if unPadding == 0 || unPadding > length {
 		return nil, errors.New("unpadding failed")
 	}
-	return data[:(length - unPadding)], nil
-}
-
-func gcd(a, b int) int {
-	if b == 0 {
-		return a
-	}
-	if b < 0 {
-		b = -b
-	}
-	return gcd(b, a%b)
-}
-
-func lcm(a, b int) int {
-	return a * b / gcd(a, b)
-}
-
-func abs(a int) int {
-	if a < 0 {
-		return -a
-	}
-	return a
-}
-
-// To improve computation performance
-func getEncryptionScaler(size int, threshold int) int {
-	matrix := make([][]int, threshold) // size=threshold*threshold
-	return searchDLCM(matrix, 1, 0, 0, size, threshold)
-}
-
-func searchDLCM(matrix [][]int, l, pos, offset, size, threshold int) int {
-	if pos == threshold {
-		d, coeff := feldman(matrix)
-		g := d
-		for i := 0; i < len(coeff); i++ {
-			g = gcd(g, coeff[i])
+	// Every padding byte must be the padding length
+	for _, b := range data[length-unPadding:] {
+		if int(b) != unPadding {
+			return nil, errors.New("unpadding failed")
 		}
-		d = d / g
-		return abs(d)
 	}
-	for i := pos + offset; i < size-threshold+pos+1; i++ {
-		row := make([]int, threshold)
-		for j := 0; j < threshold; j++ {
-			row[j] = int(math.Pow(float64(i+1), float64(j)))
-		}
-		matrix[pos] = row
-		l = lcm(l, searchDLCM(matrix, l, pos+1, i-pos, size, threshold))
-	}
-	return l
+	return data[:(length - unPadding)], nil
 }
 
 func getCombs(m int, n int) [][]int {
@@ -154,9 +88,30 @@ func searchCombs(arr []int, pos, offset, m, n int) [][]int {
 		results = append(results, comb)
 		return results
 	}
-	for i := pos + offset; i < m; i++ {
+	// Leave enough elements for the remaining positions
+	for i := pos + offset; i < m-n+pos+1; i++ {
 		arr[pos] = i
 		results = append(results, searchCombs(arr, pos+1, i-pos, m, n)...)
 	}
 	return results
 }
+
+// Append b with a 4-byte length prefix
+func appendWithLength(out []byte, b []byte) []byte {
+	var l [4]byte
+	binary.BigEndian.PutUint32(l[:], uint32(len(b)))
+	out = append(out, l[:]...)
+	return append(out, b...)
+}
+
+// Read a length prefixed slice, return the rest of input
+func readWithLength(b []byte) ([]byte, []byte, bool) {
+	if len(b) < 4 {
+		return nil, nil, false
+	}
+	l := binary.BigEndian.Uint32(b[:4])
+	if uint64(len(b)-4) < uint64(l) {
+		return nil, nil, false
+	}
+	return b[4 : 4+l], b[4+l:], true
+}
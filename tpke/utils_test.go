@@ -2,45 +2,44 @@ package tpke
 
 import (
 	"testing"
+
+	bls "github.com/txhsl/tpke/internal/bls"
 )
 
-func TestDeterminant(t *testing.T) {
-	matrix := [][]int{{7, 8, 9, 4, 3}, {4, 9, 7, 0, 0}, {3, 6, 1, 0, 0}, {0, 5, 6, 0, 0}, {0, 6, 8, 0, 0}}
-	result, _ := determinant(matrix, len(matrix))
-	if result != 0 {
-		t.Fatalf("test failed. %v", result)
-	}
-	matrix = [][]int{{6, 5, 4, 3, 2}, {4, 9, 7, 0, 0}, {3, 6, 1, 0, 0}, {0, 5, 6, 0, 0}, {0, 6, 8, 0, 0}}
-	result, _ = determinant(matrix, len(matrix))
-	if result != 0 {
-		t.Fatalf("test failed. %v", result)
-	}
-	matrix = [][]int{{6, 5, 4, 3, 2}, {7, 8, 9, 4, 3}, {3, 6, 1, 0, 0}, {0, 5, 6, 0, 0}, {0, 6, 8, 0, 0}}
-	result, _ = determinant(matrix, len(matrix))
-	if result != 12 {
-		t.Fatalf("test failed. %v", result)
+func TestLagrange(t *testing.T) {
+	size := 1000
+	threshold := 667
+	poly, _ := randomPoly(threshold, nil)
+
+	// Take the last threshold shares
+	indices := make([]int, threshold)
+	shares := make([]*bls.Fr, threshold)
+	for i := 0; i < threshold; i++ {
+		indices[i] = size - threshold + i + 1
+		shares[i] = poly.evaluate(*frFromInt(indices[i]))
 	}
-	matrix = [][]int{{6, 5, 4, 3, 2}, {7, 8, 9, 4, 3}, {4, 9, 7, 0, 0}, {0, 5, 6, 0, 0}, {0, 6, 8, 0, 0}}
-	result, _ = determinant(matrix, len(matrix))
-	if result != 16 {
-		t.Fatalf("test failed. %v", result)
+	coeff, err := lagrangeCoefficients(indices)
+	if err != nil {
+		t.Fatalf(err.Error())
 	}
-	matrix = [][]int{{6, 5, 4, 3, 2}, {7, 8, 9, 4, 3}, {4, 9, 7, 0, 0}, {3, 6, 1, 0, 0}, {0, 6, 8, 0, 0}}
-	result, _ = determinant(matrix, len(matrix))
-	if result != 78 {
-		t.Fatalf("test failed. %v", result)
+
+	// f(0)=sum(l_i*f(i))
+	result := bls.NewFr().Zero()
+	minor := bls.NewFr()
+	for i := 0; i < threshold; i++ {
+		minor.Mul(coeff[i], shares[i])
+		result.Add(result, minor)
 	}
-	matrix = [][]int{{6, 5, 4, 3, 2}, {7, 8, 9, 4, 3}, {4, 9, 7, 0, 0}, {3, 6, 1, 0, 0}, {0, 5, 6, 0, 0}}
-	result, _ = determinant(matrix, len(matrix))
-	if result != 67 {
-		t.Fatalf("test failed. %v", result)
+	if !result.Equal(poly.coeff[0]) {
+		t.Fatalf("interpolation failed.")
 	}
-	matrix = [][]int{{7, 6, 5, 4, 3, 2}, {9, 7, 8, 9, 4, 3}, {7, 4, 9, 7, 0, 0}, {5, 3, 6, 1, 0, 0}, {0, 0, 5, 6, 0, 0}, {0, 0, 6, 8, 0, 0}}
-	result, coeff := determinant(matrix, len(matrix))
-	if result != 4 {
-		t.Fatalf("test failed. %v", result)
+}
+
+func TestLagrangeInvalidIndex(t *testing.T) {
+	if _, err := lagrangeCoefficients([]int{1, 2, 2}); err == nil {
+		t.Fatalf("duplicate index accepted.")
 	}
-	if coeff[0]*7+coeff[1]*9+coeff[2]*7+coeff[3]*5+coeff[4]*0+coeff[5]*0 != result {
-		t.Fatalf("test failed.")
+	if _, err := lagrangeCoefficients([]int{0, 1, 2}); err == nil {
+		t.Fatalf("zero index accepted.")
 	}
 }
@@ -0,0 +1,479 @@
+package tpke
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"testing"
+
+	bls "github.com/txhsl/tpke/internal/bls"
+)
+
+var updateVectors = flag.Bool("update", false, "regenerate testdata/vectors.json from the seeds")
+
+var vectorsPath = "testdata/vectors.json"
+
+// Known-answer vector, every output is derived from a DRBG seeded with Seed in a fixed order:
+// dkg, plaintext, ciphertext, deterministic shares and signatures, then the cca ciphertext and its shares,
+// the envelope, the timelock ciphertext, the batch and the encrypted pvss
+type vector struct {
+	Seed      string `json:"seed"`
+	Size      int    `json:"size"`
+	Threshold int    `json:"threshold"`
+	Scheme    Scheme `json:"scheme"`
+	Message   string `json:"message"` // Signed message
+
+	Session          hexBytes     `json:"session"`  // Context of proofs of knowledge, derived from the committee
+	PVSS             []hexBytes   `json:"pvss"`     // PVSS of every dealer
+	Messages         [][]hexBytes `json:"messages"` // Encrypted shares, [receiver][dealer]
+	PublicKey        hexBytes     `json:"publicKey"`
+	PrivateKeys      []hexBytes   `json:"privateKeys"`
+	Plaintext        hexBytes     `json:"plaintext"`
+	CipherText       hexBytes     `json:"cipherText"`
+	DecryptionShares []hexBytes   `json:"decryptionShares"`
+	SignatureShares  []hexBytes   `json:"signatureShares"`
+	Signature        hexBytes     `json:"signature"`
+
+	CCACipherText       hexBytes   `json:"ccaCipherText"`       // Plaintext labelled with the message
+	CCADecryptionShares []hexBytes `json:"ccaDecryptionShares"` // Shares with proofs
+	Envelope            hexBytes   `json:"envelope"`            // Message sealed with the seed as aad
+	Identity            hexBytes   `json:"identity"`
+	IdentitySignature   hexBytes   `json:"identitySignature"`
+	TimelockCipherText  hexBytes   `json:"timelockCipherText"` // Message locked to the identity
+	BatchPlaintexts     []hexBytes `json:"batchPlaintexts"`
+	BatchCipherText     hexBytes   `json:"batchCipherText"`
+	ShareKeys           []hexBytes `json:"shareKeys"`       // Share decryption keys of recipients
+	EncryptedPVSS       hexBytes   `json:"encryptedPVSS"`   // A fresh secret dealt to the share keys
+	KeyedCipherText     hexBytes   `json:"keyedCipherText"` // Ciphertext and signature with the key id of epoch 1
+	KeyedSignature      hexBytes   `json:"keyedSignature"`
+}
+
+func generateVector(t *testing.T, in *vector) *vector {
+	random := NewDRBG([]byte(in.Seed))
+	dkg := NewDKGWithScheme(in.Size, in.Threshold, in.Scheme, random)
+	if err := dkg.Prepare(); err != nil {
+		t.Fatalf(err.Error())
+	}
+	if err := dkg.Verify(); err != nil {
+		t.Fatalf(err.Error())
+	}
+	sks := dkg.GetPrivateKeys()
+	pk := dkg.PublishGlobalPublicKey()
+	out := &vector{
+		Seed:      in.Seed,
+		Size:      in.Size,
+		Threshold: in.Threshold,
+		Scheme:    in.Scheme,
+		Message:   in.Message,
+		Session:   dkg.GetSession(),
+		PublicKey: pk.ToBytes(),
+	}
+	for i := 0; i < in.Size; i++ {
+		out.PVSS = append(out.PVSS, dkg.participants[i].pvss.ToBytes())
+		messages := make([]hexBytes, in.Size)
+		for j := range messages {
+			messages[j] = dkg.messageBox[i][j]
+		}
+		out.Messages = append(out.Messages, messages)
+		out.PrivateKeys = append(out.PrivateKeys, sks[i+1].ToBytes())
+	}
+
+	fr, err := randomFr(random)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	g1 := bls.NewG1()
+	plaintext := g1.MulScalar(g1.New(), &bls.G1One, fr)
+	out.Plaintext = g1.ToCompressed(plaintext)
+	ct, err := pk.EncryptWithReader(plaintext, random)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	out.CipherText = ct.ToBytes()
+
+	inputs := make(map[int]*SignatureShare)
+	for i := 1; i <= in.Size; i++ {
+		out.DecryptionShares = append(out.DecryptionShares, sks[i].DecryptShare(ct).ToBytes())
+		inputs[i] = sks[i].SignShare([]byte(in.Message))
+		out.SignatureShares = append(out.SignatureShares, inputs[i].ToBytes())
+	}
+	sig, err := AggregateAndVerifySig(pk, []byte(in.Message), in.Threshold, inputs, 1)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	out.Signature = sig.ToBytes()
+
+	cca, err := pk.EncryptCCAWithReader(plaintext, []byte(in.Message), random)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	out.CCACipherText = cca.ToBytes()
+	for i := 1; i <= in.Size; i++ {
+		share, err := sks[i].DecryptShareCCAWithReader(cca, random)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		out.CCADecryptionShares = append(out.CCADecryptionShares, share.ToBytes())
+	}
+	env, err := SealThresholdWithReader(pk, []byte(in.Message), []byte(in.Seed), random)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	out.Envelope = env.ToBytes()
+
+	out.Identity = BlockIdentity(100)
+	for i := 1; i <= in.Size; i++ {
+		inputs[i] = sks[i].SignShare(out.Identity)
+	}
+	sig, err = AggregateAndVerifySig(pk, out.Identity, in.Threshold, inputs, 1)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	out.IdentitySignature = sig.ToBytes()
+	tl, err := pk.EncryptTimelockWithReader(out.Identity, []byte(in.Message), random)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	out.TimelockCipherText = tl.ToBytes()
+
+	msgs := make([]*bls.Fr, 3)
+	for i := range msgs {
+		if msgs[i], err = randomFr(random); err != nil {
+			t.Fatalf(err.Error())
+		}
+		out.BatchPlaintexts = append(out.BatchPlaintexts, msgs[i].ToBytes())
+	}
+	batch, err := EncryptBatchWithReader(msgs, pk, random)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	out.BatchCipherText = batch.ToBytes()
+
+	// Transcripts grow with 255 bit ciphertexts per recipient, two recipients pin the format
+	eks := make([]*ShareEncryptionKey, 2)
+	for j := range eks {
+		dk, err := GenerateShareKey(random)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		eks[j] = dk.GetEncryptionKey()
+		out.ShareKeys = append(out.ShareKeys, dk.fr.ToBytes())
+	}
+	a0, err := randomFr(random)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	secret, err := newSecret(a0, len(eks), random)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	r, err := randomFr(random)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	ep, err := GenerateEncryptedPVSS(r, secret, eks, random)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	out.EncryptedPVSS = ep.ToBytes()
+
+	id := pk.KeyID(1)
+	keyedCT := *ct
+	keyedCT.SetKeyID(id)
+	out.KeyedCipherText = keyedCT.ToBytes()
+	keyedSig, err := BytesToSig(out.Signature)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	keyedSig.SetKeyID(id)
+	out.KeyedSignature = keyedSig.ToBytes()
+	return out
+}
+
+func readVectors(t *testing.T) []*vector {
+	b, err := os.ReadFile(vectorsPath)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	vectors := make([]*vector, 0)
+	if err := json.Unmarshal(b, &vectors); err != nil {
+		t.Fatalf(err.Error())
+	}
+	return vectors
+}
+
+// TestVectors regenerates every vector from its seed and compares the encodings byte by byte,
+// run with -update after an intended change of the wire format
+func TestVectors(t *testing.T) {
+	vectors := readVectors(t)
+	if len(vectors) == 0 {
+		t.Fatalf("no test vectors.")
+	}
+	for k, v := range vectors {
+		generated := generateVector(t, v)
+		if *updateVectors {
+			vectors[k] = generated
+			continue
+		}
+		expected, _ := json.Marshal(v)
+		actual, _ := json.Marshal(generated)
+		if !bytes.Equal(expected, actual) {
+			t.Fatalf("vector %d mismatch, seed %q.", k, v.Seed)
+		}
+	}
+	if *updateVectors {
+		b, err := json.MarshalIndent(vectors, "", "  ")
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		if err := os.WriteFile(vectorsPath, append(b, '\n'), 0644); err != nil {
+			t.Fatalf(err.Error())
+		}
+	}
+}
+
+// TestVectorsDecoding only decodes the published bytes, as another implementation would
+func TestVectorsDecoding(t *testing.T) {
+	for k, v := range readVectors(t) {
+		pk, err := BytesToPublicKey(v.PublicKey)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		if pk.GetScheme() != v.Scheme || !bytes.Equal(pk.ToBytes(), v.PublicKey) {
+			t.Fatalf("vector %d: public key mismatch.", k)
+		}
+		sks := make(map[int]*PrivateKey)
+		for i := 0; i < v.Size; i++ {
+			pvss, err := BytesToPVSS(v.PVSS[i])
+			if err != nil {
+				t.Fatalf(err.Error())
+			}
+			if !bytes.Equal(pvss.ToBytes(), v.PVSS[i]) || !pvss.Verify() || !pvss.VerifySecretProof(v.Session, i+1) {
+				t.Fatalf("vector %d: pvss %d mismatch.", k, i+1)
+			}
+			sk, err := BytesToPrivateKey(v.PrivateKeys[i])
+			if err != nil {
+				t.Fatalf(err.Error())
+			}
+			if !bytes.Equal(sk.ToBytes(), v.PrivateKeys[i]) || !bls.NewG1().Equal(sk.GetPublicKey().pg1, pk.vks[i+1]) {
+				t.Fatalf("vector %d: private key %d mismatch.", k, i+1)
+			}
+			sks[i+1] = sk
+		}
+
+		// Any threshold shares decrypt the ciphertext
+		ct, err := BytesToCipherText(v.CipherText)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		if err := ct.Verify(); err != nil {
+			t.Fatalf(err.Error())
+		}
+		shares := make(map[int]([]*DecryptionShare))
+		for i := v.Size - v.Threshold; i < v.Size; i++ {
+			share, err := BytesToDecryptionShare(v.DecryptionShares[i])
+			if err != nil {
+				t.Fatalf(err.Error())
+			}
+			if !pk.VerifyDecryptionShare(i+1, ct, share) {
+				t.Fatalf("vector %d: decryption share %d rejected.", k, i+1)
+			}
+			shares[i+1] = []*DecryptionShare{share}
+		}
+		results, err := Decrypt([]*CipherText{ct}, shares, pk, v.Threshold, 1)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		if !bytes.Equal(bls.NewG1().ToCompressed(results[0]), v.Plaintext) {
+			t.Fatalf("vector %d: plaintext mismatch.", k)
+		}
+
+		for i := 0; i < v.Size; i++ {
+			share, err := BytesToSigShare(v.SignatureShares[i])
+			if err != nil {
+				t.Fatalf(err.Error())
+			}
+			if !pk.VerifySigShare(i+1, []byte(v.Message), share) {
+				t.Fatalf("vector %d: signature share %d rejected.", k, i+1)
+			}
+		}
+		sig, err := BytesToSig(v.Signature)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		if !pk.VerifySig([]byte(v.Message), sig) {
+			t.Fatalf("vector %d: signature rejected.", k)
+		}
+
+		testVectorCCA(t, k, v, pk, sks)
+		testVectorTimelock(t, k, v, pk)
+		testVectorBatch(t, k, v, pk, sks)
+		testVectorEncryptedPVSS(t, k, v)
+		testVectorKeyID(t, k, v, pk)
+	}
+}
+
+// The cca shares decrypt the cca ciphertext, and shares of any threshold participants open the envelope
+func testVectorCCA(t *testing.T, k int, v *vector, pk *PublicKey, sks map[int]*PrivateKey) {
+	ct, err := BytesToCCACipherText(v.CCACipherText)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if err := ct.Verify(); err != nil {
+		t.Fatalf(err.Error())
+	}
+	if !bytes.Equal(ct.ToBytes(), v.CCACipherText) || !bytes.Equal(ct.GetLabel(), []byte(v.Message)) {
+		t.Fatalf("vector %d: cca ciphertext mismatch.", k)
+	}
+	shares := make(map[int]([]*DecryptionShare))
+	for i := 0; i < v.Size; i++ {
+		share, err := BytesToDecryptionShare(v.CCADecryptionShares[i])
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		if !pk.VerifyDecryptionShareCCA(i+1, ct, share) {
+			t.Fatalf("vector %d: cca decryption share %d rejected.", k, i+1)
+		}
+		shares[i+1] = []*DecryptionShare{share}
+	}
+	results, err := DecryptCCA([]*CCACipherText{ct}, shares, pk, v.Threshold)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if !bytes.Equal(bls.NewG1().ToCompressed(results[0]), v.Plaintext) {
+		t.Fatalf("vector %d: cca plaintext mismatch.", k)
+	}
+
+	env, err := BytesToEnvelope(v.Envelope)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if !bytes.Equal(env.ToBytes(), v.Envelope) || !bytes.Equal(env.GetAAD(), []byte(v.Seed)) {
+		t.Fatalf("vector %d: envelope mismatch.", k)
+	}
+	openShares := make(map[int]*DecryptionShare)
+	for i := 1; i <= v.Threshold; i++ {
+		share, err := sks[i].OpenShare(env)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		if !pk.VerifyOpenShare(i, env, share) {
+			t.Fatalf("vector %d: open share %d rejected.", k, i)
+		}
+		openShares[i] = share
+	}
+	plaintext, err := OpenThreshold(env, openShares, pk, v.Threshold)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if !bytes.Equal(plaintext, []byte(v.Message)) {
+		t.Fatalf("vector %d: envelope plaintext mismatch.", k)
+	}
+}
+
+// The signature on the identity opens the timelock ciphertext
+func testVectorTimelock(t *testing.T, k int, v *vector, pk *PublicKey) {
+	sig, err := BytesToSig(v.IdentitySignature)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if !pk.VerifySig(v.Identity, sig) {
+		t.Fatalf("vector %d: identity signature rejected.", k)
+	}
+	ct, err := BytesToTimelockCipherText(v.TimelockCipherText)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if !bytes.Equal(ct.ToBytes(), v.TimelockCipherText) || !bytes.Equal(ct.GetIdentity(), v.Identity) || ct.GetScheme() != v.Scheme {
+		t.Fatalf("vector %d: timelock ciphertext mismatch.", k)
+	}
+	plaintext, err := DecryptTimelock(ct, sig)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if !bytes.Equal(plaintext, []byte(v.Message)) {
+		t.Fatalf("vector %d: timelock plaintext mismatch.", k)
+	}
+}
+
+func testVectorBatch(t *testing.T, k int, v *vector, pk *PublicKey, sks map[int]*PrivateKey) {
+	ct, err := BytesToBatchCipherText(v.BatchCipherText)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if err := ct.Verify(); err != nil {
+		t.Fatalf(err.Error())
+	}
+	if !bytes.Equal(ct.ToBytes(), v.BatchCipherText) || ct.Len() != len(v.BatchPlaintexts) {
+		t.Fatalf("vector %d: batch ciphertext mismatch.", k)
+	}
+	shares := make(map[int]*DecryptionShare)
+	for i := v.Size - v.Threshold + 1; i <= v.Size; i++ {
+		shares[i] = sks[i].DecryptBatchShare(ct)
+	}
+	results, err := DecryptBatch(ct, shares, pk, v.Threshold, 1)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	for i, result := range results {
+		if !bytes.Equal(result.ToBytes(), v.BatchPlaintexts[i]) {
+			t.Fatalf("vector %d: batch plaintext %d mismatch.", k, i)
+		}
+	}
+}
+
+// Every recipient decrypts the share committed in the transcript
+func testVectorEncryptedPVSS(t *testing.T, k int, v *vector) {
+	dks := make([]*ShareDecryptionKey, len(v.ShareKeys))
+	eks := make([]*ShareEncryptionKey, len(v.ShareKeys))
+	for j, b := range v.ShareKeys {
+		fr, err := decodeFr(b)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		dks[j] = &ShareDecryptionKey{
+			fr: fr,
+		}
+		eks[j] = dks[j].GetEncryptionKey()
+	}
+	ep, err := BytesToEncryptedPVSS(v.EncryptedPVSS)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if !bytes.Equal(ep.ToBytes(), v.EncryptedPVSS) || !ep.Verify(eks) {
+		t.Fatalf("vector %d: encrypted pvss mismatch.", k)
+	}
+	g1 := bls.NewG1()
+	for j, dk := range dks {
+		fi, err := dk.DecryptShare(j+1, ep)
+		if err != nil {
+			t.Fatalf(err.Error())
+		}
+		if !g1.Equal(g1.MulScalar(g1.New(), &bls.G1One, fi), ep.GetPVSS().bigf[j]) {
+			t.Fatalf("vector %d: encrypted share %d mismatch.", k, j+1)
+		}
+	}
+}
+
+// Keyed encodings are the plain ones followed by the key id
+func testVectorKeyID(t *testing.T, k int, v *vector, pk *PublicKey) {
+	id := pk.KeyID(1)
+	ct, err := BytesToCipherText(v.KeyedCipherText)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if ct.GetKeyID() == nil || *ct.GetKeyID() != id || !bytes.Equal(v.KeyedCipherText, append(append([]byte{}, v.CipherText...), id.ToBytes()...)) {
+		t.Fatalf("vector %d: keyed ciphertext mismatch.", k)
+	}
+	sig, err := BytesToSig(v.KeyedSignature)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+	if sig.GetKeyID() == nil || *sig.GetKeyID() != id || !bytes.Equal(v.KeyedSignature, append(append([]byte{}, v.Signature...), id.ToBytes()...)) {
+		t.Fatalf("vector %d: keyed signature mismatch.", k)
+	}
+	if !pk.VerifySig([]byte(v.Message), sig) {
+		t.Fatalf("vector %d: keyed signature rejected.", k)
+	}
+}